@@ -8,8 +8,15 @@ import (
 )
 
 type Config struct {
+	GRPC struct {
+		// Port of the gRPC API; empty disables it.
+		Port string
+	}
 	Server struct {
 		Port string
+		// ShutdownTimeout bounds the whole graceful shutdown, including
+		// draining the Kafka consumer.
+		ShutdownTimeout time.Duration
 	}
 	Postgres struct {
 		Host     string
@@ -17,6 +24,38 @@ type Config struct {
 		User     string
 		Password string
 		DBname   string
+		// SSLMode is disable, require, verify-ca or verify-full; the
+		// certificate files are optional.
+		SSLMode         string
+		SSLRootCert     string
+		SSLCert         string
+		SSLKey          string
+		ApplicationName string
+		// QueryTimeout bounds each storage operation, also when the caller
+		// set no deadline. 0 leaves operations unbounded.
+		QueryTimeout time.Duration
+		// Pool applies to every connection pool: the main database, shards
+		// and replicas. Zero values keep the database/sql defaults.
+		Pool struct {
+			MaxOpenConns    int
+			MaxIdleConns    int
+			ConnMaxLifetime time.Duration
+			ConnMaxIdleTime time.Duration
+		}
+		// Shards spread orders over several databases by shardkey; empty
+		// keeps them in the database above, which always holds everything
+		// else.
+		Shards []PostgresShard
+		// Replicas are DSNs of read replicas of the database above; order
+		// reads go to them while they pass health checks. With shards, each
+		// shard lists its own replicas instead.
+		Replicas []string
+		Replica  struct {
+			HealthCheckInterval time.Duration
+			// MaxLag takes a replica out of rotation while its replay is
+			// further behind; 0 ignores lag.
+			MaxLag time.Duration
+		}
 	}
 	Redis struct {
 		Addr     string
@@ -30,7 +69,134 @@ type Config struct {
 	Kafka struct {
 		Brokers []string
 		Topic   string
+		// HealthCheckInterval is how often Postgres is pinged; ingestion
+		// pauses while the ping fails. 0 disables the check.
+		HealthCheckInterval time.Duration
+	}
+	Outbox struct {
+		// Topic receives order events from the outbox. Empty disables the
+		// relay; events then stay pending in Postgres.
+		Topic     string
+		Interval  time.Duration
+		BatchSize int
+		Retention time.Duration
+	}
+	Webhooks struct {
+		Workers     int
+		QueueSize   int
+		MaxAttempts int
+		Timeout     time.Duration
+		Backoff     time.Duration
+		// DisableAfter is the number of events in a row that a webhook may
+		// fail before it is disabled.
+		DisableAfter int
+	}
+	Stream struct {
+		// History is how many recent orders are kept for Last-Event-ID resume.
+		History      int
+		ClientBuffer int
+		Heartbeat    time.Duration
+	}
+	Privacy struct {
+		// DefaultRole is the role of callers that were not given one.
+		DefaultRole string
+		// ExportRole applies to webhook payloads, LogRole to log lines.
+		ExportRole string
+		LogRole    string
+		// Roles maps a role to delivery fields and show, mask or hide; "*"
+		// covers the fields a role does not name. Empty uses the built-in
+		// support, analytics and anonymous roles.
+		Roles map[string]map[string]string
+	}
+	Encryption struct {
+		// Keyring is the JSON of a keyring file. It is only read from the
+		// ORDERSERVICE_KEYRING environment variable and takes precedence
+		// over KeyringFile, which is usually a mounted secret.
+		Keyring     string
+		KeyringFile string
+		// AllowPlaintext is the development mode: without a keyring,
+		// delivery personal data is stored in plain text instead of
+		// failing the startup. Also set by ORDERSERVICE_ALLOW_PLAINTEXT.
+		AllowPlaintext bool
+		// ReencryptInterval is how often rows not sealed with the primary
+		// key are rewritten.
+		ReencryptInterval time.Duration
+		ReencryptBatch    int
+	}
+	Retention struct {
+		Enabled bool
+		// ArchiveAfterDays is the age in days at which an order moves to
+		// OrdersArchive.
+		ArchiveAfterDays int
+		Interval         time.Duration
+		BatchSize        int
+	}
+	Partitions struct {
+		// Interval is how often missing monthly partitions are created.
+		Interval time.Duration
+		// MonthsAhead is how many months after the current one get
+		// partitions in advance.
+		MonthsAhead int
+	}
+	Metrics struct {
+		// Port serves Prometheus metrics on /metrics; empty disables it.
+		Port string
+	}
+	Auth struct {
+		// AnonymousScopes are granted to requests without credentials.
+		AnonymousScopes []string
+		// APIKeys hold the hex SHA-256 of each key, never the key itself.
+		APIKeys []struct {
+			Name   string
+			Hash   string
+			Scopes []string
+			Role   string
+		}
+		// APIKeysTable also looks keys up in the ApiKeys table.
+		APIKeysTable bool
+		JWT          struct {
+			// JWKSFile enables bearer tokens; empty disables them.
+			JWKSFile  string
+			Issuer    string
+			Audience  string
+			RoleClaim string
+			Leeway    time.Duration
+		}
+	}
+	CORS struct {
+		AllowedOrigins []string
 	}
+	RateLimit struct {
+		Enabled bool
+		// Redis keeps the buckets in Redis, so replicas share one limit.
+		Redis bool
+		// TrustForwardedFor takes the client IP from X-Forwarded-For; only
+		// enable it behind a proxy that sets the header.
+		TrustForwardedFor bool
+		// TrustedHops is the number of proxies that append to
+		// X-Forwarded-For; the client IP is the entry that many from the
+		// right. Entries further left are sent by the client and are not
+		// used. 0 means 1.
+		TrustedHops int
+		Default     RateLimitRule
+		// Routes override Default: order, batch, stream and admin.
+		Routes map[string]RateLimitRule
+	}
+}
+
+// PostgresShard is a database holding a share of the orders. The name is
+// recorded with each order placed on it, so it must not change.
+type PostgresShard struct {
+	Name     string
+	DSN      string
+	Replicas []string
+}
+
+// RateLimitRule allows Burst requests at once and Rate requests per second
+// after that.
+type RateLimitRule struct {
+	Rate  float64
+	Burst int
 }
 
 func InitConfig() (*Config, error) {
@@ -41,6 +207,14 @@ func InitConfig() (*Config, error) {
 		return nil, fmt.Errorf("cannot read in config err: %w", err)
 	}
 
+	// Secrets stay out of the config file.
+	if err := viper.BindEnv("encryption.keyring", "ORDERSERVICE_KEYRING"); err != nil {
+		return nil, fmt.Errorf("cannot bind env err: %w", err)
+	}
+	if err := viper.BindEnv("encryption.allowPlaintext", "ORDERSERVICE_ALLOW_PLAINTEXT"); err != nil {
+		return nil, fmt.Errorf("cannot bind env err: %w", err)
+	}
+
 	var cfg Config
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal config err: %w", err)
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+)
+
+// ErrUnknownKey is returned by a KeyStore that does not know the key.
+var ErrUnknownKey = errors.New("unknown api key")
+
+// HashKey is how keys are stored: only the hex SHA-256 of a key is kept in
+// the config or in Postgres. Keys are random, so a plain hash is enough.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type KeyStore interface {
+	LookupAPIKey(ctx context.Context, hash string) (Principal, error)
+}
+
+type StaticKey struct {
+	Name   string
+	Hash   string
+	Scopes []string
+	Role   privacy.Role
+}
+
+// StaticKeys are the keys listed in the config.
+type StaticKeys []StaticKey
+
+func (sk StaticKeys) Validate() error {
+	for _, k := range sk {
+		if k.Name == "" {
+			return errors.New("api key name is required")
+		}
+		if b, err := hex.DecodeString(k.Hash); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("api key %q: hash must be a hex SHA-256", k.Name)
+		}
+		if err := ValidateScopes(k.Scopes); err != nil {
+			return fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+	}
+	return nil
+}
+
+func (sk StaticKeys) LookupAPIKey(ctx context.Context, hash string) (Principal, error) {
+	for _, k := range sk {
+		if subtle.ConstantTimeCompare([]byte(strings.ToLower(k.Hash)), []byte(hash)) == 1 {
+			return Principal{Subject: k.Name, Method: MethodAPIKey, Scopes: k.Scopes, Role: k.Role}, nil
+		}
+	}
+	return Principal{}, ErrUnknownKey
+}
+
+// APIKeyAuthenticator looks a key up in its stores in turn.
+type APIKeyAuthenticator struct {
+	stores []KeyStore
+}
+
+func NewAPIKeyAuthenticator(stores ...KeyStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{stores: stores}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, creds Credentials) (Principal, error) {
+
+	if creds.APIKey == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	hash := HashKey(creds.APIKey)
+	for _, s := range a.stores {
+		p, err := s.LookupAPIKey(ctx, hash)
+		if errors.Is(err, ErrUnknownKey) {
+			continue
+		}
+		if err != nil {
+			return Principal{}, fmt.Errorf("cannot look up api key err:%w", err)
+		}
+		return p, nil
+	}
+
+	return Principal{}, ErrUnauthorized
+}
+
+func ValidateScopes(scopes []string) error {
+	for _, s := range scopes {
+		known := false
+		for _, k := range Scopes {
+			if s == k {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown scope %q, expected one of %s", s, strings.Join(Scopes, ", "))
+		}
+	}
+	return nil
+}
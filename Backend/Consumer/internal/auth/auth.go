@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+)
+
+const (
+	ScopeReadOrders = "orders:read"
+	// ScopeAdmin grants every other scope as well.
+	ScopeAdmin = "admin"
+)
+
+var Scopes = []string{ScopeReadOrders, ScopeAdmin}
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request
+	// carries no credentials of its kind, so the next one can try.
+	ErrNoCredentials = errors.New("no credentials")
+	ErrUnauthorized  = errors.New("invalid credentials")
+)
+
+// Credentials are taken from the transport: HTTP headers or gRPC metadata.
+type Credentials struct {
+	APIKey string
+	Bearer string
+}
+
+func (c Credentials) Empty() bool {
+	return c.APIKey == "" && c.Bearer == ""
+}
+
+type Principal struct {
+	Subject string
+	// Method is api_key, jwt or anonymous.
+	Method string
+	Scopes []string
+	// Role decides which delivery data the principal sees; empty falls
+	// back to the default role of the privacy policy.
+	Role privacy.Role
+}
+
+func (p Principal) Anonymous() bool {
+	return p.Method == MethodAnonymous
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	MethodAPIKey    = "api_key"
+	MethodJWT       = "jwt"
+	MethodAnonymous = "anonymous"
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, creds Credentials) (Principal, error)
+}
+
+// Chain tries its authenticators in turn. Requests without credentials get
+// the anonymous principal, which holds the scopes granted to everyone.
+type Chain struct {
+	authenticators []Authenticator
+	anonymous      Principal
+}
+
+func NewChain(anonymousScopes []string, authenticators ...Authenticator) *Chain {
+	return &Chain{
+		authenticators: authenticators,
+		anonymous: Principal{
+			Subject: MethodAnonymous,
+			Method:  MethodAnonymous,
+			Scopes:  anonymousScopes,
+		},
+	}
+}
+
+func (c *Chain) Authenticate(ctx context.Context, creds Credentials) (Principal, error) {
+
+	if creds.Empty() {
+		return c.anonymous, nil
+	}
+
+	for _, a := range c.authenticators {
+		p, err := a.Authenticate(ctx, creds)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+
+	// Credentials nobody understands, e.g. a bearer token while JWT is
+	// not configured, must not silently become anonymous access.
+	return Principal{}, ErrUnauthorized
+}
+
+type principalKey struct{}
+
+// WithPrincipal stores the principal and its role, so the privacy policy
+// sees the role without knowing about authentication.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	ctx = context.WithValue(ctx, principalKey{}, p)
+	if p.Role != "" {
+		ctx = privacy.WithRole(ctx, p.Role)
+	}
+	return ctx
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+)
+
+type MockKeyStore struct {
+	LookupAPIKeyFunc func(ctx context.Context, hash string) (Principal, error)
+}
+
+func (m MockKeyStore) LookupAPIKey(ctx context.Context, hash string) (Principal, error) {
+	return m.LookupAPIKeyFunc(ctx, hash)
+}
+
+func TestChain(t *testing.T) {
+
+	static := StaticKeys{{Name: "ops", Hash: HashKey("secret"), Scopes: []string{ScopeAdmin}, Role: privacy.RoleSupport}}
+	down := MockKeyStore{LookupAPIKeyFunc: func(ctx context.Context, hash string) (Principal, error) {
+		return Principal{}, errors.New("connection refused")
+	}}
+
+	tests := []struct {
+		name        string
+		stores      []KeyStore
+		creds       Credentials
+		wantSubject string
+		wantErr     error
+		wantAnyErr  bool
+	}{
+		{
+			name:        "anonymous",
+			stores:      []KeyStore{static},
+			wantSubject: MethodAnonymous,
+		},
+		{
+			name:        "static key",
+			stores:      []KeyStore{static},
+			creds:       Credentials{APIKey: "secret"},
+			wantSubject: "ops",
+		},
+		{
+			name:    "unknown key",
+			stores:  []KeyStore{static},
+			creds:   Credentials{APIKey: "guess"},
+			wantErr: ErrUnauthorized,
+		},
+		{
+			name:    "bearer without jwt",
+			stores:  []KeyStore{static},
+			creds:   Credentials{Bearer: "token"},
+			wantErr: ErrUnauthorized,
+		},
+		{
+			name:        "static key wins over broken store",
+			stores:      []KeyStore{static, down},
+			creds:       Credentials{APIKey: "secret"},
+			wantSubject: "ops",
+		},
+		{
+			name:       "store unavailable",
+			stores:     []KeyStore{static, down},
+			creds:      Credentials{APIKey: "guess"},
+			wantAnyErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain([]string{ScopeReadOrders}, NewAPIKeyAuthenticator(tt.stores...))
+
+			p, err := chain.Authenticate(context.Background(), tt.creds)
+			if tt.wantAnyErr {
+				if err == nil || errors.Is(err, ErrUnauthorized) {
+					t.Fatalf("expected a store error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected err:%v, got err:%v", tt.wantErr, err)
+			}
+			if p.Subject != tt.wantSubject {
+				t.Errorf("expected subject %q, got %q", tt.wantSubject, p.Subject)
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+
+	reader := Principal{Scopes: []string{ScopeReadOrders}}
+	if !reader.HasScope(ScopeReadOrders) || reader.HasScope(ScopeAdmin) {
+		t.Error("reader must only read orders")
+	}
+
+	admin := Principal{Scopes: []string{ScopeAdmin}}
+	if !admin.HasScope(ScopeReadOrders) {
+		t.Error("admin must have every scope")
+	}
+}
+
+func TestStaticKeysValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    StaticKeys
+		wantErr bool
+	}{
+		{name: "valid", keys: StaticKeys{{Name: "ops", Hash: HashKey("k"), Scopes: []string{ScopeAdmin}}}},
+		{name: "plain key instead of hash", keys: StaticKeys{{Name: "ops", Hash: "k"}}, wantErr: true},
+		{name: "unknown scope", keys: StaticKeys{{Name: "ops", Hash: HashKey("k"), Scopes: []string{"root"}}}, wantErr: true},
+		{name: "no name", keys: StaticKeys{{Hash: HashKey("k")}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.keys.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+
+	head, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	input := b64(head) + "." + b64(body)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("cannot sign: %v", err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("cannot sign: %v", err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return input + "." + b64(sig)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate rsa key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate ec key: %v", err)
+	}
+
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+	}})
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, jwks, 0o600); err != nil {
+		t.Fatalf("cannot write jwks: %v", err)
+	}
+
+	a, err := NewJWTAuthenticator(JWTOptions{JWKSFile: file, Issuer: "https://idp", Audience: "orders", Leeway: time.Second})
+	if err != nil {
+		t.Fatalf("cannot init authenticator: %v", err)
+	}
+
+	now := time.Now()
+	valid := func() map[string]any {
+		return map[string]any{
+			"sub":   "analyst@example.com",
+			"iss":   "https://idp",
+			"aud":   []string{"orders", "reports"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"scope": "orders:read",
+			"role":  "analytics",
+		}
+	}
+	with := func(key string, value any) map[string]any {
+		c := valid()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "rs256", token: sign(t, "RS256", "rsa", rsaKey, valid())},
+		{name: "es256", token: sign(t, "ES256", "ec", ecKey, valid())},
+		{name: "expired", token: sign(t, "RS256", "rsa", rsaKey, with("exp", now.Add(-time.Minute).Unix())), wantErr: true},
+		{name: "no exp", token: sign(t, "RS256", "rsa", rsaKey, with("exp", nil)), wantErr: true},
+		{name: "not yet valid", token: sign(t, "RS256", "rsa", rsaKey, with("nbf", now.Add(time.Minute).Unix())), wantErr: true},
+		{name: "other issuer", token: sign(t, "RS256", "rsa", rsaKey, with("iss", "https://evil")), wantErr: true},
+		{name: "other audience", token: sign(t, "RS256", "rsa", rsaKey, with("aud", "billing")), wantErr: true},
+		{name: "unknown kid", token: sign(t, "RS256", "other", rsaKey, valid()), wantErr: true},
+		{name: "alg does not match key", token: sign(t, "ES256", "rsa", ecKey, valid()), wantErr: true},
+		{name: "malformed", token: "not.a.jwt", wantErr: true},
+		{
+			name: "tampered payload",
+			token: func() string {
+				tok := sign(t, "RS256", "rsa", rsaKey, valid())
+				forged, _ := json.Marshal(with("scope", "admin"))
+				parts := strings.Split(tok, ".")
+				return parts[0] + "." + b64(forged) + "." + parts[2]
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(context.Background(), Credentials{Bearer: tt.token})
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnauthorized) {
+					t.Fatalf("expected ErrUnauthorized, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Subject != "analyst@example.com" || p.Role != privacy.RoleAnalytics || !p.HasScope(ScopeReadOrders) || p.HasScope(ScopeAdmin) {
+				t.Errorf("unexpected principal %+v", p)
+			}
+		})
+	}
+}
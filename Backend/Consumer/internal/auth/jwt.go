@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+)
+
+type JWTOptions struct {
+	// JWKSFile is a local JSON Web Key Set with the RSA and P-256 keys
+	// tokens may be signed with.
+	JWKSFile string
+	Issuer   string
+	Audience string
+	// RoleClaim names the claim with the privacy role, "role" by default.
+	RoleClaim string
+	// Leeway absorbs clock skew when checking exp and nbf.
+	Leeway time.Duration
+}
+
+// JWTAuthenticator validates RS256 and ES256 bearer tokens.
+type JWTAuthenticator struct {
+	keys map[string]crypto.PublicKey
+	opts JWTOptions
+	now  func() time.Time
+}
+
+func NewJWTAuthenticator(opts JWTOptions) (*JWTAuthenticator, error) {
+
+	data, err := os.ReadFile(opts.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read jwks file err:%w", err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.RoleClaim == "" {
+		opts.RoleClaim = "role"
+	}
+
+	return &JWTAuthenticator{keys: keys, opts: opts, now: time.Now}, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("cannot parse jwks err:%w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no signing keys")
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.New("invalid modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid exponent")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("rsa keys must have at least 2048 bits")
+		}
+		return key, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.New("invalid coordinates")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+type claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	// Scopes come either space separated in scope or as a list in scp.
+	Scope string   `json:"scope"`
+	Scp   []string `json:"scp"`
+}
+
+// audience is either a string or a list of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, creds Credentials) (Principal, error) {
+
+	if creds.Bearer == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	payload, err := a.verify(creds.Bearer)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Principal{}, fmt.Errorf("%w: invalid claims", ErrUnauthorized)
+	}
+	if err := a.checkClaims(c); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	var custom map[string]any
+	if err := json.Unmarshal(payload, &custom); err != nil {
+		return Principal{}, fmt.Errorf("%w: invalid claims", ErrUnauthorized)
+	}
+	role, _ := custom[a.opts.RoleClaim].(string)
+
+	scopes := c.Scp
+	if c.Scope != "" {
+		scopes = strings.Fields(c.Scope)
+	}
+
+	return Principal{Subject: c.Subject, Method: MethodJWT, Scopes: scopes, Role: privacy.Role(role)}, nil
+}
+
+// verify checks the signature and returns the decoded payload.
+func (a *JWTAuthenticator) verify(token string) ([]byte, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	head, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(head, &header); err != nil {
+		return nil, errors.New("malformed header")
+	}
+
+	key, ok := a.keys[header.Kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", header.Kid)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	// The algorithm has to match the key type, otherwise a token could pick
+	// a weaker check than the key was meant for.
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" {
+			return nil, fmt.Errorf("algorithm %q does not match an RSA key", header.Alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+			return nil, errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" {
+			return nil, fmt.Errorf("algorithm %q does not match an EC key", header.Alg)
+		}
+		if len(sig) != 64 {
+			return nil, errors.New("invalid signature")
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return nil, errors.New("invalid signature")
+		}
+	default:
+		return nil, errors.New("unsupported key")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed payload")
+	}
+	return payload, nil
+}
+
+func (a *JWTAuthenticator) checkClaims(c claims) error {
+
+	now := a.now()
+
+	if c.ExpiresAt == nil {
+		return errors.New("token has no exp")
+	}
+	if now.After(time.Unix(*c.ExpiresAt, 0).Add(a.opts.Leeway)) {
+		return errors.New("token expired")
+	}
+	if c.NotBefore != nil && now.Add(a.opts.Leeway).Before(time.Unix(*c.NotBefore, 0)) {
+		return errors.New("token not valid yet")
+	}
+	if a.opts.Issuer != "" && c.Issuer != a.opts.Issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	if a.opts.Audience != "" {
+		found := false
+		for _, aud := range c.Audience {
+			if aud == a.opts.Audience {
+				found = true
+			}
+		}
+		if !found {
+			return errors.New("token is not meant for this audience")
+		}
+	}
+	if c.Subject == "" {
+		return errors.New("token has no sub")
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+	"github.com/lib/pq"
+)
+
+// APIKeyStorage looks API keys up in the ApiKeys table. Keys are added and
+// revoked with SQL; only their SHA-256 is stored (see auth.HashKey).
+type APIKeyStorage struct {
+	db *sql.DB
+}
+
+func NewAPIKeyStorage(db *sql.DB) *APIKeyStorage {
+	return &APIKeyStorage{db: db}
+}
+
+func (ks *APIKeyStorage) LookupAPIKey(ctx context.Context, hash string) (auth.Principal, error) {
+
+	p := auth.Principal{Method: auth.MethodAPIKey}
+	var role string
+
+	err := ks.db.QueryRowContext(ctx, `SELECT name, scopes, role FROM ApiKeys
+	WHERE key_hash = $1 AND revoked_at IS NULL`, hash).Scan(&p.Subject, (*pq.StringArray)(&p.Scopes), &role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.Principal{}, auth.ErrUnknownKey
+	}
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("cannot get api key err:%w", err)
+	}
+
+	p.Role = privacy.Role(role)
+
+	return p, nil
+}
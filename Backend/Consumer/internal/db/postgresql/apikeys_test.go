@@ -0,0 +1,53 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+)
+
+func TestLookupAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		wantErr error
+	}{
+		{
+			name: "found",
+			rows: sqlmock.NewRows([]string{"name", "scopes", "role"}).AddRow("reports", "{orders:read}", "analytics"),
+		},
+		{
+			name:    "unknown or revoked",
+			rows:    sqlmock.NewRows([]string{"name", "scopes", "role"}),
+			wantErr: auth.ErrUnknownKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			hash := auth.HashKey("key")
+			mock.ExpectQuery("SELECT name, scopes, role FROM ApiKeys").WithArgs(hash).WillReturnRows(tt.rows)
+
+			p, err := NewAPIKeyStorage(db).LookupAPIKey(context.Background(), hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected err:%v, got err:%v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && (p.Subject != "reports" || p.Role != privacy.RoleAnalytics || !p.HasScope(auth.ScopeReadOrders)) {
+				t.Errorf("unexpected principal %+v", p)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// ArchiveOrders moves up to limit orders created before the cutoff to
+// OrdersArchive, one JSON document per order with the delivery encrypted as
+// in Delivery. Payments, items and delivery rows go with the order. Rows
+// locked by another replica are skipped. It returns the archived IDs.
+func (pg *PGStorage) ArchiveOrders(ctx context.Context, before time.Time, limit int) (IDs []string, err error) {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start transaction err:%w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				pg.log.Error("rollback failed: %v", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, "SELECT order_uid FROM Orders WHERE date_created < $1"+
+		" ORDER BY date_created LIMIT $2 FOR UPDATE SKIP LOCKED", before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("cannot select orders to archive err:%w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("cannot scan id err:%w", err)
+		}
+		IDs = append(IDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	if len(IDs) == 0 {
+		return nil, tx.Commit()
+	}
+
+	var orders []models.Order
+	orders, err = pg.queryOrders(ctx, tx, IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+
+		var sealed models.Order
+		sealed, err = pg.keyring.EncryptOrder(order)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encrypt order %s err:%w", order.OrderUID, err)
+		}
+
+		var payload []byte
+		payload, err = json.Marshal(sealed)
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal order %s err:%w", order.OrderUID, err)
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO OrdersArchive(order_uid, customer_id, date_created, payload)"+
+			" VALUES ($1, $2, $3, $4) ON CONFLICT (order_uid) DO NOTHING",
+			order.OrderUID, order.CustomerID, order.DateCreated, payload)
+		if err != nil {
+			return nil, fmt.Errorf("cannot insert into table OrdersArchive err:%w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM Orders WHERE order_uid = ANY($1) AND date_created < $2", pq.Array(IDs), before)
+	if err != nil {
+		return nil, fmt.Errorf("cannot delete archived orders err:%w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("cannot commit transaction err:%w", err)
+	}
+
+	return IDs, nil
+}
+
+// GetArchivedOrders reads orders from the archive; unknown IDs are skipped.
+func (pg *PGStorage) GetArchivedOrders(ctx context.Context, orderIDs []string) ([]models.Order, error) {
+
+	var orders []models.Order
+	err := pg.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		orders, err = pg.queryArchivedOrders(ctx, db, orderIDs)
+		return err
+	})
+
+	return orders, err
+}
+
+func (pg *PGStorage) queryArchivedOrders(ctx context.Context, q querier, orderIDs []string) ([]models.Order, error) {
+
+	if len(orderIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT payload FROM OrdersArchive WHERE order_uid = ANY($1)", pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get archived orders err:%w", err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("cannot scan archived order err:%w", err)
+		}
+
+		var order models.Order
+		if err := json.Unmarshal(payload, &order); err != nil {
+			return nil, fmt.Errorf("cannot unmarshal archived order err:%w", err)
+		}
+
+		order, err := pg.keyring.DecryptOrder(order)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt archived order err:%w", err)
+		}
+
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	return orders, nil
+}
+
+func (pg *PGStorage) getArchivedOrder(ctx context.Context, q querier, orderID string) (models.Order, error) {
+
+	orders, err := pg.queryArchivedOrders(ctx, q, []string{orderID})
+	if err != nil {
+		return models.Order{}, err
+	}
+	if len(orders) == 0 {
+		return models.Order{}, errs.ErrOrderNotFound
+	}
+
+	return orders[0], nil
+}
+
+func (pg *PGStorage) reencryptArchive(ctx context.Context, limit int) (n int, err error) {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("cannot start transaction err:%w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				pg.log.Error("rollback failed: %v", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, "SELECT order_uid, payload FROM OrdersArchive WHERE "+
+		notSealed("payload->'delivery'->>'name'", "payload->'delivery'->>'phone'",
+			"payload->'delivery'->>'address'", "payload->'delivery'->>'email'")+
+		" ORDER BY order_uid LIMIT $2 FOR UPDATE SKIP LOCKED", pg.keyring.SealedPrefix()+"%", limit)
+	if err != nil {
+		return 0, fmt.Errorf("cannot select archived orders to re-encrypt err:%w", err)
+	}
+
+	payloads := map[string][]byte{}
+	for rows.Next() {
+		var id string
+		var payload []byte
+		if err = rows.Scan(&id, &payload); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("cannot scan archived order err:%w", err)
+		}
+		payloads[id] = payload
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	for id, payload := range payloads {
+
+		var order models.Order
+		if err = json.Unmarshal(payload, &order); err != nil {
+			return 0, fmt.Errorf("cannot unmarshal archived order %s err:%w", id, err)
+		}
+
+		order.Delivery, err = pg.keyring.RewrapDelivery(order.Delivery)
+		if err != nil {
+			return 0, fmt.Errorf("cannot re-encrypt archived order %s err:%w", id, err)
+		}
+
+		if payload, err = json.Marshal(order); err != nil {
+			return 0, fmt.Errorf("cannot marshal archived order %s err:%w", id, err)
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE OrdersArchive SET payload = $1 WHERE order_uid = $2", payload, id)
+		if err != nil {
+			return 0, fmt.Errorf("cannot update archived order %s err:%w", id, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("cannot commit transaction err:%w", err)
+	}
+
+	return len(payloads), nil
+}
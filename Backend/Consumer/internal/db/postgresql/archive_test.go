@@ -0,0 +1,58 @@
+package postgresql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestArchiveOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	defer db.Close()
+
+	pg := NewPGStorage(db, nil, zaptest.NewLogger(t))
+	before := time.Date(2025, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT order_uid FROM Orders WHERE date_created < \\$1").WithArgs(before, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow("1"))
+	mock.ExpectQuery("FROM Orders o JOIN Delivery d").WithArgs(pq.Array([]string{"1"})).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id", "delivery_service",
+			"shardkey", "sm_id", "date_created", "oof_shard",
+			"delivery_id", "name", "phone", "zip", "city", "address", "region", "email",
+			"transaction", "request_id", "currency", "provider", "amount", "payment_dt", "bank",
+			"delivery_cost", "goods_total", "custom_fee", "items",
+		}).AddRow(
+			"1", "TRACK1", "WBIL", "en", "", "alice", "meest", "9", 99, "2021-11-26T06:22:19Z", "1",
+			"7", "John Doe", "+9720000000", "2639809", "Kiryat Mozkin", "Ploshad Mira 15", "Kraiot", "john@test.com",
+			"tx1", "", "USD", "wbpay", 1817, 1637907727, "alpha", 1500, 317, 0, []byte(`[]`),
+		))
+	mock.ExpectExec("INSERT INTO OrdersArchive").WithArgs("1", "alice", "2021-11-26T06:22:19Z", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// The date bound keeps the delete to the partitions being archived.
+	mock.ExpectExec("DELETE FROM Orders WHERE order_uid = ANY\\(\\$1\\) AND date_created < \\$2").
+		WithArgs(pq.Array([]string{"1"}), before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	IDs, err := pg.ArchiveOrders(context.Background(), before, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(IDs, []string{"1"}) {
+		t.Errorf("unexpected ids %v", IDs)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/LootNex/OrderService/Consumer/internal/gdpr"
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// CustomerOrderIDs includes archived orders.
+func (pg *PGStorage) CustomerOrderIDs(ctx context.Context, customerID string) ([]string, error) {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := pg.db.QueryContext(ctx, "SELECT order_uid FROM (SELECT order_uid, date_created FROM Orders WHERE customer_id = $1"+
+		" UNION ALL SELECT order_uid, date_created FROM OrdersArchive WHERE customer_id = $1) o ORDER BY date_created, order_uid", customerID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get orders of customer err:%w", err)
+	}
+	defer rows.Close()
+
+	var IDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("cannot scan id err:%w", err)
+		}
+		IDs = append(IDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	return IDs, nil
+}
+
+func (pg *PGStorage) AnonymizeCustomer(ctx context.Context, customerID, pseudonym string, audit gdpr.Audit) ([]string, error) {
+	return pg.anonymizeCustomer(ctx, customerID, pseudonym, &audit)
+}
+
+// anonymizeCustomer writes no audit record when audit is nil; the sharded
+// storage writes one record for all shards.
+func (pg *PGStorage) anonymizeCustomer(ctx context.Context, customerID, pseudonym string, audit *gdpr.Audit) (IDs []string, err error) {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start transaction err:%w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				pg.log.Error("rollback failed: %v", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, "SELECT order_uid FROM Orders WHERE customer_id = $1 FOR UPDATE", customerID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot lock orders of customer err:%w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("cannot scan id err:%w", err)
+		}
+		IDs = append(IDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	var delivery []byte
+	delivery, err = json.Marshal(models.Delivery{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal delivery err:%w", err)
+	}
+
+	if len(IDs) > 0 {
+		_, err = tx.ExecContext(ctx, "UPDATE Delivery SET name = '', phone = '', zip = '', city = '', address = '', region = '',"+
+			" email = '', email_bidx = NULL, phone_bidx = NULL WHERE order_id = ANY($1)", pq.Array(IDs))
+		if err != nil {
+			return nil, fmt.Errorf("cannot anonymize table Delivery err:%w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE Orders SET customer_id = $1 WHERE order_uid = ANY($2)", pseudonym, pq.Array(IDs))
+		if err != nil {
+			return nil, fmt.Errorf("cannot anonymize table Orders err:%w", err)
+		}
+
+		// Events still waiting in the outbox, and delivered ones kept until
+		// cleanup, carry a full copy of the order.
+		_, err = tx.ExecContext(ctx, "UPDATE Outbox SET payload = jsonb_set(jsonb_set(jsonb_set(payload,"+
+			" '{customer_id}', to_jsonb($1::text)), '{order,customer_id}', to_jsonb($1::text)), '{order,delivery}', $2::jsonb)"+
+			" WHERE aggregate_id = ANY($3)", pseudonym, string(delivery), pq.Array(IDs))
+		if err != nil {
+			return nil, fmt.Errorf("cannot anonymize table Outbox err:%w", err)
+		}
+	}
+
+	rows, err = tx.QueryContext(ctx, "UPDATE OrdersArchive SET customer_id = $1,"+
+		" payload = jsonb_set(jsonb_set(payload, '{customer_id}', to_jsonb($1::text)), '{delivery}', $2::jsonb)"+
+		" WHERE customer_id = $3 RETURNING order_uid", pseudonym, string(delivery), customerID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot anonymize table OrdersArchive err:%w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("cannot scan id err:%w", err)
+		}
+		IDs = append(IDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	if audit != nil {
+		audit.Orders = len(IDs)
+		if err = insertAudit(ctx, tx, *audit); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("cannot commit transaction err:%w", err)
+	}
+
+	return IDs, nil
+}
+
+func (pg *PGStorage) SaveAudit(ctx context.Context, audit gdpr.Audit) error {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	return insertAudit(ctx, pg.db, audit)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertAudit(ctx context.Context, db execer, audit gdpr.Audit) error {
+
+	_, err := db.ExecContext(ctx, "INSERT INTO PrivacyRequests(kind, subject_hash, requested_by, orders) VALUES ($1, $2, $3, $4)",
+		audit.Kind, audit.SubjectHash, audit.RequestedBy, audit.Orders)
+	if err != nil {
+		return fmt.Errorf("cannot insert into table PrivacyRequests err:%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package postgresql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LootNex/OrderService/Consumer/internal/gdpr"
+	"github.com/lib/pq"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestAnonymizeCustomer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	defer db.Close()
+
+	pg := NewPGStorage(db, nil, zaptest.NewLogger(t))
+	audit := gdpr.Audit{Kind: gdpr.KindErase, SubjectHash: "9c1f0a6b", RequestedBy: "jwt:dpo"}
+	IDs := pq.Array([]string{"a", "b"})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT order_uid FROM Orders WHERE customer_id = $1 FOR UPDATE")).
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow("a").AddRow("b"))
+	mock.ExpectExec("UPDATE Delivery SET name = ''").WithArgs(IDs).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE Orders SET customer_id = $1")).WithArgs("erased-1", IDs).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE Outbox SET payload").WithArgs("erased-1", sqlmock.AnyArg(), IDs).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("UPDATE OrdersArchive SET customer_id").WithArgs("erased-1", sqlmock.AnyArg(), "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow("c"))
+	mock.ExpectExec("INSERT INTO PrivacyRequests").WithArgs(gdpr.KindErase, audit.SubjectHash, "jwt:dpo", 3).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	got, err := pg.AnonymizeCustomer(context.Background(), "alice", "erased-1", audit)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("expected two orders and one archived, got %v", got)
+	}
+
+	// Nothing to anonymize still leaves an audit record.
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT order_uid FROM Orders").WithArgs("bob").WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+	mock.ExpectQuery("UPDATE OrdersArchive").WithArgs("erased-2", sqlmock.AnyArg(), "bob").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+	mock.ExpectExec("INSERT INTO PrivacyRequests").WithArgs(gdpr.KindErase, audit.SubjectHash, "jwt:dpo", 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if _, err := pg.AnonymizeCustomer(context.Background(), "bob", "erased-2", audit); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
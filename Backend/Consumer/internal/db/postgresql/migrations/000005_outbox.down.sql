@@ -0,0 +1 @@
+DROP TABLE Outbox
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS Outbox(
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON Outbox(id) WHERE delivered_at IS NULL
\ No newline at end of file
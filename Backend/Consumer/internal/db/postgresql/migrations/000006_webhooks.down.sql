@@ -0,0 +1,2 @@
+DROP TABLE WebhookDeliveries;
+DROP TABLE Webhooks
\ No newline at end of file
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS Webhooks(
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    failure_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    disabled_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS WebhookDeliveries(
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id BIGINT NOT NULL REFERENCES Webhooks(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT,
+    error TEXT,
+    duration_ms BIGINT NOT NULL,
+    delivered_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON WebhookDeliveries(webhook_id, id DESC)
\ No newline at end of file
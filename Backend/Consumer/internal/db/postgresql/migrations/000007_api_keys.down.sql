@@ -0,0 +1 @@
+DROP TABLE ApiKeys
\ No newline at end of file
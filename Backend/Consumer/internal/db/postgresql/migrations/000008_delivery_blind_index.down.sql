@@ -0,0 +1,2 @@
+ALTER TABLE Delivery DROP COLUMN IF EXISTS phone_bidx;
+ALTER TABLE Delivery DROP COLUMN IF EXISTS email_bidx
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE Delivery ADD COLUMN IF NOT EXISTS email_bidx TEXT;
+ALTER TABLE Delivery ADD COLUMN IF NOT EXISTS phone_bidx TEXT;
+
+CREATE INDEX IF NOT EXISTS delivery_email_bidx_idx ON Delivery(email_bidx);
+CREATE INDEX IF NOT EXISTS delivery_phone_bidx_idx ON Delivery(phone_bidx)
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_customer_idx;
+DROP TABLE PrivacyRequests
\ No newline at end of file
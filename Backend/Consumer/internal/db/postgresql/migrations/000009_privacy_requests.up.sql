@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS PrivacyRequests(
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    subject_hash TEXT NOT NULL,
+    requested_by TEXT NOT NULL,
+    orders INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS privacy_requests_subject_idx ON PrivacyRequests(subject_hash);
+CREATE INDEX IF NOT EXISTS orders_customer_idx ON Orders(customer_id)
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_date_created_idx;
+DROP TABLE OrdersArchive
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS OrdersArchive(
+    order_uid TEXT PRIMARY KEY,
+    customer_id TEXT,
+    date_created TIMESTAMPTZ,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    payload JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS orders_archive_customer_idx ON OrdersArchive(customer_id);
+CREATE INDEX IF NOT EXISTS orders_date_created_idx ON Orders(date_created)
\ No newline at end of file
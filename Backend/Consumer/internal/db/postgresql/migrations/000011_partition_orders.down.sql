@@ -0,0 +1,108 @@
+ALTER TABLE Orders RENAME TO orders_partitioned;
+ALTER TABLE Delivery RENAME TO delivery_partitioned;
+ALTER TABLE Payments RENAME TO payments_partitioned;
+ALTER TABLE Items RENAME TO items_partitioned;
+
+ALTER INDEX orders_pkey RENAME TO orders_partitioned_pkey;
+ALTER INDEX delivery_pkey RENAME TO delivery_partitioned_pkey;
+ALTER INDEX payments_pkey RENAME TO payments_partitioned_pkey;
+ALTER INDEX items_pkey RENAME TO items_partitioned_pkey;
+DROP INDEX orders_customer_idx;
+DROP INDEX orders_date_created_idx;
+DROP INDEX delivery_email_bidx_idx;
+DROP INDEX delivery_phone_bidx_idx;
+
+CREATE TABLE Orders(
+    order_uid TEXT PRIMARY KEY,
+    track_number TEXT,
+    entry TEXT,
+    locale TEXT,
+    internal_signature TEXT,
+    customer_id TEXT,
+    delivery_service TEXT,
+    shardkey TEXT,
+    sm_id INT,
+    date_created TIMESTAMPTZ,
+    oof_shard TEXT
+);
+
+CREATE TABLE Delivery(
+    delivery_id INT PRIMARY KEY,
+    name TEXT NOT NULL,
+    phone TEXT NOT NULL,
+    zip TEXT,
+    city TEXT NOT NULL,
+    address TEXT NOT NULL,
+    region TEXT NOT NULL,
+    email TEXT,
+    order_id TEXT REFERENCES Orders(order_uid) ON DELETE CASCADE,
+    email_bidx TEXT,
+    phone_bidx TEXT
+);
+
+ALTER SEQUENCE delivery_delivery_id_seq OWNED BY NONE;
+ALTER TABLE Delivery ALTER COLUMN delivery_id SET DEFAULT nextval('delivery_delivery_id_seq');
+ALTER SEQUENCE delivery_delivery_id_seq OWNED BY Delivery.delivery_id;
+
+CREATE TABLE Payments(
+    transaction TEXT PRIMARY KEY,
+    request_id TEXT,
+    currency TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    amount INT NOT NULL,
+    payment_dt INT NOT NULL,
+    bank TEXT NOT NULL,
+    delivery_cost INT NOT NULL,
+    goods_total INT NOT NULL,
+    custom_fee INT,
+    order_id TEXT REFERENCES Orders(order_uid) ON DELETE CASCADE
+);
+
+CREATE TABLE Items(
+    chrt_id INT PRIMARY KEY,
+    track_number TEXT,
+    price INT,
+    rid TEXT,
+    name TEXT,
+    sale INT,
+    size TEXT,
+    total_price INT,
+    nm_id INT,
+    brand TEXT,
+    status INT,
+    order_id TEXT REFERENCES Orders(order_uid) ON DELETE CASCADE
+);
+
+-- The composite keys allowed the same order_uid, transaction or chrt_id in
+-- different months; only the first row of each is kept.
+INSERT INTO Orders
+SELECT DISTINCT ON (order_uid) order_uid, track_number, entry, locale, internal_signature, customer_id,
+    delivery_service, shardkey, sm_id, date_created, oof_shard
+FROM orders_partitioned ORDER BY order_uid, date_created;
+
+INSERT INTO Delivery
+SELECT d.delivery_id, d.name, d.phone, d.zip, d.city, d.address, d.region, d.email, d.order_id, d.email_bidx, d.phone_bidx
+FROM delivery_partitioned d JOIN Orders o ON o.order_uid = d.order_id AND o.date_created = d.order_date;
+
+INSERT INTO Payments
+SELECT DISTINCT ON (p.transaction) p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank,
+    p.delivery_cost, p.goods_total, p.custom_fee, p.order_id
+FROM payments_partitioned p JOIN Orders o ON o.order_uid = p.order_id AND o.date_created = p.order_date
+ORDER BY p.transaction, p.order_date;
+
+INSERT INTO Items
+SELECT DISTINCT ON (i.chrt_id) i.chrt_id, i.track_number, i.price, i.rid, i.name, i.sale, i.size, i.total_price,
+    i.nm_id, i.brand, i.status, i.order_id
+FROM items_partitioned i JOIN Orders o ON o.order_uid = i.order_id AND o.date_created = i.order_date
+ORDER BY i.chrt_id, i.order_date;
+
+CREATE INDEX orders_customer_idx ON Orders(customer_id);
+CREATE INDEX orders_date_created_idx ON Orders(date_created);
+CREATE INDEX delivery_email_bidx_idx ON Delivery(email_bidx);
+CREATE INDEX delivery_phone_bidx_idx ON Delivery(phone_bidx);
+
+DROP FUNCTION create_order_partitions(DATE, INT);
+DROP TABLE items_partitioned;
+DROP TABLE payments_partitioned;
+DROP TABLE delivery_partitioned;
+DROP TABLE orders_partitioned
@@ -0,0 +1,179 @@
+-- Orders and their child tables are range-partitioned by month on the order's
+-- date_created. Child rows carry it as order_date, so a month is stored in
+-- one partition of each table. Unique keys of a partitioned table must
+-- include the partition key, hence the composite primary keys.
+ALTER TABLE Orders RENAME TO orders_unpartitioned;
+ALTER TABLE Delivery RENAME TO delivery_unpartitioned;
+ALTER TABLE Payments RENAME TO payments_unpartitioned;
+ALTER TABLE Items RENAME TO items_unpartitioned;
+
+ALTER INDEX orders_pkey RENAME TO orders_unpartitioned_pkey;
+ALTER INDEX delivery_pkey RENAME TO delivery_unpartitioned_pkey;
+ALTER INDEX payments_pkey RENAME TO payments_unpartitioned_pkey;
+ALTER INDEX items_pkey RENAME TO items_unpartitioned_pkey;
+ALTER INDEX IF EXISTS orders_customer_idx RENAME TO orders_unpartitioned_customer_idx;
+ALTER INDEX IF EXISTS orders_date_created_idx RENAME TO orders_unpartitioned_date_created_idx;
+ALTER INDEX IF EXISTS delivery_email_bidx_idx RENAME TO delivery_unpartitioned_email_bidx_idx;
+ALTER INDEX IF EXISTS delivery_phone_bidx_idx RENAME TO delivery_unpartitioned_phone_bidx_idx;
+
+CREATE TABLE Orders(
+    order_uid TEXT NOT NULL,
+    track_number TEXT,
+    entry TEXT,
+    locale TEXT,
+    internal_signature TEXT,
+    customer_id TEXT,
+    delivery_service TEXT,
+    shardkey TEXT,
+    sm_id INT,
+    date_created TIMESTAMPTZ NOT NULL,
+    oof_shard TEXT,
+    PRIMARY KEY (order_uid, date_created)
+) PARTITION BY RANGE (date_created);
+
+CREATE TABLE Delivery(
+    delivery_id INT NOT NULL,
+    name TEXT NOT NULL,
+    phone TEXT NOT NULL,
+    zip TEXT,
+    city TEXT NOT NULL,
+    address TEXT NOT NULL,
+    region TEXT NOT NULL,
+    email TEXT,
+    order_id TEXT NOT NULL,
+    email_bidx TEXT,
+    phone_bidx TEXT,
+    order_date TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (delivery_id, order_date),
+    FOREIGN KEY (order_id, order_date) REFERENCES Orders(order_uid, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (order_date);
+
+-- The serial sequence moves to the new table so ids keep growing.
+ALTER SEQUENCE delivery_delivery_id_seq OWNED BY NONE;
+ALTER TABLE Delivery ALTER COLUMN delivery_id SET DEFAULT nextval('delivery_delivery_id_seq');
+ALTER SEQUENCE delivery_delivery_id_seq OWNED BY Delivery.delivery_id;
+
+CREATE TABLE Payments(
+    transaction TEXT NOT NULL,
+    request_id TEXT,
+    currency TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    amount INT NOT NULL,
+    payment_dt INT NOT NULL,
+    bank TEXT NOT NULL,
+    delivery_cost INT NOT NULL,
+    goods_total INT NOT NULL,
+    custom_fee INT,
+    order_id TEXT NOT NULL,
+    order_date TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (transaction, order_date),
+    FOREIGN KEY (order_id, order_date) REFERENCES Orders(order_uid, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (order_date);
+
+CREATE TABLE Items(
+    chrt_id INT NOT NULL,
+    track_number TEXT,
+    price INT,
+    rid TEXT,
+    name TEXT,
+    sale INT,
+    size TEXT,
+    total_price INT,
+    nm_id INT,
+    brand TEXT,
+    status INT,
+    order_id TEXT NOT NULL,
+    order_date TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (chrt_id, order_date),
+    FOREIGN KEY (order_id, order_date) REFERENCES Orders(order_uid, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (order_date);
+
+CREATE INDEX orders_customer_idx ON Orders(customer_id);
+CREATE INDEX orders_date_created_idx ON Orders(date_created);
+CREATE INDEX delivery_order_idx ON Delivery(order_id);
+CREATE INDEX delivery_email_bidx_idx ON Delivery(email_bidx);
+CREATE INDEX delivery_phone_bidx_idx ON Delivery(phone_bidx);
+CREATE INDEX payments_order_idx ON Payments(order_id);
+CREATE INDEX items_order_idx ON Items(order_id);
+
+-- Rows outside every monthly partition land in the default ones. A month
+-- cannot be attached while its rows sit in a default partition, so the
+-- maintenance job creates partitions ahead of time.
+CREATE TABLE orders_default PARTITION OF Orders DEFAULT;
+CREATE TABLE delivery_default PARTITION OF Delivery DEFAULT;
+CREATE TABLE payments_default PARTITION OF Payments DEFAULT;
+CREATE TABLE items_default PARTITION OF Items DEFAULT;
+
+-- create_order_partitions creates the monthly partitions of all four tables
+-- for months starting at start_month (UTC) and returns how many were new.
+CREATE OR REPLACE FUNCTION create_order_partitions(start_month DATE, months INT) RETURNS INT AS $$
+DECLARE
+    created INT := 0;
+    month_start DATE;
+    parent TEXT;
+    part_name TEXT;
+BEGIN
+    -- Replicas run the job concurrently.
+    PERFORM pg_advisory_xact_lock(hashtext('create_order_partitions'));
+
+    FOR i IN 0..months - 1 LOOP
+        month_start := (date_trunc('month', start_month::timestamp) + make_interval(months => i))::date;
+
+        FOREACH parent IN ARRAY ARRAY['orders', 'delivery', 'payments', 'items'] LOOP
+            part_name := parent || '_' || to_char(month_start, 'YYYY_MM');
+            CONTINUE WHEN to_regclass(part_name) IS NOT NULL;
+
+            BEGIN
+                EXECUTE format('CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)', part_name, parent,
+                    month_start::timestamp AT TIME ZONE 'UTC',
+                    (month_start + INTERVAL '1 month')::timestamp AT TIME ZONE 'UTC');
+                created := created + 1;
+            EXCEPTION WHEN check_violation THEN
+                RAISE WARNING 'cannot create partition %: rows for this month are in %_default', part_name, parent;
+            END;
+        END LOOP;
+    END LOOP;
+
+    RETURN created;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Existing orders get partitions from their first month, but at most two
+-- years back: anything older is due for the archive and stays in the
+-- default partitions. Three months ahead are created as well.
+SELECT create_order_partitions(start_month,
+    ((EXTRACT(YEAR FROM age(current_month, start_month)) * 12 + EXTRACT(MONTH FROM age(current_month, start_month))) + 4)::int)
+FROM (
+    SELECT date_trunc('month', now() AT TIME ZONE 'UTC')::date AS current_month,
+        LEAST(date_trunc('month', now() AT TIME ZONE 'UTC'),
+            GREATEST(date_trunc('month', COALESCE(min(date_created), now()) AT TIME ZONE 'UTC'),
+                date_trunc('month', now() AT TIME ZONE 'UTC') - INTERVAL '2 years'))::date AS start_month
+    FROM orders_unpartitioned
+) bounds;
+
+-- Orders without a creation date cannot be routed to a month; they keep
+-- the epoch so the default partition holds them.
+INSERT INTO Orders
+SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service,
+    shardkey, sm_id, COALESCE(date_created, 'epoch'), oof_shard
+FROM orders_unpartitioned;
+
+INSERT INTO Delivery(delivery_id, name, phone, zip, city, address, region, email, order_id, email_bidx, phone_bidx, order_date)
+SELECT d.delivery_id, d.name, d.phone, d.zip, d.city, d.address, d.region, d.email, d.order_id, d.email_bidx, d.phone_bidx,
+    COALESCE(o.date_created, 'epoch')
+FROM delivery_unpartitioned d JOIN orders_unpartitioned o ON o.order_uid = d.order_id;
+
+INSERT INTO Payments
+SELECT p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank, p.delivery_cost,
+    p.goods_total, p.custom_fee, p.order_id, COALESCE(o.date_created, 'epoch')
+FROM payments_unpartitioned p JOIN orders_unpartitioned o ON o.order_uid = p.order_id;
+
+INSERT INTO Items
+SELECT i.chrt_id, i.track_number, i.price, i.rid, i.name, i.sale, i.size, i.total_price, i.nm_id, i.brand, i.status,
+    i.order_id, COALESCE(o.date_created, 'epoch')
+FROM items_unpartitioned i JOIN orders_unpartitioned o ON o.order_uid = i.order_id;
+
+DROP TABLE items_unpartitioned;
+DROP TABLE payments_unpartitioned;
+DROP TABLE delivery_unpartitioned;
+DROP TABLE orders_unpartitioned
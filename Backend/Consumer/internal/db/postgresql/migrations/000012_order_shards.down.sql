@@ -0,0 +1 @@
+DROP TABLE OrderShards
\ No newline at end of file
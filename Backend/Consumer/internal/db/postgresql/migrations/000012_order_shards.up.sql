@@ -0,0 +1,5 @@
+-- Used only in the main database when orders are sharded.
+CREATE TABLE IF NOT EXISTS OrderShards(
+    order_uid TEXT PRIMARY KEY,
+    shard TEXT NOT NULL
+)
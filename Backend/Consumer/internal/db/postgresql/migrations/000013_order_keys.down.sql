@@ -0,0 +1 @@
+DROP TABLE OrderKeys
\ No newline at end of file
@@ -0,0 +1,31 @@
+-- Orders and its child tables are partitioned, so their primary keys only
+-- hold within a month. OrderKeys is not partitioned: it keeps order_uid,
+-- the payment transaction and item chrt_id unique across all months and
+-- the archive. Inserting the order's key first also makes a concurrent
+-- save of the same order wait for the first one and then fail.
+CREATE TABLE IF NOT EXISTS OrderKeys(
+    kind TEXT NOT NULL,
+    key TEXT NOT NULL,
+    order_uid TEXT NOT NULL,
+    PRIMARY KEY (kind, key)
+);
+
+CREATE INDEX IF NOT EXISTS order_keys_order_idx ON OrderKeys(order_uid);
+
+INSERT INTO OrderKeys(kind, key, order_uid)
+SELECT 'order', order_uid, order_uid FROM Orders
+UNION ALL
+SELECT 'order', order_uid, order_uid FROM OrdersArchive
+ON CONFLICT DO NOTHING;
+
+INSERT INTO OrderKeys(kind, key, order_uid)
+SELECT 'transaction', transaction, order_id FROM Payments
+UNION ALL
+SELECT 'transaction', payload->'payment'->>'transaction', order_uid FROM OrdersArchive
+ON CONFLICT DO NOTHING;
+
+INSERT INTO OrderKeys(kind, key, order_uid)
+SELECT 'chrt_id', chrt_id::text, order_id FROM Items
+UNION ALL
+SELECT 'chrt_id', item->>'chrt_id', order_uid FROM OrdersArchive, jsonb_array_elements(payload->'items') AS item
+ON CONFLICT DO NOTHING;
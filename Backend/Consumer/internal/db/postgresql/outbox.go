@@ -0,0 +1,119 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/outbox"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+type OutboxStorage struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewOutboxStorage(db *sql.DB, logg *zap.Logger) *OutboxStorage {
+	return &OutboxStorage{
+		db:  db,
+		log: logg,
+	}
+}
+
+func insertOutbox(ctx context.Context, tx *sql.Tx, msg outbox.Message) error {
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO Outbox(event_type, aggregate_id, payload, created_at) VALUES ($1, $2, $3, $4)",
+		msg.EventType, msg.Key, msg.Payload, msg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("cannot insert into table Outbox err: %w", err)
+	}
+
+	return nil
+}
+
+// PublishPending locks up to limit pending rows, publishes them and marks
+// them delivered in the same transaction. Rows locked by another replica are
+// skipped. If the commit fails after publish, the rows are published again.
+func (ob *OutboxStorage) PublishPending(ctx context.Context, limit int,
+	publish func(ctx context.Context, msgs []outbox.Message) error) (int, error) {
+
+	tx, err := ob.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("cannot start transaction err:%w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				ob.log.Error("rollback failed: %v", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, event_type, aggregate_id, payload, created_at FROM Outbox"+
+		" WHERE delivered_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED", limit)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get pending outbox events err:%w", err)
+	}
+
+	var msgs []outbox.Message
+	var ids []int64
+
+	for rows.Next() {
+		var msg outbox.Message
+		if err = rows.Scan(&msg.ID, &msg.EventType, &msg.Key, &msg.Payload, &msg.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("cannot scan outbox event err:%w", err)
+		}
+		msgs = append(msgs, msg)
+		ids = append(ids, msg.ID)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	if len(msgs) == 0 {
+		err = tx.Commit()
+		if err != nil {
+			return 0, fmt.Errorf("cannot commit transaction err:%w", err)
+		}
+		return 0, nil
+	}
+
+	if err = publish(ctx, msgs); err != nil {
+		return 0, fmt.Errorf("cannot publish outbox events err:%w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE Outbox SET delivered_at = now() WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("cannot mark outbox events delivered err:%w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("cannot commit transaction err:%w", err)
+	}
+
+	return len(msgs), nil
+}
+
+func (ob *OutboxStorage) DeleteDelivered(ctx context.Context, before time.Time) (int64, error) {
+
+	res, err := ob.db.ExecContext(ctx, "DELETE FROM Outbox WHERE delivered_at IS NOT NULL AND delivered_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("cannot delete delivered outbox events err:%w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get affected rows err: %w", err)
+	}
+
+	return n, nil
+}
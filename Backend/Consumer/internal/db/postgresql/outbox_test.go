@@ -0,0 +1,76 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LootNex/OrderService/Consumer/internal/outbox"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestPublishPending(t *testing.T) {
+	tests := []struct {
+		name       string
+		publishErr error
+		want       int
+	}{
+		{
+			name: "success",
+			want: 2,
+		},
+		{
+			name:       "publish failed",
+			publishErr: errors.New("broker unavailable"),
+			want:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			storage := NewOutboxStorage(db, zaptest.NewLogger(t))
+
+			now := time.Now()
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT id, event_type, aggregate_id, payload, created_at FROM Outbox").
+				WithArgs(10).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "event_type", "aggregate_id", "payload", "created_at"}).
+					AddRow(1, outbox.EventOrderAccepted, "a", []byte(`{}`), now).
+					AddRow(2, outbox.EventOrderAccepted, "b", []byte(`{}`), now))
+			if tt.publishErr == nil {
+				mock.ExpectExec("UPDATE Outbox SET delivered_at").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			var published []outbox.Message
+			n, err := storage.PublishPending(context.Background(), 10, func(ctx context.Context, msgs []outbox.Message) error {
+				published = msgs
+				return tt.publishErr
+			})
+
+			if !errors.Is(err, tt.publishErr) {
+				t.Errorf("expected %v, got %v", tt.publishErr, err)
+			}
+			if n != tt.want {
+				t.Errorf("expected %d published, got %d", tt.want, n)
+			}
+			if len(published) != 2 || published[0].Key != "a" || published[1].ID != 2 {
+				t.Errorf("unexpected messages %+v", published)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CreatePartitions creates the monthly partitions of Orders, Delivery,
+// Payments and Items for months months starting with the month of from, and
+// returns how many tables were new. Existing partitions are left alone.
+func (pg *PGStorage) CreatePartitions(ctx context.Context, from time.Time, months int) (int, error) {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	var created int
+	err := pg.db.QueryRowContext(ctx, "SELECT create_order_partitions($1::date, $2)",
+		from.UTC().Format(time.DateOnly), months).Scan(&created)
+	if err != nil {
+		return 0, fmt.Errorf("cannot create partitions err:%w", err)
+	}
+
+	return created, nil
+}
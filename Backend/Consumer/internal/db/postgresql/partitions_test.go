@@ -0,0 +1,37 @@
+package postgresql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCreatePartitions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	defer db.Close()
+
+	pg := NewPGStorage(db, nil, zaptest.NewLogger(t))
+
+	// Early on 1 November in Moscow is still October in UTC.
+	from := time.Date(2026, 11, 1, 1, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT create_order_partitions($1::date, $2)")).
+		WithArgs("2026-10-31", 4).
+		WillReturnRows(sqlmock.NewRows([]string{"create_order_partitions"}).AddRow(4))
+
+	n, err := pg.CreatePartitions(context.Background(), from, 4)
+	if err != nil || n != 4 {
+		t.Fatalf("expected 4 partitions, got %d, %v", n, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
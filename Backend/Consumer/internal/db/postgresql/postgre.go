@@ -2,22 +2,221 @@ package postgresql
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/LootNex/OrderService/Consumer/configs"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"go.uber.org/zap"
 )
 
+// PoolOptions limit the connections kept open to a database. Zero values
+// keep the database/sql defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func NewPoolOptions(config *config.Config) PoolOptions {
+	return PoolOptions{
+		MaxOpenConns:    config.Postgres.Pool.MaxOpenConns,
+		MaxIdleConns:    config.Postgres.Pool.MaxIdleConns,
+		ConnMaxLifetime: config.Postgres.Pool.ConnMaxLifetime,
+		ConnMaxIdleTime: config.Postgres.Pool.ConnMaxIdleTime,
+	}
+}
+
+func (o PoolOptions) apply(db *sql.DB) {
+	if o.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(o.MaxOpenConns)
+	}
+	if o.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(o.MaxIdleConns)
+	}
+	if o.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	}
+	if o.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+	}
+}
+
+// sslModes are the modes lib/pq supports.
+var sslModes = map[string]bool{"disable": true, "require": true, "verify-ca": true, "verify-full": true}
+
 func InitPostgres(config *config.Config, log *zap.Logger) (*sql.DB, error) {
 
-	strConn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		config.Postgres.Host, config.Postgres.Port, config.Postgres.User, config.Postgres.Password, config.Postgres.DBname)
+	strConn, err := connString(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return OpenPostgres(strConn, NewPoolOptions(config), log)
+}
+
+// connString builds the DSN of the main database. An empty sslMode keeps
+// TLS disabled, as before it was configurable.
+func connString(config *config.Config) (string, error) {
+
+	c := config.Postgres
+	mode := c.SSLMode
+	if mode == "" {
+		mode = "disable"
+	}
+	if !sslModes[mode] {
+		return "", fmt.Errorf("unsupported postgres sslMode %q", mode)
+	}
+
+	params := [][2]string{
+		{"host", c.Host},
+		{"port", strconv.Itoa(c.Port)},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.DBname},
+		{"sslmode", mode},
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+		{"application_name", c.ApplicationName},
+	}
+
+	var parts []string
+	for _, p := range params {
+		if p[1] != "" {
+			parts = append(parts, p[0]+"="+quoteConnValue(p[1]))
+		}
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// MergeConnString completes the DSN of a shard or replica with the TLS
+// options and application name of the config, so they apply to every
+// database. Options set in the DSN win over the config. An unset sslMode
+// adds nothing and leaves the DSN's mode, or the lib/pq default, in place.
+func MergeConnString(dsn string, config *config.Config) (string, error) {
+
+	c := config.Postgres
+	if c.SSLMode != "" && !sslModes[c.SSLMode] {
+		return "", fmt.Errorf("unsupported postgres sslMode %q", c.SSLMode)
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		converted, err := pq.ParseURL(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid postgres URL err:%w", err)
+		}
+		dsn = converted
+	}
+
+	params, err := parseConnString(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	set := make(map[string]bool, len(params))
+	for _, p := range params {
+		set[p[0]] = true
+	}
+
+	defaults := [][2]string{
+		{"sslmode", c.SSLMode},
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+		{"application_name", c.ApplicationName},
+	}
+	for _, p := range defaults {
+		if p[1] != "" && !set[p[0]] {
+			params = append(params, p)
+		}
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		parts = append(parts, p[0]+"="+quoteConnValue(p[1]))
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// parseConnString splits a key=value DSN the way lib/pq reads it: values
+// are separated by whitespace, may be single-quoted and escape with a
+// backslash.
+func parseConnString(dsn string) ([][2]string, error) {
+
+	var params [][2]string
+
+	s := strings.TrimSpace(dsn)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, errors.New("malformed DSN: expected key=value")
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeftFunc(s[eq+1:], unicode.IsSpace)
+
+		var value strings.Builder
+		quoted := strings.HasPrefix(s, "'")
+		if quoted {
+			s = s[1:]
+		}
+
+		i := 0
+		closed := false
+		for ; i < len(s); i++ {
+			ch := s[i]
+			if ch == '\\' && i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+				continue
+			}
+			if quoted && ch == '\'' {
+				closed = true
+				i++
+				break
+			}
+			if !quoted && unicode.IsSpace(rune(ch)) {
+				break
+			}
+			value.WriteByte(ch)
+		}
+		if quoted && !closed {
+			return nil, fmt.Errorf("malformed DSN: unterminated quote in %s", key)
+		}
+
+		params = append(params, [2]string{key, value.String()})
+		s = strings.TrimLeftFunc(s[i:], unicode.IsSpace)
+	}
+
+	return params, nil
+}
+
+// quoteConnValue quotes values with spaces, quotes or backslashes, so a
+// password like that does not break the DSN.
+func quoteConnValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// OpenPostgres connects to the database and applies migrations.
+func OpenPostgres(strConn string, pool PoolOptions, log *zap.Logger) (*sql.DB, error) {
 
 	db, err := sql.Open("postgres", strConn)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open posgres err:%w", err)
 	}
+	pool.apply(db)
 
 	if err = db.Ping(); err != nil {
 		return nil, fmt.Errorf("ping err:%w", err)
@@ -30,3 +229,28 @@ func InitPostgres(config *config.Config, log *zap.Logger) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// OpenReplica connects to a read replica. Replicas follow the primary's
+// schema, so no migrations run, and an unreachable replica is not an error:
+// health checks keep it out of rotation until it answers.
+func OpenReplica(strConn string, pool PoolOptions) (*sql.DB, error) {
+
+	db, err := sql.Open("postgres", strConn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open replica err:%w", err)
+	}
+	pool.apply(db)
+
+	return db, nil
+}
+
+// RegisterPoolMetrics exports the pool statistics of db as the go_sql_*
+// metrics labelled with db_name=name.
+func RegisterPoolMetrics(db *sql.DB, name string) error {
+
+	if err := prometheus.Register(collectors.NewDBStatsCollector(db, name)); err != nil {
+		return fmt.Errorf("cannot register pool metrics of %s err:%w", name, err)
+	}
+
+	return nil
+}
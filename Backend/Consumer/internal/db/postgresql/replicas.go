@@ -0,0 +1,285 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const defaultReplicaCheckInterval = 5 * time.Second
+
+var (
+	replicaUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "orderservice_postgres_replica_up",
+		Help: "Whether a read replica passed its last health check.",
+	}, []string{"replica"})
+	replicaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "orderservice_postgres_replica_lag_seconds",
+		Help: "Replay lag of a read replica at its last health check.",
+	}, []string{"replica"})
+	readsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orderservice_postgres_reads_total",
+		Help: "Order reads by the kind of database that served them.",
+	}, []string{"target"})
+)
+
+type readPrimaryKey struct{}
+
+// ReadPrimary sends the reads made with the returned context to the
+// primary, so they see everything written before them.
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryKey{}, true)
+}
+
+// ReadsPrimary reports whether ctx was made by ReadPrimary.
+func ReadsPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(readPrimaryKey{}).(bool)
+	return v
+}
+
+type readSourceKey struct{}
+
+// ReadSource records whether reads made with a context from TrackReads were
+// served by a replica.
+type ReadSource struct {
+	replica atomic.Bool
+}
+
+// Replica reports whether any tracked read came from a replica. Such data
+// may lag the primary, e.g. still hold a customer erased moments ago, so it
+// must not be written to the cache.
+func (rs *ReadSource) Replica() bool {
+	return rs.replica.Load()
+}
+
+// TrackReads returns a context whose reads are recorded in the ReadSource.
+func TrackReads(ctx context.Context) (context.Context, *ReadSource) {
+	src := &ReadSource{}
+	return context.WithValue(ctx, readSourceKey{}, src), src
+}
+
+// ReadFromReplica records in the ReadSource of ctx, if any, that a read was
+// served by a replica.
+func ReadFromReplica(ctx context.Context) {
+	if src, ok := ctx.Value(readSourceKey{}).(*ReadSource); ok {
+		src.replica.Store(true)
+	}
+}
+
+type ReplicaOptions struct {
+	// Name labels the metrics of the replicas, e.g. with the shard.
+	Name                string
+	HealthCheckInterval time.Duration
+	// MaxLag takes a replica out of rotation while its replay is further
+	// behind the primary; 0 ignores lag.
+	MaxLag time.Duration
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// ReplicaSet spreads reads over the healthy read replicas of a database.
+// A replica takes reads only after it passes a health check, so until the
+// first check, and whenever none is healthy, reads go to the primary.
+type ReplicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	opts     ReplicaOptions
+	log      *zap.Logger
+}
+
+func NewReplicaSet(replicas []*sql.DB, opts ReplicaOptions, logg *zap.Logger) *ReplicaSet {
+	if opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = defaultReplicaCheckInterval
+	}
+
+	rs := &ReplicaSet{
+		opts: opts,
+		log:  logg,
+	}
+	for i, db := range replicas {
+		name := fmt.Sprint(i)
+		if opts.Name != "" {
+			name = opts.Name + "/" + name
+		}
+		rs.replicas = append(rs.replicas, &replica{name: name, db: db})
+		replicaUp.WithLabelValues(name).Set(0)
+	}
+
+	return rs
+}
+
+// reader picks a healthy replica in turn, or returns nil when the read must
+// go to the primary.
+func (rs *ReplicaSet) reader(ctx context.Context) *sql.DB {
+
+	if ReadsPrimary(ctx) || len(rs.replicas) == 0 {
+		return nil
+	}
+
+	start := rs.next.Add(1)
+	for i := range rs.replicas {
+		r := rs.replicas[(start+uint64(i))%uint64(len(rs.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+
+	return nil
+}
+
+// markDown takes the replica out of rotation until its next passed check.
+func (rs *ReplicaSet) markDown(db *sql.DB) {
+	for _, r := range rs.replicas {
+		if r.db == db {
+			rs.setHealthy(r, false)
+		}
+	}
+}
+
+func (rs *ReplicaSet) setHealthy(r *replica, healthy bool) {
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		rs.log.Info("read replica is back in rotation", zap.String("replica", r.name))
+		replicaUp.WithLabelValues(r.name).Set(1)
+	} else {
+		rs.log.Warn("read replica taken out of rotation", zap.String("replica", r.name))
+		replicaUp.WithLabelValues(r.name).Set(0)
+	}
+}
+
+// Run checks the replicas right away and then on every tick until ctx is
+// cancelled.
+func (rs *ReplicaSet) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(rs.opts.HealthCheckInterval)
+	defer ticker.Stop()
+
+	rs.check(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			rs.check(ctx)
+		}
+	}
+}
+
+func (rs *ReplicaSet) check(ctx context.Context) {
+
+	for _, r := range rs.replicas {
+
+		lag, err := rs.lag(ctx, r.db)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			rs.log.Debug("read replica health check failed", zap.String("replica", r.name), zap.Error(err))
+			rs.setHealthy(r, false)
+			continue
+		}
+
+		replicaLag.WithLabelValues(r.name).Set(lag.Seconds())
+		rs.setHealthy(r, rs.opts.MaxLag <= 0 || lag <= rs.opts.MaxLag)
+	}
+}
+
+var errNotStreaming = errors.New("replica is not streaming from the primary")
+
+// lag is zero when the replica has replayed everything it received, so an
+// idle primary does not look like a lagging replica. A replica that lost
+// its upstream has replayed everything too, so it must also be streaming.
+func (rs *ReplicaSet) lag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, rs.opts.HealthCheckInterval)
+	defer cancel()
+
+	var streaming bool
+	var seconds float64
+	err := db.QueryRowContext(ctx, "SELECT COALESCE((SELECT status = 'streaming' FROM pg_stat_wal_receiver), false),"+
+		" CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0"+
+		" ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END").Scan(&streaming, &seconds)
+	if err != nil {
+		return 0, fmt.Errorf("cannot check replica err:%w", err)
+	}
+	if !streaming {
+		return 0, errNotStreaming
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Close closes the replica connections.
+func (rs *ReplicaSet) Close() error {
+
+	var firstErr error
+	for _, r := range rs.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// UseReplicas sends order reads to the replicas of rs.
+func (pg *PGStorage) UseReplicas(rs *ReplicaSet) {
+	pg.replicas = rs
+}
+
+// read runs fn on a replica when one is healthy. If the replica fails, it is
+// taken out of rotation and fn runs again on the primary. A missing order is
+// not retried: a caller that must see its own writes reads with ReadPrimary.
+// Each attempt gets its own query timeout, so a replica that hangs still
+// leaves time for the primary.
+func (pg *PGStorage) read(ctx context.Context, fn func(ctx context.Context, db *sql.DB) error) error {
+
+	var db *sql.DB
+	if pg.replicas != nil {
+		db = pg.replicas.reader(ctx)
+	}
+	if db == nil {
+		readsTotal.WithLabelValues("primary").Inc()
+		return pg.attempt(ctx, pg.db, fn)
+	}
+
+	readsTotal.WithLabelValues("replica").Inc()
+	err := pg.attempt(ctx, db, fn)
+	if err == nil || errors.Is(err, errs.ErrOrderNotFound) {
+		ReadFromReplica(ctx)
+		return err
+	}
+	if ctx.Err() != nil {
+		return err
+	}
+
+	pg.log.Warn("read from replica failed, retrying on the primary", zap.Error(err))
+	pg.replicas.markDown(db)
+
+	readsTotal.WithLabelValues("primary").Inc()
+	return pg.attempt(ctx, pg.db, fn)
+}
+
+func (pg *PGStorage) attempt(ctx context.Context, db *sql.DB, fn func(ctx context.Context, db *sql.DB) error) error {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	return fn(ctx, db)
+}
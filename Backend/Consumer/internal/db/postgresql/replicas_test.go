@@ -0,0 +1,202 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"go.uber.org/zap/zaptest"
+)
+
+func openMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+		db.Close()
+	})
+
+	return db, mock
+}
+
+func expectLag(mock sqlmock.Sqlmock, seconds float64) {
+	mock.ExpectQuery("pg_last_wal_replay_lsn").WillReturnRows(sqlmock.NewRows([]string{"streaming", "lag"}).AddRow(true, seconds))
+}
+
+func TestReplicaSetCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		want   bool
+	}{
+		{name: "in sync", expect: func(mock sqlmock.Sqlmock) { expectLag(mock, 0) }, want: true},
+		{name: "small lag", expect: func(mock sqlmock.Sqlmock) { expectLag(mock, 2.5) }, want: true},
+		{name: "too far behind", expect: func(mock sqlmock.Sqlmock) { expectLag(mock, 30) }},
+		{name: "upstream lost", expect: func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery("pg_stat_wal_receiver").WillReturnRows(sqlmock.NewRows([]string{"streaming", "lag"}).AddRow(false, 0))
+		}},
+		{name: "unreachable", expect: func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery("pg_last_wal_replay_lsn").WillReturnError(errors.New("connection refused"))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := openMock(t)
+			rs := NewReplicaSet([]*sql.DB{db}, ReplicaOptions{Name: "test", MaxLag: 10 * time.Second}, zaptest.NewLogger(t))
+
+			if rs.reader(context.Background()) != nil {
+				t.Fatal("a replica must not take reads before its first check")
+			}
+
+			tt.expect(mock)
+			rs.check(context.Background())
+
+			if got := rs.reader(context.Background()) == db; got != tt.want {
+				t.Errorf("expected replica in rotation %v, got %v", tt.want, got)
+			}
+			if rs.reader(ReadPrimary(context.Background())) != nil {
+				t.Error("ReadPrimary must bypass the replicas")
+			}
+		})
+	}
+}
+
+func TestReplicaSetRoundRobin(t *testing.T) {
+	a, amock := openMock(t)
+	b, bmock := openMock(t)
+	rs := NewReplicaSet([]*sql.DB{a, b}, ReplicaOptions{}, zaptest.NewLogger(t))
+
+	expectLag(amock, 0)
+	expectLag(bmock, 0)
+	rs.check(context.Background())
+
+	used := map[*sql.DB]int{}
+	for i := 0; i < 4; i++ {
+		used[rs.reader(context.Background())]++
+	}
+	if used[a] != 2 || used[b] != 2 {
+		t.Errorf("expected reads spread evenly, got a=%d b=%d", used[a], used[b])
+	}
+
+	rs.markDown(a)
+	for i := 0; i < 2; i++ {
+		if rs.reader(context.Background()) != b {
+			t.Fatal("expected reads on the remaining replica")
+		}
+	}
+}
+
+func TestPGStorageReadReplica(t *testing.T) {
+	ids := func(IDs ...string) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"order_uid"})
+		for _, id := range IDs {
+			rows.AddRow(id)
+		}
+		return rows
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		expect func(primary, replica sqlmock.Sqlmock)
+		want   []string
+		wantUp bool
+		// wantReplica is whether the read is reported as served by a replica.
+		wantReplica bool
+	}{
+		{
+			name: "replica",
+			ctx:  context.Background(),
+			expect: func(primary, replica sqlmock.Sqlmock) {
+				replica.ExpectQuery("SELECT order_uid FROM Orders").WillReturnRows(ids("r"))
+			},
+			want:        []string{"r"},
+			wantUp:      true,
+			wantReplica: true,
+		},
+		{
+			name: "read your writes",
+			ctx:  ReadPrimary(context.Background()),
+			expect: func(primary, replica sqlmock.Sqlmock) {
+				primary.ExpectQuery("SELECT order_uid FROM Orders").WillReturnRows(ids("p"))
+			},
+			want:   []string{"p"},
+			wantUp: true,
+		},
+		{
+			name: "replica fails",
+			ctx:  context.Background(),
+			expect: func(primary, replica sqlmock.Sqlmock) {
+				replica.ExpectQuery("SELECT order_uid FROM Orders").WillReturnError(errors.New("connection reset"))
+				primary.ExpectQuery("SELECT order_uid FROM Orders").WillReturnRows(ids("p"))
+			},
+			want: []string{"p"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryDB, primary := openMock(t)
+			replicaDB, replica := openMock(t)
+
+			rs := NewReplicaSet([]*sql.DB{replicaDB}, ReplicaOptions{}, zaptest.NewLogger(t))
+			expectLag(replica, 0)
+			rs.check(context.Background())
+
+			pg := NewPGStorage(primaryDB, nil, zaptest.NewLogger(t))
+			pg.UseReplicas(rs)
+
+			tt.expect(primary, replica)
+			ctx, src := TrackReads(tt.ctx)
+			IDs, err := pg.GetAllOrderID(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(IDs, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, IDs)
+			}
+			if up := rs.replicas[0].healthy.Load(); up != tt.wantUp {
+				t.Errorf("expected replica in rotation %v, got %v", tt.wantUp, up)
+			}
+			if src.Replica() != tt.wantReplica {
+				t.Errorf("expected read from replica %v, got %v", tt.wantReplica, src.Replica())
+			}
+		})
+	}
+}
+
+func TestPGStorageReadReplicaNotFound(t *testing.T) {
+	primaryDB, _ := openMock(t)
+	replicaDB, replica := openMock(t)
+
+	rs := NewReplicaSet([]*sql.DB{replicaDB}, ReplicaOptions{}, zaptest.NewLogger(t))
+	expectLag(replica, 0)
+	rs.check(context.Background())
+
+	pg := NewPGStorage(primaryDB, nil, zaptest.NewLogger(t))
+	pg.UseReplicas(rs)
+
+	// A missing order is an answer, not a failure: the primary is not asked
+	// and the replica stays in rotation.
+	replica.ExpectQuery("FROM Orders WHERE order_uid").WithArgs("1").WillReturnError(sql.ErrNoRows)
+	replica.ExpectQuery("FROM OrdersArchive").WillReturnRows(sqlmock.NewRows([]string{"payload"}))
+
+	if _, err := pg.GetOrderByID(context.Background(), "1"); !errors.Is(err, errs.ErrOrderNotFound) {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+	if !rs.replicas[0].healthy.Load() {
+		t.Error("expected the replica to stay in rotation")
+	}
+}
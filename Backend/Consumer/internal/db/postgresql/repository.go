@@ -3,33 +3,92 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-
-	"github.com/LootNex/OrderService/Consumer/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/encryption"
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/outbox"
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
+const uniqueViolation = "23505"
+
+// PGStorage keeps delivery names, phones, addresses and emails encrypted
+// when it has a keyring; a nil keyring stores them in plain text.
 type PGStorage struct {
-	db  *sql.DB
-	log *zap.Logger
+	db      *sql.DB
+	keyring *encryption.Keyring
+	// replicas serve order reads when set; writes always go to db.
+	replicas *ReplicaSet
+	// queryTimeout bounds every operation; 0 leaves it to the caller.
+	queryTimeout time.Duration
+	log          *zap.Logger
 }
 
 type RepManager interface {
 	SaveNewOrder(ctx context.Context, order models.Order) error
 	GetOrderByID(ctx context.Context, orderID string) (models.Order, error)
 	GetAllOrderID(ctx context.Context) ([]string, error)
+	UpdateOrderStatus(ctx context.Context, order models.Order) error
+	ReplaceOrder(ctx context.Context, order models.Order) error
+	ListOrders(ctx context.Context, filter ListFilter) ([]string, error)
+	GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, error)
+}
+
+// ListFilter selects orders for ListOrders. Zero fields do not filter.
+// Results are ordered by date_created and order_uid, newest first, and After
+// continues a previous page.
+type ListFilter struct {
+	CustomerID      string
+	DeliveryService string
+	// Email and Phone match exactly after normalization: case is ignored in
+	// emails and only digits count in phones.
+	Email         string
+	Phone         string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	After         *Cursor
+	Limit         int
+}
+
+type Cursor struct {
+	DateCreated time.Time
+	OrderUID    string
 }
 
-func NewPGStorage(db *sql.DB, logg *zap.Logger) *PGStorage {
+func NewPGStorage(db *sql.DB, keyring *encryption.Keyring, logg *zap.Logger) *PGStorage {
 	return &PGStorage{
-		db:  db,
-		log: logg,
+		db:      db,
+		keyring: keyring,
+		log:     logg,
 	}
 }
 
+// SetQueryTimeout bounds every operation of the storage, also when the
+// caller's context has no deadline.
+func (pg *PGStorage) SetQueryTimeout(d time.Duration) {
+	pg.queryTimeout = d
+}
+
+func (pg *PGStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if pg.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, pg.queryTimeout)
+}
+
 func (pg *PGStorage) SaveNewOrder(ctx context.Context, order models.Order) error {
 
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
 	tx, err := pg.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("cannot start transaction err:%w", err)
@@ -43,26 +102,146 @@ func (pg *PGStorage) SaveNewOrder(ctx context.Context, order models.Order) error
 		}
 	}()
 
-	_, err = tx.ExecContext(ctx, "INSERT INTO Orders VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+	if err = pg.insertOrder(ctx, tx, order); err != nil {
+		return err
+	}
+
+	// The event is written in the same transaction, so it exists if and only
+	// if the order was stored. Its delivery is sealed like the Delivery row;
+	// the relay opens it when publishing.
+	var sealed models.Order
+	sealed, err = pg.keyring.EncryptOrder(order)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt delivery err:%w", err)
+	}
+	var event outbox.Message
+	event, err = outbox.NewOrderAccepted(sealed, time.Now())
+	if err != nil {
+		return err
+	}
+	if err = insertOutbox(ctx, tx, event); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("cannot commit transaction err:%w", err)
+	}
+
+	return nil
+
+}
+
+// ReplaceOrder overwrites a stored order, live or archived, with order and
+// records an order.replaced event, all in one transaction. The old rows are
+// deleted first, so a corrected date_created moves the order to its new
+// partition.
+func (pg *PGStorage) ReplaceOrder(ctx context.Context, order models.Order) (err error) {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction err:%w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				pg.log.Error("rollback failed: %v", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	// Child rows go with the order through ON DELETE CASCADE.
+	var deleted int64
+	for _, table := range []string{"Orders", "OrdersArchive"} {
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE order_uid = $1", order.OrderUID)
+		if err != nil {
+			return fmt.Errorf("cannot delete from table %s err:%w", table, err)
+		}
+		var n int64
+		if n, err = res.RowsAffected(); err != nil {
+			return fmt.Errorf("cannot get affected rows err:%w", err)
+		}
+		deleted += n
+	}
+	if deleted == 0 {
+		err = errs.ErrOrderNotFound
+		return err
+	}
+	// The keys are claimed again with the new payment and items.
+	_, err = tx.ExecContext(ctx, "DELETE FROM OrderKeys WHERE order_uid = $1", order.OrderUID)
+	if err != nil {
+		return fmt.Errorf("cannot delete from table OrderKeys err:%w", err)
+	}
+
+	if err = pg.insertOrder(ctx, tx, order); err != nil {
+		return err
+	}
+
+	var sealed models.Order
+	sealed, err = pg.keyring.EncryptOrder(order)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt delivery err:%w", err)
+	}
+	var event outbox.Message
+	event, err = outbox.NewOrderReplaced(sealed, time.Now())
+	if err != nil {
+		return err
+	}
+	if err = insertOutbox(ctx, tx, event); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("cannot commit transaction err:%w", err)
+	}
+
+	return nil
+}
+
+// insertOrder writes the order and its delivery, payment and items.
+func (pg *PGStorage) insertOrder(ctx context.Context, tx *sql.Tx, order models.Order) error {
+
+	if err := insertKeys(ctx, tx, order); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO Orders VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
 		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID,
 		order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard)
 
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return errs.ErrOrderExists
+		}
 		return fmt.Errorf("cannot insert into table Orders err: %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx, "INSERT INTO Delivery(name, phone, zip, city, address, region, email, order_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
-		order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City,
-		order.Delivery.Address, order.Delivery.Region, order.Delivery.Email, order.OrderUID)
+	delivery, err := pg.keyring.EncryptDelivery(order.Delivery)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt delivery err:%w", err)
+	}
+	idx := pg.keyring.DeliveryIndexes(order.Delivery)
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO Delivery(name, phone, zip, city, address, region, email, order_id, email_bidx, phone_bidx, order_date)"+
+		" VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		delivery.Name, delivery.Phone, delivery.Zip, delivery.City,
+		delivery.Address, delivery.Region, delivery.Email, order.OrderUID, nullable(idx.Email), nullable(idx.Phone), order.DateCreated)
 
 	if err != nil {
 		return fmt.Errorf("cannot insert into table Delivery err: %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx, "INSERT INTO Payments VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+	_, err = tx.ExecContext(ctx, "INSERT INTO Payments VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
 		order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider,
 		order.Payment.Amount, order.Payment.PaymentDT, order.Payment.Bank, order.Payment.DeliveryCost,
-		order.Payment.GoodsTotal, order.Payment.CustomFee, order.OrderUID)
+		order.Payment.GoodsTotal, order.Payment.CustomFee, order.OrderUID, order.DateCreated)
 
 	if err != nil {
 		return fmt.Errorf("cannot insert into table Payments err: %w", err)
@@ -70,49 +249,100 @@ func (pg *PGStorage) SaveNewOrder(ctx context.Context, order models.Order) error
 
 	for _, item := range order.Items {
 
-		_, err = tx.ExecContext(ctx, "INSERT INTO Items VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		_, err = tx.ExecContext(ctx, "INSERT INTO Items VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
 			item.ChrtID, item.TrackNumber, item.Price, item.RID, item.Name, item.Sale, item.Size, item.TotalPrice,
-			item.NmID, item.Brand, item.Status, order.OrderUID)
+			item.NmID, item.Brand, item.Status, order.OrderUID, order.DateCreated)
 
 		if err != nil {
 			return fmt.Errorf("cannot insert into table Items err: %w", err)
 		}
 	}
 
-	err = tx.Commit()
+	return nil
+}
+
+// insertKeys claims the order_uid, payment transaction and item chrt_ids
+// of the order in OrderKeys. The partitioned tables cannot keep them unique
+// across months, and a concurrent save of the same order blocks on the
+// order's key until the first transaction ends, then fails here.
+func insertKeys(ctx context.Context, tx *sql.Tx, order models.Order) error {
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO OrderKeys(kind, key, order_uid) VALUES ('order', $1, $1)", order.OrderUID)
 	if err != nil {
-		return fmt.Errorf("cannot commit transaction err:%w", err)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return errs.ErrOrderExists
+		}
+		return fmt.Errorf("cannot insert into table OrderKeys err: %w", err)
 	}
 
-	return nil
+	args := []any{order.OrderUID, order.Payment.Transaction}
+	values := []string{"('transaction', $2, $1)"}
+	for _, item := range order.Items {
+		args = append(args, strconv.Itoa(item.ChrtID))
+		values = append(values, fmt.Sprintf("('chrt_id', $%d, $1)", len(args)))
+	}
 
+	_, err = tx.ExecContext(ctx, "INSERT INTO OrderKeys(kind, key, order_uid) VALUES "+strings.Join(values, ", "), args...)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%w: payment transaction or item chrt_id belongs to another order", errs.ErrInvalidOrder)
+		}
+		return fmt.Errorf("cannot insert into table OrderKeys err: %w", err)
+	}
+
+	return nil
 }
 
+// GetOrderByID falls back to the archive for orders moved there by the
+// retention job. Child rows are read by the order's date, so each query
+// touches one partition.
 func (pg *PGStorage) GetOrderByID(ctx context.Context, orderID string) (models.Order, error) {
 
+	var order models.Order
+	err := pg.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		order, err = pg.getOrderByID(ctx, db, orderID)
+		return err
+	})
+
+	return order, err
+}
+
+func (pg *PGStorage) getOrderByID(ctx context.Context, db *sql.DB, orderID string) (models.Order, error) {
+
 	var order models.Order
 	var items []models.Item
 
-	err := pg.db.QueryRowContext(ctx, "SELECT track_number, entry, locale, internal_signature, customer_id, delivery_service,"+
+	err := db.QueryRowContext(ctx, "SELECT track_number, entry, locale, internal_signature, customer_id, delivery_service,"+
 		"shardkey, sm_id, date_created, oof_shard FROM Orders WHERE order_uid = $1", orderID).Scan(
 		&order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature, &order.CustomerID,
 		&order.DeliveryService, &order.ShardKey, &order.SmID, &order.DateCreated, &order.OofShard)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return pg.getArchivedOrder(ctx, db, orderID)
+	}
 	if err != nil {
 		return models.Order{}, fmt.Errorf("cannot scan info from Orders err:%w", err)
 	}
 
-	err = pg.db.QueryRowContext(ctx, "SELECT delivery_id, name, phone, zip, city, address, region, email"+
-		" FROM Delivery WHERE order_id = $1", orderID).Scan(
-		&order.Delivery.Delivery_ID, &order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City,
+	err = db.QueryRowContext(ctx, "SELECT delivery_id, name, phone, zip, city, address, region, email"+
+		" FROM Delivery WHERE order_id = $1 AND order_date = $2", orderID, order.DateCreated).Scan(
+		&order.Delivery.DeliveryID, &order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City,
 		&order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email)
 
 	if err != nil {
 		return models.Order{}, fmt.Errorf("cannot scan info from Delivery err:%w", err)
 	}
 
-	err = pg.db.QueryRowContext(ctx, "SELECT transaction, request_id, currency, provider, amount, payment_dt, bank,"+
-		" delivery_cost, goods_total, custom_fee FROM Payments WHERE order_id = $1", orderID).Scan(
+	order.Delivery, err = pg.keyring.DecryptDelivery(order.Delivery)
+	if err != nil {
+		return models.Order{}, fmt.Errorf("cannot decrypt delivery of %s err:%w", orderID, err)
+	}
+
+	err = db.QueryRowContext(ctx, "SELECT transaction, request_id, currency, provider, amount, payment_dt, bank,"+
+		" delivery_cost, goods_total, custom_fee FROM Payments WHERE order_id = $1 AND order_date = $2", orderID, order.DateCreated).Scan(
 		&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider,
 		&order.Payment.Amount, &order.Payment.PaymentDT, &order.Payment.Bank, &order.Payment.DeliveryCost,
 		&order.Payment.GoodsTotal, &order.Payment.CustomFee)
@@ -121,8 +351,8 @@ func (pg *PGStorage) GetOrderByID(ctx context.Context, orderID string) (models.O
 		return models.Order{}, fmt.Errorf("cannot scan info from Payments err:%w", err)
 	}
 
-	rows, err := pg.db.QueryContext(ctx, "SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status"+
-		" FROM Items WHERE order_id = $1", orderID)
+	rows, err := db.QueryContext(ctx, "SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status"+
+		" FROM Items WHERE order_id = $1 AND order_date = $2", orderID, order.DateCreated)
 	if err != nil {
 		return models.Order{}, fmt.Errorf("cannot get info about items err:%w", err)
 	}
@@ -144,35 +374,445 @@ func (pg *PGStorage) GetOrderByID(ctx context.Context, orderID string) (models.O
 		return models.Order{}, fmt.Errorf("error while scanning rows err:%w", err)
 	}
 
+	order.OrderUID = orderID
 	order.Items = items
 
 	return order, nil
 
 }
 
+// orderIDPageSize is how many order ids a page of a full scan reads.
+// Each page gets its own query timeout, so the scans at startup do not
+// have to fit the whole table into one.
+var orderIDPageSize = 1000
+
+// GetAllOrderID lists the live orders page by page.
 func (pg *PGStorage) GetAllOrderID(ctx context.Context) ([]string, error) {
 
 	var IDs []string
+	after := ""
+	for {
+		var page []string
+		err := pg.read(ctx, func(ctx context.Context, db *sql.DB) error {
+			var err error
+			page, err = orderIDPage(ctx, db, "SELECT order_uid FROM Orders WHERE order_uid > $1 ORDER BY order_uid LIMIT $2", after)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		IDs = append(IDs, page...)
+		if len(page) < orderIDPageSize {
+			return IDs, nil
+		}
+		after = page[len(page)-1]
+	}
+}
+
+// storedOrderIDs returns a page of the live and archived orders after the
+// given order_uid; OrderKeys holds both.
+func (pg *PGStorage) storedOrderIDs(ctx context.Context, after string) ([]string, error) {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	return orderIDPage(ctx, pg.db, "SELECT key FROM OrderKeys WHERE kind = 'order' AND key > $1 ORDER BY key LIMIT $2", after)
+}
+
+func orderIDPage(ctx context.Context, db *sql.DB, query, after string) ([]string, error) {
 
-	rows, err := pg.db.QueryContext(ctx, "SELECT order_uid FROM Orders")
+	rows, err := db.QueryContext(ctx, query, after, orderIDPageSize)
 	if err != nil {
-		return nil, fmt.Errorf("cannot get all orderID from Orders err:%w", err)
+		return nil, fmt.Errorf("cannot get order ids err:%w", err)
 	}
+	defer rows.Close()
 
+	var IDs []string
 	for rows.Next() {
 		var id string
-		err := rows.Scan(&id)
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("cannot scan id err:%w", err)
+		}
+		IDs = append(IDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	return IDs, nil
+}
+
+// UpdateOrderStatus applies the item statuses of a repeated order message.
+// Messages for one order_uid share a partition, so they arrive in the order
+// they were produced and the last one processed is the current state.
+func (pg *PGStorage) UpdateOrderStatus(ctx context.Context, order models.Order) error {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction err:%w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				pg.log.Error("rollback failed: %v", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	for _, item := range order.Items {
+
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, "UPDATE Items SET status = $1 WHERE chrt_id = $2 AND order_id = $3",
+			item.Status, item.ChrtID, order.OrderUID)
 		if err != nil {
-			return nil, fmt.Errorf("cannot scan id")
+			return fmt.Errorf("cannot update status in table Items err: %w", err)
 		}
 
-		IDs = append(IDs, id)
+		var affected int64
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("cannot get affected rows err: %w", err)
+		}
+		if affected == 0 {
+			err = fmt.Errorf("item %d of order %s: %w", item.ChrtID, order.OrderUID, errs.ErrOrderNotFound)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("cannot commit transaction err:%w", err)
+	}
+
+	return nil
+
+}
+
+func (pg *PGStorage) ListOrders(ctx context.Context, filter ListFilter) ([]string, error) {
+
+	keys, err := pg.listOrderKeys(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var IDs []string
+	for _, key := range keys {
+		IDs = append(IDs, key.OrderUID)
+	}
+
+	return IDs, nil
+}
+
+// listOrderKeys returns the sort key of each listed order, so pages from
+// several shards can be merged.
+func (pg *PGStorage) listOrderKeys(ctx context.Context, filter ListFilter) ([]Cursor, error) {
+
+	query := "SELECT order_uid, date_created FROM Orders WHERE TRUE"
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.CustomerID != "" {
+		query += " AND customer_id = " + arg(filter.CustomerID)
+	}
+	if filter.DeliveryService != "" {
+		query += " AND delivery_service = " + arg(filter.DeliveryService)
+	}
+	// Date bounds are plain comparisons on the partition key, repeated for
+	// Delivery, so Postgres skips the months outside the range.
+	dates := func(column string) string {
+		var cond string
+		if !filter.CreatedAfter.IsZero() {
+			cond += " AND " + column + " >= " + arg(filter.CreatedAfter)
+		}
+		if !filter.CreatedBefore.IsZero() {
+			cond += " AND " + column + " < " + arg(filter.CreatedBefore)
+		}
+		if filter.After != nil {
+			cond += " AND " + column + " <= " + arg(filter.After.DateCreated)
+		}
+		return cond
+	}
+
+	if filter.Email != "" {
+		query += " AND (order_uid, date_created) IN (SELECT order_id, order_date FROM Delivery WHERE " +
+			pg.deliveryMatch("email", encryption.FieldEmail, filter.Email, arg) + dates("order_date") + ")"
+	}
+	if filter.Phone != "" {
+		query += " AND (order_uid, date_created) IN (SELECT order_id, order_date FROM Delivery WHERE " +
+			pg.deliveryMatch("phone", encryption.FieldPhone, filter.Phone, arg) + dates("order_date") + ")"
+	}
+	query += dates("date_created")
+	if filter.After != nil {
+		query += " AND (date_created, order_uid) < (" + arg(filter.After.DateCreated) + ", " + arg(filter.After.OrderUID) + ")"
+	}
+	query += " ORDER BY date_created DESC, order_uid DESC LIMIT " + arg(filter.Limit)
+
+	var keys []Cursor
+	err := pg.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		keys, err = scanOrderKeys(db.QueryContext(ctx, query, args...))
+		return err
+	})
+
+	return keys, err
+}
+
+func scanOrderKeys(rows *sql.Rows, err error) ([]Cursor, error) {
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot list orders err:%w", err)
+	}
+	defer rows.Close()
+
+	var keys []Cursor
+	for rows.Next() {
+		var key Cursor
+		if err := rows.Scan(&key.OrderUID, &key.DateCreated); err != nil {
+			return nil, fmt.Errorf("cannot scan id err:%w", err)
+		}
+		keys = append(keys, key)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error while scanning rows err:%w", err)
 	}
 
-	return IDs, nil
+	return keys, nil
+}
+
+// GetOrdersByIDs loads several orders with a single query; items are
+// aggregated to JSON per order. Orders moved to the archive are read from
+// there, unknown IDs are skipped.
+func (pg *PGStorage) GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, error) {
+
+	if len(orderIDs) == 0 {
+		return nil, nil
+	}
+
+	var orders []models.Order
+	err := pg.read(ctx, func(ctx context.Context, db *sql.DB) error {
+		var err error
+		orders, err = pg.getOrdersByIDs(ctx, db, orderIDs)
+		return err
+	})
+
+	return orders, err
+}
+
+func (pg *PGStorage) getOrdersByIDs(ctx context.Context, db *sql.DB, orderIDs []string) ([]models.Order, error) {
+
+	orders, err := pg.queryOrders(ctx, db, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orders) == len(orderIDs) {
+		return orders, nil
+	}
+
+	found := make(map[string]bool, len(orders))
+	for _, order := range orders {
+		found[order.OrderUID] = true
+	}
+	var missing []string
+	for _, id := range orderIDs {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	archived, err := pg.queryArchivedOrders(ctx, db, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(orders, archived...), nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (pg *PGStorage) queryOrders(ctx context.Context, q querier, orderIDs []string) ([]models.Order, error) {
+
+	rows, err := q.QueryContext(ctx, "SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,"+
+		" o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,"+
+		" d.delivery_id, d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,"+
+		" p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank,"+
+		" p.delivery_cost, p.goods_total, p.custom_fee,"+
+		" (SELECT COALESCE(json_agg(json_build_object('chrt_id', i.chrt_id, 'track_number', i.track_number,"+
+		" 'price', i.price, 'rid', i.rid, 'name', i.name, 'sale', i.sale, 'size', i.size,"+
+		" 'total_price', i.total_price, 'nm_id', i.nm_id, 'brand', i.brand, 'status', i.status)), '[]')"+
+		" FROM Items i WHERE i.order_id = o.order_uid AND i.order_date = o.date_created)"+
+		" FROM Orders o JOIN Delivery d ON d.order_id = o.order_uid AND d.order_date = o.date_created"+
+		" JOIN Payments p ON p.order_id = o.order_uid AND p.order_date = o.date_created"+
+		" WHERE o.order_uid = ANY($1)", pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get orders err:%w", err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var order models.Order
+		var items []byte
+
+		if err := rows.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+			&order.CustomerID, &order.DeliveryService, &order.ShardKey, &order.SmID, &order.DateCreated, &order.OofShard,
+			&order.Delivery.DeliveryID, &order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City,
+			&order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
+			&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider,
+			&order.Payment.Amount, &order.Payment.PaymentDT, &order.Payment.Bank, &order.Payment.DeliveryCost,
+			&order.Payment.GoodsTotal, &order.Payment.CustomFee, &items); err != nil {
+			return nil, fmt.Errorf("cannot scan order err:%w", err)
+		}
+
+		order.Delivery, err = pg.keyring.DecryptDelivery(order.Delivery)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt delivery of %s err:%w", order.OrderUID, err)
+		}
+
+		if err := json.Unmarshal(items, &order.Items); err != nil {
+			return nil, fmt.Errorf("cannot unmarshal items err:%w", err)
+		}
+		if len(order.Items) == 0 {
+			order.Items = nil
+		}
+
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	return orders, nil
+}
+
+// deliveryMatch compares a delivery column with a value. Encrypted columns
+// are matched through their blind index.
+func (pg *PGStorage) deliveryMatch(column, field, value string, arg func(v any) string) string {
+	if pg.keyring == nil {
+		if column == "email" {
+			return "lower(trim(email)) = " + arg(encryption.Normalize(field, value))
+		}
+		return "regexp_replace(phone, '[^0-9]', '', 'g') = " + arg(encryption.Normalize(field, value))
+	}
+	return column + "_bidx = " + arg(pg.keyring.BlindIndex(field, value))
+}
+
+// ReencryptDeliveries rewrites deliveries that are not sealed with the
+// primary key: rows stored before encryption was enabled or before the last
+// rotation. Archived orders are rewritten once Delivery is done. Locked rows
+// are skipped, so several instances can run it.
+func (pg *PGStorage) ReencryptDeliveries(ctx context.Context, limit int) (int, error) {
+
+	if pg.keyring == nil {
+		return 0, nil
+	}
+
+	n, err := pg.reencryptDeliveryRows(ctx, limit)
+	if err != nil || n == limit {
+		return n, err
+	}
+
+	m, err := pg.reencryptArchive(ctx, limit-n)
+	return n + m, err
+}
+
+// notSealed matches rows where any of the columns holds a value that is not
+// sealed with the primary key; $1 is the sealed prefix pattern.
+func notSealed(columns ...string) string {
+	conds := make([]string, 0, len(columns))
+	for _, c := range columns {
+		conds = append(conds, "(COALESCE("+c+", '') = '' OR "+c+" LIKE $1)")
+	}
+	return "NOT (" + strings.Join(conds, " AND ") + ")"
+}
+
+func (pg *PGStorage) reencryptDeliveryRows(ctx context.Context, limit int) (n int, err error) {
+
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("cannot start transaction err:%w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				pg.log.Error("rollback failed: %v", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, "SELECT delivery_id, order_date, name, phone, address, COALESCE(email, '') FROM Delivery"+
+		" WHERE "+notSealed("name", "phone", "address", "email")+
+		" ORDER BY delivery_id LIMIT $2 FOR UPDATE SKIP LOCKED", pg.keyring.SealedPrefix()+"%", limit)
+	if err != nil {
+		return 0, fmt.Errorf("cannot select deliveries to re-encrypt err:%w", err)
+	}
+
+	var deliveries []models.Delivery
+	var dates []time.Time
+	for rows.Next() {
+		var d models.Delivery
+		var date time.Time
+		if err = rows.Scan(&d.DeliveryID, &date, &d.Name, &d.Phone, &d.Address, &d.Email); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("cannot scan delivery err:%w", err)
+		}
+		deliveries = append(deliveries, d)
+		dates = append(dates, date)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	for i, d := range deliveries {
+
+		var plain, sealed models.Delivery
+		plain, err = pg.keyring.DecryptDelivery(d)
+		if err != nil {
+			return 0, fmt.Errorf("cannot decrypt delivery %s err:%w", d.DeliveryID, err)
+		}
+		sealed, err = pg.keyring.RewrapDelivery(d)
+		if err != nil {
+			return 0, fmt.Errorf("cannot re-encrypt delivery %s err:%w", d.DeliveryID, err)
+		}
+		idx := pg.keyring.DeliveryIndexes(plain)
+
+		_, err = tx.ExecContext(ctx, "UPDATE Delivery SET name = $1, phone = $2, address = $3, email = $4,"+
+			" email_bidx = $5, phone_bidx = $6 WHERE delivery_id = $7 AND order_date = $8",
+			sealed.Name, sealed.Phone, sealed.Address, sealed.Email, nullable(idx.Email), nullable(idx.Phone), d.DeliveryID, dates[i])
+		if err != nil {
+			return 0, fmt.Errorf("cannot update delivery %s err:%w", d.DeliveryID, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("cannot commit transaction err:%w", err)
+	}
+
+	return len(deliveries), nil
+}
+
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
 }
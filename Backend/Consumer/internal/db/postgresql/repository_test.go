@@ -1,14 +1,25 @@
 package postgresql
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/LootNex/OrderService/Consumer/internal/models"
+	"github.com/LootNex/OrderService/Consumer/internal/encryption"
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/outbox"
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 )
@@ -45,7 +56,7 @@ func TestSaveNewOrder(t *testing.T) {
 
 	log := zaptest.NewLogger(t)
 
-	storage := NewPGStorage(db, log)
+	storage := NewPGStorage(db, nil, log)
 
 	order := models.Order{
 		OrderUID: "123",
@@ -62,6 +73,12 @@ func TestSaveNewOrder(t *testing.T) {
 
 	mock.ExpectBegin()
 
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO OrderKeys(kind, key, order_uid) VALUES ('order', $1, $1)")).WithArgs(order.OrderUID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO OrderKeys(kind, key, order_uid) VALUES ('transaction', $2, $1), ('chrt_id', $3, $1)")).
+		WithArgs(order.OrderUID, order.Payment.Transaction, "1").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+
 	mock.ExpectExec("INSERT INTO Orders").
 		WithArgs(order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
 			order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard).
@@ -69,19 +86,23 @@ func TestSaveNewOrder(t *testing.T) {
 
 	mock.ExpectExec("INSERT INTO Delivery").
 		WithArgs(order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City,
-			order.Delivery.Address, order.Delivery.Region, order.Delivery.Email, order.OrderUID).
+			order.Delivery.Address, order.Delivery.Region, order.Delivery.Email, order.OrderUID, nil, nil, order.DateCreated).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectExec("INSERT INTO Payments").
 		WithArgs(order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider,
 			order.Payment.Amount, order.Payment.PaymentDT, order.Payment.Bank, order.Payment.DeliveryCost,
-			order.Payment.GoodsTotal, order.Payment.CustomFee, order.OrderUID).
+			order.Payment.GoodsTotal, order.Payment.CustomFee, order.OrderUID, order.DateCreated).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectExec("INSERT INTO Items").
 		WithArgs(order.Items[0].ChrtID, order.Items[0].TrackNumber, order.Items[0].Price, order.Items[0].RID,
 			order.Items[0].Name, order.Items[0].Sale, order.Items[0].Size, order.Items[0].TotalPrice,
-			order.Items[0].NmID, order.Items[0].Brand, order.Items[0].Status, order.OrderUID).
+			order.Items[0].NmID, order.Items[0].Brand, order.Items[0].Status, order.OrderUID, order.DateCreated).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec("INSERT INTO Outbox").
+		WithArgs(outbox.EventOrderAccepted, order.OrderUID, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectCommit()
@@ -104,7 +125,7 @@ func TestGetOrderByID(t *testing.T) {
 	defer db.Close()
 
 	log, _ := zap.NewDevelopment()
-	pg := NewPGStorage(db, log)
+	pg := NewPGStorage(db, nil, log)
 
 	orderID := "order123"
 
@@ -121,17 +142,17 @@ func TestGetOrderByID(t *testing.T) {
 	mockDelivery := sqlmock.NewRows([]string{"delivery_id", "name", "phone", "zip", "city", "address", "region", "email"}).
 		AddRow(1, "John Doe", "123456", "11111", "City", "Street 1", "Region", "john@test.com")
 	mock.ExpectQuery("SELECT delivery_id, name, phone, zip, city, address, region, email FROM Delivery").
-		WithArgs(orderID).WillReturnRows(mockDelivery)
+		WithArgs(orderID, "2025-09-01T10:00:00Z").WillReturnRows(mockDelivery)
 
 	mockPayments := sqlmock.NewRows([]string{"transaction", "request_id", "currency", "provider", "amount", "payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee"}).
 		AddRow("trx123", "req1", "USD", "prov1", 100.0, 1234567890, "bank1", 10.0, 90.0, 0.0)
 	mock.ExpectQuery("SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee FROM Payments").
-		WithArgs(orderID).WillReturnRows(mockPayments)
+		WithArgs(orderID, "2025-09-01T10:00:00Z").WillReturnRows(mockPayments)
 
 	mockItems := sqlmock.NewRows([]string{"chrt_id", "track_number", "price", "rid", "name", "sale", "size", "total_price", "nm_id", "brand", "status"}).
 		AddRow(1, "T1", 100.0, "rid1", "ItemName", 0.0, "M", 100.0, 101, "BrandX", 1)
 	mock.ExpectQuery("SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM Items").
-		WithArgs(orderID).WillReturnRows(mockItems)
+		WithArgs(orderID, "2025-09-01T10:00:00Z").WillReturnRows(mockItems)
 
 	ctx := context.Background()
 	order, err := pg.GetOrderByID(ctx, orderID)
@@ -139,6 +160,9 @@ func TestGetOrderByID(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
+	if order.OrderUID != orderID {
+		t.Errorf("expected order_uid %v, got %v", orderID, order.OrderUID)
+	}
 	if order.TrackNumber != "TRACK123" {
 		t.Errorf("expected track_number TRACK123, got %v", order.TrackNumber)
 	}
@@ -161,7 +185,7 @@ func TestGetAllOrderByID(t *testing.T) {
 	defer db.Close()
 
 	log, _ := zap.NewDevelopment()
-	pg := NewPGStorage(db, log)
+	pg := NewPGStorage(db, nil, log)
 
 	orderID := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}
 
@@ -183,3 +207,487 @@ func TestGetAllOrderByID(t *testing.T) {
 	}
 
 }
+
+func TestSaveNewOrder_Exists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	storage := NewPGStorage(db, nil, zaptest.NewLogger(t))
+
+	// The order_uid is taken, whatever month the stored order is in.
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO OrderKeys").WithArgs("123").WillReturnError(&pq.Error{Code: uniqueViolation})
+	mock.ExpectRollback()
+
+	err = storage.SaveNewOrder(context.Background(), models.Order{OrderUID: "123", DateCreated: "2026-10-19T00:00:00Z"})
+	if !errors.Is(err, errs.ErrOrderExists) {
+		t.Errorf("expected ErrOrderExists, got %v", err)
+	}
+
+	// The payment transaction belongs to another order.
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO OrderKeys").WithArgs("123").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO OrderKeys").WithArgs("123", "tx_1").WillReturnError(&pq.Error{Code: uniqueViolation})
+	mock.ExpectRollback()
+
+	err = storage.SaveNewOrder(context.Background(), models.Order{OrderUID: "123", Payment: models.Payment{Transaction: "tx_1"}})
+	if !errors.Is(err, errs.ErrInvalidOrder) {
+		t.Errorf("expected ErrInvalidOrder, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+// Two saves of one order race: Postgres makes the second insert into
+// OrderKeys wait for the first transaction and then fail, so exactly one
+// save stores the order.
+func TestSaveNewOrder_Concurrent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+
+	storage := NewPGStorage(db, nil, zaptest.NewLogger(t))
+
+	order := models.Order{OrderUID: "123", Payment: models.Payment{Transaction: "tx_1"}}
+
+	mock.ExpectBegin()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("VALUES ('order', $1, $1)")).WithArgs("123").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("VALUES ('order', $1, $1)")).WithArgs("123").WillReturnError(&pq.Error{Code: uniqueViolation})
+	mock.ExpectExec(regexp.QuoteMeta("VALUES ('transaction', $2, $1)")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Orders").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Delivery").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	results := make(chan error, 2)
+	for range 2 {
+		go func() { results <- storage.SaveNewOrder(context.Background(), order) }()
+	}
+
+	var saved, exists int
+	for range 2 {
+		switch err := <-results; {
+		case err == nil:
+			saved++
+		case errors.Is(err, errs.ErrOrderExists):
+			exists++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if saved != 1 || exists != 1 {
+		t.Errorf("expected one save and one ErrOrderExists, got %d and %d", saved, exists)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReplaceOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	storage := NewPGStorage(db, nil, zaptest.NewLogger(t))
+
+	order := models.Order{OrderUID: "123", Payment: models.Payment{Transaction: "tx_1"}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM Orders WHERE order_uid = $1")).WithArgs("123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM OrdersArchive WHERE order_uid = $1")).WithArgs("123").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM OrderKeys WHERE order_uid = $1")).WithArgs("123").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO OrderKeys").WithArgs("123").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO OrderKeys").WithArgs("123", "tx_1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Orders").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Delivery").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Outbox").
+		WithArgs(outbox.EventOrderReplaced, order.OrderUID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := storage.ReplaceOrder(context.Background(), order); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Nothing to replace.
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM Orders").WithArgs("123").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM OrdersArchive").WithArgs("123").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if err := storage.ReplaceOrder(context.Background(), order); !errors.Is(err, errs.ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	order := models.Order{
+		OrderUID: "123",
+		Items: []models.Item{
+			{ChrtID: 1, Status: 203},
+			{ChrtID: 2, Status: 204},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		affected int64
+		wantErr  error
+	}{
+		{
+			name:     "success",
+			affected: 1,
+			wantErr:  nil,
+		},
+		{
+			name:     "unknown item",
+			affected: 0,
+			wantErr:  errs.ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			storage := NewPGStorage(db, nil, zaptest.NewLogger(t))
+
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE Items SET status").
+				WithArgs(203, 1, order.OrderUID).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			if tt.wantErr == nil {
+				mock.ExpectExec("UPDATE Items SET status").
+					WithArgs(204, 2, order.OrderUID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err = storage.UpdateOrderStatus(context.Background(), order)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetOrderByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	defer db.Close()
+
+	pg := NewPGStorage(db, nil, zaptest.NewLogger(t))
+
+	mock.ExpectQuery("FROM Orders").WithArgs("missing").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("FROM OrdersArchive").WithArgs(pq.Array([]string{"missing"})).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}))
+
+	if _, err := pg.GetOrderByID(context.Background(), "missing"); !errors.Is(err, errs.ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	defer db.Close()
+
+	pg := NewPGStorage(db, nil, zaptest.NewLogger(t))
+
+	from := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	cursor := Cursor{DateCreated: from.Add(time.Hour), OrderUID: "b"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT order_uid, date_created FROM Orders WHERE TRUE AND customer_id = $1 AND date_created >= $2"+
+		" AND date_created <= $3 AND (date_created, order_uid) < ($4, $5) ORDER BY date_created DESC, order_uid DESC LIMIT $6")).
+		WithArgs("alice", from, cursor.DateCreated, cursor.DateCreated, cursor.OrderUID, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "date_created"}).AddRow("a", from))
+
+	ids, err := pg.ListOrders(context.Background(), ListFilter{
+		CustomerID:   "alice",
+		CreatedAfter: from,
+		After:        &cursor,
+		Limit:        2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"a"}) {
+		t.Errorf("unexpected ids %v", ids)
+	}
+
+	// The date range is repeated for Delivery so its partitions are pruned too.
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT order_uid, date_created FROM Orders WHERE TRUE AND (order_uid, date_created) IN"+
+		" (SELECT order_id, order_date FROM Delivery WHERE regexp_replace(phone, '[^0-9]', '', 'g') = $1 AND order_date < $2)"+
+		" AND date_created < $3 ORDER BY date_created DESC, order_uid DESC LIMIT $4")).
+		WithArgs("9720000000", from, from, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+
+	if _, err := pg.ListOrders(context.Background(), ListFilter{Phone: "+972 000-0000", CreatedBefore: from, Limit: 10}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetOrdersByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	defer db.Close()
+
+	pg := NewPGStorage(db, nil, zaptest.NewLogger(t))
+
+	rows := sqlmock.NewRows([]string{
+		"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id", "delivery_service",
+		"shardkey", "sm_id", "date_created", "oof_shard",
+		"delivery_id", "name", "phone", "zip", "city", "address", "region", "email",
+		"transaction", "request_id", "currency", "provider", "amount", "payment_dt", "bank",
+		"delivery_cost", "goods_total", "custom_fee", "items",
+	}).AddRow(
+		"1", "TRACK1", "WBIL", "en", "", "alice", "meest", "9", 99, "2021-11-26T06:22:19Z", "1",
+		"7", "John Doe", "+9720000000", "2639809", "Kiryat Mozkin", "Ploshad Mira 15", "Kraiot", "john@test.com",
+		"tx1", "", "USD", "wbpay", 1817, 1637907727, "alpha", 1500, 317, 0,
+		[]byte(`[{"chrt_id": 9934930, "track_number": "TRACK1", "price": 453, "status": 202}]`),
+	)
+	mock.ExpectQuery("FROM Orders o JOIN Delivery d").WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
+	mock.ExpectQuery("FROM OrdersArchive").WithArgs(pq.Array([]string{"2", "3"})).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).AddRow([]byte(`{"order_uid": "2", "customer_id": "bob"}`)))
+
+	orders, err := pg.GetOrdersByIDs(context.Background(), []string{"1", "2", "3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(orders) != 2 || orders[1].OrderUID != "2" || orders[1].CustomerID != "bob" {
+		t.Fatalf("expected order 1 and archived order 2, got %+v", orders)
+	}
+	if orders[0].Delivery.Name != "John Doe" || orders[0].Payment.Amount != 1817 {
+		t.Errorf("unexpected order %+v", orders[0])
+	}
+	if len(orders[0].Items) != 1 || orders[0].Items[0].ChrtID != 9934930 || orders[0].Items[0].Status != 202 {
+		t.Errorf("unexpected items %+v", orders[0].Items)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// captureArg records the value it is matched against.
+type captureArg struct {
+	value *string
+}
+
+func (c captureArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*c.value = s
+	return ok
+}
+
+type captureBytes struct {
+	value *[]byte
+}
+
+func (c captureBytes) Match(v driver.Value) bool {
+	b, ok := v.([]byte)
+	*c.value = b
+	return ok
+}
+
+func testKeyring(t *testing.T, primary string, ids ...string) *encryption.Keyring {
+	t.Helper()
+
+	keys := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte(id[len(id)-1:]), 32)
+	}
+
+	k, err := encryption.NewKeyring(primary, keys, bytes.Repeat([]byte{0xaa}, 32))
+	if err != nil {
+		t.Fatalf("cannot create keyring: %v", err)
+	}
+	return k
+}
+
+func TestEncryptedDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	defer db.Close()
+
+	keyring := testKeyring(t, "k1", "k1")
+	pg := NewPGStorage(db, keyring, zaptest.NewLogger(t))
+
+	order := models.Order{
+		OrderUID: "b563feb7b2b84b6test",
+		Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com"},
+	}
+
+	var name, phone, address, email string
+	var payload []byte
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO OrderKeys").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO OrderKeys").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Orders").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Delivery").
+		WithArgs(captureArg{&name}, captureArg{&phone}, "", "Kiryat Mozkin", captureArg{&address}, "Kraiot", captureArg{&email},
+			order.OrderUID, keyring.BlindIndex(encryption.FieldEmail, "test@gmail.com"), keyring.BlindIndex(encryption.FieldPhone, "+9720000000"),
+			order.DateCreated).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO Outbox").
+		WithArgs(outbox.EventOrderAccepted, order.OrderUID, captureBytes{&payload}, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := pg.SaveNewOrder(context.Background(), order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, v := range []string{name, phone, address, email} {
+		if !encryption.IsSealed(v) {
+			t.Errorf("expected an encrypted value, got %q", v)
+		}
+	}
+
+	var event outbox.OrderAccepted
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatalf("cannot decode outbox payload: %v", err)
+	}
+	for _, v := range []string{event.Order.Delivery.Name, event.Order.Delivery.Phone, event.Order.Delivery.Address, event.Order.Delivery.Email} {
+		if !encryption.IsSealed(v) {
+			t.Errorf("expected an encrypted value in the outbox event, got %q", v)
+		}
+	}
+	if event.Order.Delivery.City != "Kiryat Mozkin" {
+		t.Errorf("expected the city in plain text, got %q", event.Order.Delivery.City)
+	}
+
+	mock.ExpectQuery("SELECT track_number").WithArgs(order.OrderUID).
+		WillReturnRows(sqlmock.NewRows([]string{"track_number", "entry", "locale", "internal_signature", "customer_id",
+			"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard"}).
+			AddRow("", "", "", "", "", "", "", 0, "", ""))
+	mock.ExpectQuery("FROM Delivery").WithArgs(order.OrderUID, "").
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id", "name", "phone", "zip", "city", "address", "region", "email"}).
+			AddRow("1", name, phone, "", "Kiryat Mozkin", address, "Kraiot", email))
+	mock.ExpectQuery("FROM Payments").WithArgs(order.OrderUID, "").
+		WillReturnRows(sqlmock.NewRows([]string{"transaction", "request_id", "currency", "provider", "amount", "payment_dt",
+			"bank", "delivery_cost", "goods_total", "custom_fee"}).AddRow("", "", "", "", 0, 0, "", 0, 0, 0))
+	mock.ExpectQuery("FROM Items").WithArgs(order.OrderUID, "").
+		WillReturnRows(sqlmock.NewRows([]string{"chrt_id"}))
+
+	got, err := pg.GetOrderByID(context.Background(), order.OrderUID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order.Delivery.DeliveryID = "1"
+	if got.Delivery != order.Delivery {
+		t.Errorf("expected the decrypted delivery, got %+v", got.Delivery)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT order_uid, date_created FROM Orders WHERE TRUE AND (order_uid, date_created) IN"+
+		" (SELECT order_id, order_date FROM Delivery WHERE email_bidx = $1)")).
+		WithArgs(keyring.BlindIndex(encryption.FieldEmail, "test@gmail.com"), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "date_created"}).AddRow(order.OrderUID, time.Time{}))
+
+	if _, err := pg.ListOrders(context.Background(), ListFilter{Email: "Test@Gmail.com", Limit: 10}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReencryptDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock db: %v", err)
+	}
+	defer db.Close()
+
+	old, _ := testKeyring(t, "k1", "k1").Encrypt(encryption.FieldName, "Test Testov")
+	keyring := testKeyring(t, "k2", "k1", "k2")
+	pg := NewPGStorage(db, keyring, zaptest.NewLogger(t))
+
+	var name, phone string
+	mock.ExpectBegin()
+	created := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT delivery_id, order_date, name, phone, address, COALESCE\\(email, ''\\) FROM Delivery").
+		WithArgs("enc:v1:k2:%", 100).
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id", "order_date", "name", "phone", "address", "email"}).
+			AddRow("1", created, old, "+9720000000", "", ""))
+	mock.ExpectExec("UPDATE Delivery SET").
+		WithArgs(captureArg{&name}, captureArg{&phone}, "", "", nil, keyring.BlindIndex(encryption.FieldPhone, "+9720000000"), "1", created).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var archived []byte
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT order_uid, payload FROM OrdersArchive").
+		WithArgs("enc:v1:k2:%", 99).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "payload"}).
+			AddRow("old", []byte(`{"order_uid": "old", "delivery": {"name": "Legacy Plain"}}`)))
+	mock.ExpectExec("UPDATE OrdersArchive SET payload").
+		WithArgs(captureBytes{&archived}, "old").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := pg.ReencryptDeliveries(context.Background(), 100)
+	if err != nil || n != 2 {
+		t.Fatalf("expected two rows, got %d, %v", n, err)
+	}
+	if bytes.Contains(archived, []byte("Legacy Plain")) || !bytes.Contains(archived, []byte("enc:v1:k2:")) {
+		t.Errorf("archived order is not re-encrypted: %s", archived)
+	}
+	if !strings.HasPrefix(name, "enc:v1:k2:") || !strings.HasPrefix(phone, "enc:v1:k2:") {
+		t.Errorf("expected values sealed with the primary key, got %q and %q", name, phone)
+	}
+	if plain, _ := keyring.Decrypt(encryption.FieldName, name); plain != "Test Testov" {
+		t.Errorf("unexpected name %q", plain)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,434 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/gdpr"
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Shard is one of the databases orders are spread over.
+type Shard struct {
+	Name    string
+	Storage *PGStorage
+}
+
+// ShardDirectory records which shard holds each order, so reads by
+// order_uid go to a single database. It lives in the main database.
+type ShardDirectory struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewShardDirectory(db *sql.DB) *ShardDirectory {
+	return &ShardDirectory{db: db}
+}
+
+// SetQueryTimeout bounds every directory query like the storage's.
+func (sd *ShardDirectory) SetQueryTimeout(d time.Duration) {
+	sd.queryTimeout = d
+}
+
+func (sd *ShardDirectory) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if sd.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, sd.queryTimeout)
+}
+
+// Assign records shard for the order unless the order already has one, and
+// returns the shard that holds it.
+func (sd *ShardDirectory) Assign(ctx context.Context, orderID, shard string) (string, error) {
+
+	ctx, cancel := sd.withTimeout(ctx)
+	defer cancel()
+
+	var assigned string
+	err := sd.db.QueryRowContext(ctx, "INSERT INTO OrderShards(order_uid, shard) VALUES ($1, $2)"+
+		" ON CONFLICT (order_uid) DO UPDATE SET order_uid = EXCLUDED.order_uid RETURNING shard", orderID, shard).Scan(&assigned)
+	if err != nil {
+		return "", fmt.Errorf("cannot assign shard err:%w", err)
+	}
+
+	return assigned, nil
+}
+
+// Register records shard for orders that have none.
+func (sd *ShardDirectory) Register(ctx context.Context, orderIDs []string, shard string) error {
+
+	ctx, cancel := sd.withTimeout(ctx)
+	defer cancel()
+
+	_, err := sd.db.ExecContext(ctx, "INSERT INTO OrderShards(order_uid, shard) SELECT unnest($1::text[]), $2"+
+		" ON CONFLICT (order_uid) DO NOTHING", pq.Array(orderIDs), shard)
+	if err != nil {
+		return fmt.Errorf("cannot insert into table OrderShards err:%w", err)
+	}
+
+	return nil
+}
+
+// Lookup returns the shard of each known order.
+func (sd *ShardDirectory) Lookup(ctx context.Context, orderIDs []string) (map[string]string, error) {
+
+	ctx, cancel := sd.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := sd.db.QueryContext(ctx, "SELECT order_uid, shard FROM OrderShards WHERE order_uid = ANY($1)", pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("cannot look up shards err:%w", err)
+	}
+	defer rows.Close()
+
+	shards := make(map[string]string, len(orderIDs))
+	for rows.Next() {
+		var id, shard string
+		if err := rows.Scan(&id, &shard); err != nil {
+			return nil, fmt.Errorf("cannot scan shard err:%w", err)
+		}
+		shards[id] = shard
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	return shards, nil
+}
+
+// ShardedStorage spreads orders over several databases by shardkey. A new
+// order goes to the shard its shardkey hashes to and stays there: the
+// directory keeps the placement, so adding shards moves nothing. Listings
+// query every shard and merge the pages. Orders missing from the directory
+// are looked for on every shard.
+type ShardedStorage struct {
+	shards []Shard
+	byName map[string]*PGStorage
+	dir    *ShardDirectory
+	// main keeps the privacy audit records.
+	main *PGStorage
+	log  *zap.Logger
+}
+
+func NewShardedStorage(main *PGStorage, dir *ShardDirectory, shards []Shard, logg *zap.Logger) (*ShardedStorage, error) {
+
+	if len(shards) == 0 {
+		return nil, errors.New("no shards configured")
+	}
+
+	byName := make(map[string]*PGStorage, len(shards))
+	for _, s := range shards {
+		if s.Name == "" {
+			return nil, errors.New("shard without a name")
+		}
+		if _, ok := byName[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate shard %q", s.Name)
+		}
+		byName[s.Name] = s.Storage
+	}
+
+	return &ShardedStorage{
+		shards: shards,
+		byName: byName,
+		dir:    dir,
+		main:   main,
+		log:    logg,
+	}, nil
+}
+
+// Backfill registers the orders, archived ones included, that each shard
+// held before it was added to the directory. It goes page by page, so each
+// query stays within the query timeout however many orders there are.
+func (ss *ShardedStorage) Backfill(ctx context.Context) error {
+
+	for _, s := range ss.shards {
+		after := ""
+		for {
+			IDs, err := s.Storage.storedOrderIDs(ctx, after)
+			if err != nil {
+				return fmt.Errorf("shard %s: %w", s.Name, err)
+			}
+			if len(IDs) == 0 {
+				break
+			}
+			if err := ss.dir.Register(ctx, IDs, s.Name); err != nil {
+				return fmt.Errorf("shard %s: %w", s.Name, err)
+			}
+			if len(IDs) < orderIDPageSize {
+				break
+			}
+			after = IDs[len(IDs)-1]
+		}
+	}
+
+	return nil
+}
+
+// pick hashes the shardkey, or order_uid when there is none, to a shard.
+func (ss *ShardedStorage) pick(order models.Order) string {
+	key := order.ShardKey
+	if key == "" {
+		key = order.OrderUID
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return ss.shards[h.Sum32()%uint32(len(ss.shards))].Name
+}
+
+func (ss *ShardedStorage) shard(name string) (*PGStorage, error) {
+	st, ok := ss.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("order is on unknown shard %q", name)
+	}
+	return st, nil
+}
+
+// route calls fn on the shard of the order, or on every shard in turn until
+// one has it.
+func (ss *ShardedStorage) route(ctx context.Context, orderID string, fn func(st *PGStorage) error) error {
+
+	placed, err := ss.dir.Lookup(ctx, []string{orderID})
+	if err != nil {
+		return err
+	}
+	if name, ok := placed[orderID]; ok {
+		st, err := ss.shard(name)
+		if err != nil {
+			return err
+		}
+		return fn(st)
+	}
+
+	for _, s := range ss.shards {
+		if err := fn(s.Storage); !errors.Is(err, errs.ErrOrderNotFound) {
+			return err
+		}
+	}
+
+	return errs.ErrOrderNotFound
+}
+
+// each runs fn on all shards concurrently.
+func (ss *ShardedStorage) each(ctx context.Context, fn func(ctx context.Context, i int, st *PGStorage) error) error {
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, s := range ss.shards {
+		g.Go(func() error {
+			if err := fn(gctx, i, s.Storage); err != nil {
+				return fmt.Errorf("shard %s: %w", s.Name, err)
+			}
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+func (ss *ShardedStorage) SaveNewOrder(ctx context.Context, order models.Order) error {
+
+	name, err := ss.dir.Assign(ctx, order.OrderUID, ss.pick(order))
+	if err != nil {
+		return err
+	}
+
+	st, err := ss.shard(name)
+	if err != nil {
+		return err
+	}
+
+	return st.SaveNewOrder(ctx, order)
+}
+
+func (ss *ShardedStorage) GetOrderByID(ctx context.Context, orderID string) (models.Order, error) {
+
+	var order models.Order
+	err := ss.route(ctx, orderID, func(st *PGStorage) error {
+		var err error
+		order, err = st.GetOrderByID(ctx, orderID)
+		return err
+	})
+
+	return order, err
+}
+
+func (ss *ShardedStorage) UpdateOrderStatus(ctx context.Context, order models.Order) error {
+	return ss.route(ctx, order.OrderUID, func(st *PGStorage) error {
+		return st.UpdateOrderStatus(ctx, order)
+	})
+}
+
+func (ss *ShardedStorage) ReplaceOrder(ctx context.Context, order models.Order) error {
+	return ss.route(ctx, order.OrderUID, func(st *PGStorage) error {
+		return st.ReplaceOrder(ctx, order)
+	})
+}
+
+func (ss *ShardedStorage) GetAllOrderID(ctx context.Context) ([]string, error) {
+
+	found := make([][]string, len(ss.shards))
+	err := ss.each(ctx, func(ctx context.Context, i int, st *PGStorage) error {
+		var err error
+		found[i], err = st.GetAllOrderID(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var IDs []string
+	for _, ids := range found {
+		IDs = append(IDs, ids...)
+	}
+
+	return IDs, nil
+}
+
+// ListOrders takes a full page from every shard and keeps the first Limit
+// orders of the merged result, so pages and cursors work as on one
+// database.
+func (ss *ShardedStorage) ListOrders(ctx context.Context, filter ListFilter) ([]string, error) {
+
+	found := make([][]Cursor, len(ss.shards))
+	err := ss.each(ctx, func(ctx context.Context, i int, st *PGStorage) error {
+		var err error
+		found[i], err = st.listOrderKeys(ctx, filter)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Cursor
+	for _, k := range found {
+		keys = append(keys, k...)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].DateCreated.Equal(keys[j].DateCreated) {
+			return keys[i].DateCreated.After(keys[j].DateCreated)
+		}
+		return keys[i].OrderUID > keys[j].OrderUID
+	})
+	if filter.Limit > 0 && len(keys) > filter.Limit {
+		keys = keys[:filter.Limit]
+	}
+
+	var IDs []string
+	for _, key := range keys {
+		IDs = append(IDs, key.OrderUID)
+	}
+
+	return IDs, nil
+}
+
+// GetOrdersByIDs queries each shard once for its orders; orders missing
+// from the directory are asked of every shard.
+func (ss *ShardedStorage) GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, error) {
+
+	if len(orderIDs) == 0 {
+		return nil, nil
+	}
+
+	placed, err := ss.dir.Lookup(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byShard := make(map[string][]string, len(ss.shards))
+	var unplaced []string
+	for _, id := range orderIDs {
+		name, ok := placed[id]
+		if !ok {
+			unplaced = append(unplaced, id)
+			continue
+		}
+		if _, err := ss.shard(name); err != nil {
+			return nil, err
+		}
+		byShard[name] = append(byShard[name], id)
+	}
+
+	found := make([][]models.Order, len(ss.shards))
+	err = ss.each(ctx, func(ctx context.Context, i int, st *PGStorage) error {
+		IDs := append(byShard[ss.shards[i].Name], unplaced...)
+		if len(IDs) == 0 {
+			return nil
+		}
+
+		var err error
+		found[i], err = st.GetOrdersByIDs(ctx, IDs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	for _, o := range found {
+		orders = append(orders, o...)
+	}
+
+	return orders, nil
+}
+
+func (ss *ShardedStorage) CustomerOrderIDs(ctx context.Context, customerID string) ([]string, error) {
+
+	found := make([][]string, len(ss.shards))
+	err := ss.each(ctx, func(ctx context.Context, i int, st *PGStorage) error {
+		var err error
+		found[i], err = st.CustomerOrderIDs(ctx, customerID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var IDs []string
+	for _, ids := range found {
+		IDs = append(IDs, ids...)
+	}
+
+	return IDs, nil
+}
+
+// AnonymizeCustomer anonymizes the customer on every shard, each in its own
+// transaction, and then writes one audit record to the main database. If a
+// shard fails, the request can simply be repeated.
+func (ss *ShardedStorage) AnonymizeCustomer(ctx context.Context, customerID, pseudonym string, audit gdpr.Audit) ([]string, error) {
+
+	found := make([][]string, len(ss.shards))
+	err := ss.each(ctx, func(ctx context.Context, i int, st *PGStorage) error {
+		var err error
+		found[i], err = st.anonymizeCustomer(ctx, customerID, pseudonym, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var IDs []string
+	for _, ids := range found {
+		IDs = append(IDs, ids...)
+	}
+
+	audit.Orders = len(IDs)
+	if err := ss.main.SaveAudit(ctx, audit); err != nil {
+		return nil, err
+	}
+
+	return IDs, nil
+}
+
+func (ss *ShardedStorage) SaveAudit(ctx context.Context, audit gdpr.Audit) error {
+	return ss.main.SaveAudit(ctx, audit)
+}
@@ -0,0 +1,232 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/gdpr"
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/lib/pq"
+	"go.uber.org/zap/zaptest"
+)
+
+// newTestShards builds a sharded storage over shards "a" and "b"; the
+// directory and audit records live in main.
+func newTestShards(t *testing.T) (ss *ShardedStorage, main, a, b sqlmock.Sqlmock) {
+	t.Helper()
+
+	open := func() (*sql.DB, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to open sqlmock db: %v", err)
+		}
+		t.Cleanup(func() {
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+			db.Close()
+		})
+		return db, mock
+	}
+
+	log := zaptest.NewLogger(t)
+	mainDB, main := open()
+	aDB, a := open()
+	bDB, b := open()
+
+	ss, err := NewShardedStorage(NewPGStorage(mainDB, nil, log), NewShardDirectory(mainDB), []Shard{
+		{Name: "a", Storage: NewPGStorage(aDB, nil, log)},
+		{Name: "b", Storage: NewPGStorage(bDB, nil, log)},
+	}, log)
+	if err != nil {
+		t.Fatalf("cannot create sharded storage: %v", err)
+	}
+
+	return ss, main, a, b
+}
+
+func TestNewShardedStorage(t *testing.T) {
+	tests := []struct {
+		name   string
+		shards []Shard
+	}{
+		{name: "no shards"},
+		{name: "no name", shards: []Shard{{Name: ""}}},
+		{name: "duplicate", shards: []Shard{{Name: "a"}, {Name: "a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewShardedStorage(nil, nil, tt.shards, zaptest.NewLogger(t)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestShardedBackfill(t *testing.T) {
+	pageSize := orderIDPageSize
+	orderIDPageSize = 2
+	t.Cleanup(func() { orderIDPageSize = pageSize })
+
+	ss, main, a, b := newTestShards(t)
+
+	ids := func(IDs ...string) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"key"})
+		for _, id := range IDs {
+			rows.AddRow(id)
+		}
+		return rows
+	}
+
+	a.ExpectQuery("SELECT key FROM OrderKeys").WithArgs("", 2).WillReturnRows(ids("1", "2"))
+	main.ExpectExec("INSERT INTO OrderShards").WithArgs(pq.Array([]string{"1", "2"}), "a").WillReturnResult(sqlmock.NewResult(0, 2))
+	a.ExpectQuery("SELECT key FROM OrderKeys").WithArgs("2", 2).WillReturnRows(ids("3"))
+	main.ExpectExec("INSERT INTO OrderShards").WithArgs(pq.Array([]string{"3"}), "a").WillReturnResult(sqlmock.NewResult(0, 1))
+	b.ExpectQuery("SELECT key FROM OrderKeys").WithArgs("", 2).WillReturnRows(ids())
+
+	if err := ss.Backfill(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestShardPick(t *testing.T) {
+	ss, _, _, _ := newTestShards(t)
+
+	used := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		order := models.Order{OrderUID: fmt.Sprint("order", i), ShardKey: fmt.Sprint(i)}
+		name := ss.pick(order)
+		if again := ss.pick(order); again != name {
+			t.Fatalf("shardkey %d moved from %s to %s", i, name, again)
+		}
+		used[name] = true
+	}
+
+	if !used["a"] || !used["b"] {
+		t.Errorf("expected orders on both shards, got %v", used)
+	}
+}
+
+func TestShardedSaveNewOrder(t *testing.T) {
+	ss, main, _, b := newTestShards(t)
+
+	order := models.Order{OrderUID: "1", ShardKey: "9"}
+
+	// The order was placed on b before, whatever its shardkey hashes to now.
+	main.ExpectQuery("INSERT INTO OrderShards").WithArgs("1", ss.pick(order)).
+		WillReturnRows(sqlmock.NewRows([]string{"shard"}).AddRow("b"))
+	b.ExpectBegin()
+	b.ExpectExec("INSERT INTO OrderKeys").WithArgs("1").WillReturnError(&pq.Error{Code: uniqueViolation})
+	b.ExpectRollback()
+
+	if err := ss.SaveNewOrder(context.Background(), order); !errors.Is(err, errs.ErrOrderExists) {
+		t.Errorf("expected ErrOrderExists from shard b, got %v", err)
+	}
+}
+
+func TestShardedGetOrderByID(t *testing.T) {
+	archived := sqlmock.NewRows([]string{"payload"}).AddRow([]byte(`{"order_uid": "1", "customer_id": "alice"}`))
+
+	t.Run("directory", func(t *testing.T) {
+		ss, main, _, b := newTestShards(t)
+
+		main.ExpectQuery("FROM OrderShards").WithArgs(pq.Array([]string{"1"})).
+			WillReturnRows(sqlmock.NewRows([]string{"order_uid", "shard"}).AddRow("1", "b"))
+		b.ExpectQuery("FROM Orders").WithArgs("1").WillReturnError(sql.ErrNoRows)
+		b.ExpectQuery("FROM OrdersArchive").WillReturnRows(archived)
+
+		order, err := ss.GetOrderByID(context.Background(), "1")
+		if err != nil || order.CustomerID != "alice" {
+			t.Fatalf("expected the order from shard b, got %+v, %v", order, err)
+		}
+	})
+
+	t.Run("every shard", func(t *testing.T) {
+		ss, main, a, b := newTestShards(t)
+
+		main.ExpectQuery("FROM OrderShards").WillReturnRows(sqlmock.NewRows([]string{"order_uid", "shard"}))
+		a.ExpectQuery("FROM Orders").WithArgs("1").WillReturnError(sql.ErrNoRows)
+		a.ExpectQuery("FROM OrdersArchive").WillReturnRows(sqlmock.NewRows([]string{"payload"}))
+		b.ExpectQuery("FROM Orders").WithArgs("1").WillReturnError(sql.ErrNoRows)
+		b.ExpectQuery("FROM OrdersArchive").WillReturnRows(sqlmock.NewRows([]string{"payload"}))
+
+		if _, err := ss.GetOrderByID(context.Background(), "1"); !errors.Is(err, errs.ErrOrderNotFound) {
+			t.Errorf("expected ErrOrderNotFound, got %v", err)
+		}
+	})
+}
+
+func TestShardedListOrders(t *testing.T) {
+	ss, _, a, b := newTestShards(t)
+
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	a.ExpectQuery("SELECT order_uid, date_created FROM Orders").WithArgs("alice", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "date_created"}).
+			AddRow("x", day.Add(3*time.Hour)).AddRow("y", day.Add(time.Hour)))
+	b.ExpectQuery("SELECT order_uid, date_created FROM Orders").WithArgs("alice", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "date_created"}).
+			AddRow("z", day.Add(2*time.Hour)))
+
+	IDs, err := ss.ListOrders(context.Background(), ListFilter{CustomerID: "alice", Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(IDs, []string{"x", "z"}) {
+		t.Errorf("expected the two newest orders across shards, got %v", IDs)
+	}
+}
+
+func TestShardedGetOrdersByIDs(t *testing.T) {
+	ss, main, a, b := newTestShards(t)
+
+	main.ExpectQuery("FROM OrderShards").WithArgs(pq.Array([]string{"1", "2", "3"})).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "shard"}).AddRow("1", "a").AddRow("2", "b"))
+	// Order 3 is not in the directory, so both shards are asked for it.
+	a.ExpectQuery("FROM Orders o JOIN Delivery d").WithArgs(pq.Array([]string{"1", "3"})).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+	a.ExpectQuery("FROM OrdersArchive").WithArgs(pq.Array([]string{"1", "3"})).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).AddRow([]byte(`{"order_uid": "1"}`)))
+	b.ExpectQuery("FROM Orders o JOIN Delivery d").WithArgs(pq.Array([]string{"2", "3"})).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+	b.ExpectQuery("FROM OrdersArchive").WithArgs(pq.Array([]string{"2", "3"})).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).AddRow([]byte(`{"order_uid": "2"}`)))
+
+	orders, err := ss.GetOrdersByIDs(context.Background(), []string{"1", "2", "3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orders) != 2 || orders[0].OrderUID != "1" || orders[1].OrderUID != "2" {
+		t.Errorf("unexpected orders %+v", orders)
+	}
+}
+
+func TestShardedAnonymizeCustomer(t *testing.T) {
+	ss, main, a, b := newTestShards(t)
+
+	audit := gdpr.Audit{Kind: gdpr.KindErase, SubjectHash: "9c1f0a6b", RequestedBy: "jwt:dpo"}
+
+	for _, shard := range []sqlmock.Sqlmock{a, b} {
+		shard.ExpectBegin()
+		shard.ExpectQuery("SELECT order_uid FROM Orders").WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+		shard.ExpectQuery("UPDATE OrdersArchive").WithArgs("erased-1", sqlmock.AnyArg(), "alice").
+			WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow("old"))
+		shard.ExpectCommit()
+	}
+	// One audit record for the whole request, in the main database.
+	main.ExpectExec("INSERT INTO PrivacyRequests").WithArgs(gdpr.KindErase, audit.SubjectHash, "jwt:dpo", 2).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	IDs, err := ss.AnonymizeCustomer(context.Background(), "alice", "erased-1", audit)
+	if err != nil || len(IDs) != 2 {
+		t.Fatalf("expected an archived order from each shard, got %v, %v", IDs, err)
+	}
+}
@@ -0,0 +1,193 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/webhooks"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const webhookColumns = "id, url, event_types, secret, active, failure_count, created_at, disabled_at"
+
+type WebhookStorage struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewWebhookStorage(db *sql.DB, logg *zap.Logger) *WebhookStorage {
+	return &WebhookStorage{
+		db:  db,
+		log: logg,
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row rowScanner) (webhooks.Subscription, error) {
+
+	var sub webhooks.Subscription
+	var disabledAt sql.NullTime
+
+	err := row.Scan(&sub.ID, &sub.URL, (*pq.StringArray)(&sub.EventTypes), &sub.Secret, &sub.Active,
+		&sub.FailureCount, &sub.CreatedAt, &disabledAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhooks.Subscription{}, errs.ErrWebhookNotFound
+	}
+	if err != nil {
+		return webhooks.Subscription{}, fmt.Errorf("cannot scan webhook err:%w", err)
+	}
+
+	if disabledAt.Valid {
+		sub.DisabledAt = &disabledAt.Time
+	}
+
+	return sub, nil
+}
+
+func (ws *WebhookStorage) queryWebhooks(ctx context.Context, query string, args ...any) ([]webhooks.Subscription, error) {
+
+	rows, err := ws.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get webhooks err:%w", err)
+	}
+	defer rows.Close()
+
+	var subs []webhooks.Subscription
+	for rows.Next() {
+		sub, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	return subs, nil
+}
+
+func (ws *WebhookStorage) CreateWebhook(ctx context.Context, sub webhooks.Subscription) (webhooks.Subscription, error) {
+	return scanWebhook(ws.db.QueryRowContext(ctx, "INSERT INTO Webhooks(url, event_types, secret, active)"+
+		" VALUES ($1, $2, $3, $4) RETURNING "+webhookColumns,
+		sub.URL, pq.Array(sub.EventTypes), sub.Secret, sub.Active))
+}
+
+func (ws *WebhookStorage) GetWebhook(ctx context.Context, id int64) (webhooks.Subscription, error) {
+	return scanWebhook(ws.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM Webhooks WHERE id = $1", id))
+}
+
+func (ws *WebhookStorage) ListWebhooks(ctx context.Context) ([]webhooks.Subscription, error) {
+	return ws.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM Webhooks ORDER BY id")
+}
+
+// UpdateWebhook saves the editable fields. Re-activating a webhook clears its
+// failure streak.
+func (ws *WebhookStorage) UpdateWebhook(ctx context.Context, sub webhooks.Subscription) (webhooks.Subscription, error) {
+	return scanWebhook(ws.db.QueryRowContext(ctx, "UPDATE Webhooks SET url = $2, event_types = $3, secret = $4,"+
+		" failure_count = CASE WHEN $5::BOOLEAN AND NOT active THEN 0 ELSE failure_count END,"+
+		" disabled_at = CASE WHEN $5::BOOLEAN THEN NULL ELSE COALESCE(disabled_at, now()) END,"+
+		" active = $5 WHERE id = $1 RETURNING "+webhookColumns,
+		sub.ID, sub.URL, pq.Array(sub.EventTypes), sub.Secret, sub.Active))
+}
+
+func (ws *WebhookStorage) DeleteWebhook(ctx context.Context, id int64) error {
+
+	res, err := ws.db.ExecContext(ctx, "DELETE FROM Webhooks WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("cannot delete webhook err:%w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot get affected rows err: %w", err)
+	}
+	if affected == 0 {
+		return errs.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (ws *WebhookStorage) WebhooksFor(ctx context.Context, eventType string) ([]webhooks.Subscription, error) {
+	return ws.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM Webhooks WHERE active AND $1 = ANY(event_types) ORDER BY id", eventType)
+}
+
+func (ws *WebhookStorage) LogDelivery(ctx context.Context, d webhooks.Delivery) error {
+
+	_, err := ws.db.ExecContext(ctx, "INSERT INTO WebhookDeliveries(webhook_id, event_id, event_type, attempt,"+
+		" status_code, error, duration_ms, delivered_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		d.WebhookID, d.EventID, d.EventType, d.Attempt, sql.NullInt64{Int64: int64(d.StatusCode), Valid: d.StatusCode != 0},
+		sql.NullString{String: d.Error, Valid: d.Error != ""}, d.DurationMS, d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("cannot insert into table WebhookDeliveries err: %w", err)
+	}
+
+	return nil
+}
+
+func (ws *WebhookStorage) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]webhooks.Delivery, error) {
+
+	rows, err := ws.db.QueryContext(ctx, "SELECT id, webhook_id, event_id, event_type, attempt, status_code, error,"+
+		" duration_ms, delivered_at FROM WebhookDeliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2", webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get webhook deliveries err:%w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []webhooks.Delivery
+	for rows.Next() {
+		var d webhooks.Delivery
+		var status sql.NullInt64
+		var errText sql.NullString
+
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Attempt, &status, &errText,
+			&d.DurationMS, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("cannot scan webhook delivery err:%w", err)
+		}
+		d.StatusCode, d.Error = int(status.Int64), errText.String
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while scanning rows err:%w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (ws *WebhookStorage) RecordFailure(ctx context.Context, id int64, disableAfter int) (bool, error) {
+
+	var active bool
+	err := ws.db.QueryRowContext(ctx, "UPDATE Webhooks SET failure_count = failure_count + 1,"+
+		" active = active AND failure_count + 1 < $2,"+
+		" disabled_at = CASE WHEN active AND failure_count + 1 >= $2 THEN now() ELSE disabled_at END"+
+		" WHERE id = $1 RETURNING active", id, disableAfter).Scan(&active)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, errs.ErrWebhookNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot record webhook failure err:%w", err)
+	}
+
+	return !active, nil
+}
+
+func (ws *WebhookStorage) RecordSuccess(ctx context.Context, id int64) error {
+
+	_, err := ws.db.ExecContext(ctx, "UPDATE Webhooks SET failure_count = 0 WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("cannot reset webhook failures err:%w", err)
+	}
+
+	return nil
+}
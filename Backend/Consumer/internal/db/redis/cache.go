@@ -7,39 +7,51 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/LootNex/OrderService/Consumer/internal/encryption"
 	"github.com/LootNex/OrderService/Consumer/internal/errs"
-	"github.com/LootNex/OrderService/Consumer/internal/models"
+	"github.com/LootNex/OrderService/Contract/models"
 	"github.com/go-redis/redis/v8"
 )
 
+const cacheTTL = 10 * time.Second
+
 type RedisComander interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	Get(ctx context.Context, key string) *redis.StringCmd
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
 }
 
+// CacheStorage stores delivery personal data sealed the same way as
+// PGStorage when it has a keyring.
 type CacheStorage struct {
 	rediscache RedisComander
+	keyring    *encryption.Keyring
 }
 
 type CacheManager interface {
 	SaveOrderCache(ctx context.Context, order models.Order) error
 	GetOrderByID(ctx context.Context, orderID string) (models.Order, error)
+	GetOrdersByIDs(ctx context.Context, orderIDs []string) (map[string]models.Order, error)
+	SaveOrdersCache(ctx context.Context, orders []models.Order) error
 }
 
-func NewCacheStorage(redisConn RedisComander) *CacheStorage {
+func NewCacheStorage(redisConn RedisComander, keyring *encryption.Keyring) *CacheStorage {
 	return &CacheStorage{
 		rediscache: redisConn,
+		keyring:    keyring,
 	}
 }
 
 func (cs *CacheStorage) SaveOrderCache(ctx context.Context, order models.Order) error {
 
-	orderJson, err := json.Marshal(order)
+	orderJson, err := cs.marshal(order)
 	if err != nil {
-		return fmt.Errorf("cannot marshall order err:%w", err)
+		return err
 	}
 
-	err = cs.rediscache.Set(ctx, order.OrderUID, orderJson, 10*time.Second).Err()
+	err = cs.rediscache.Set(ctx, order.OrderUID, orderJson, cacheTTL).Err()
 	if err != nil {
 		return fmt.Errorf("cannot save order in redis set err:%w", err)
 	}
@@ -60,10 +72,104 @@ func (cs *CacheStorage) GetOrderByID(ctx context.Context, orderID string) (model
 		}
 	}
 
-	if err = json.Unmarshal([]byte(orderJson), &order); err != nil {
-		return order, fmt.Errorf("cannot unmarshal order err:%w", err)
+	return cs.unmarshal(orderJson)
+
+}
+
+// GetOrdersByIDs reads all orders with one MGET. Orders missing from the
+// cache are simply absent from the result.
+func (cs *CacheStorage) GetOrdersByIDs(ctx context.Context, orderIDs []string) (map[string]models.Order, error) {
+
+	orders := make(map[string]models.Order, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return orders, nil
 	}
 
-	return order, nil
+	values, err := cs.rediscache.MGet(ctx, orderIDs...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis mget err:%w", err)
+	}
+
+	for i, v := range values {
+		orderJson, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		order, err := cs.unmarshal(orderJson)
+		if err != nil {
+			return nil, err
+		}
+		orders[orderIDs[i]] = order
+	}
+
+	return orders, nil
+
+}
+
+// SaveOrdersCache writes the orders in a single pipeline round trip.
+func (cs *CacheStorage) SaveOrdersCache(ctx context.Context, orders []models.Order) error {
+
+	if len(orders) == 0 {
+		return nil
+	}
+
+	_, err := cs.rediscache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, order := range orders {
+			orderJson, err := cs.marshal(order)
+			if err != nil {
+				return err
+			}
+			pipe.Set(ctx, order.OrderUID, orderJson, cacheTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot save orders in redis pipeline err:%w", err)
+	}
+
+	return nil
+}
+
+func (cs *CacheStorage) DeleteOrders(ctx context.Context, orderIDs []string) error {
+
+	if len(orderIDs) == 0 {
+		return nil
+	}
+
+	if err := cs.rediscache.Del(ctx, orderIDs...).Err(); err != nil {
+		return fmt.Errorf("redis del err:%w", err)
+	}
+
+	return nil
+}
+
+func (cs *CacheStorage) marshal(order models.Order) ([]byte, error) {
+
+	order, err := cs.keyring.EncryptOrder(order)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encrypt order err:%w", err)
+	}
 
+	orderJson, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshall order err:%w", err)
+	}
+
+	return orderJson, nil
+}
+
+func (cs *CacheStorage) unmarshal(orderJson string) (models.Order, error) {
+
+	var order models.Order
+	if err := json.Unmarshal([]byte(orderJson), &order); err != nil {
+		return models.Order{}, fmt.Errorf("cannot unmarshal order err:%w", err)
+	}
+
+	order, err := cs.keyring.DecryptOrder(order)
+	if err != nil {
+		return models.Order{}, fmt.Errorf("cannot decrypt order err:%w", err)
+	}
+
+	return order, nil
 }
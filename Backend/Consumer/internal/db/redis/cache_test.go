@@ -1,19 +1,25 @@
 package redis
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/LootNex/OrderService/Consumer/internal/models"
+	"github.com/LootNex/OrderService/Consumer/internal/encryption"
+	"github.com/LootNex/OrderService/Contract/models"
 	"github.com/go-redis/redis/v8"
 )
 
 type MockRedisComander struct {
-	SetFunc func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
-	GetFunc func(ctx context.Context, key string) *redis.StringCmd
+	SetFunc       func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	GetFunc       func(ctx context.Context, key string) *redis.StringCmd
+	MGetFunc      func(ctx context.Context, keys ...string) *redis.SliceCmd
+	DelFunc       func(ctx context.Context, keys ...string) *redis.IntCmd
+	PipelinedFunc func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
 }
 
 func (mRC MockRedisComander) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
@@ -24,6 +30,18 @@ func (mRC MockRedisComander) Get(ctx context.Context, key string) *redis.StringC
 	return mRC.GetFunc(ctx, key)
 }
 
+func (mRC MockRedisComander) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	return mRC.MGetFunc(ctx, keys...)
+}
+
+func (mRC MockRedisComander) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	return mRC.DelFunc(ctx, keys...)
+}
+
+func (mRC MockRedisComander) Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	return mRC.PipelinedFunc(ctx, fn)
+}
+
 func TestSaveOrderCache(t *testing.T) {
 
 	tests := []struct {
@@ -119,3 +137,115 @@ func TestGetOrderByID(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOrdersByIDs(t *testing.T) {
+	cached, _ := json.Marshal(models.Order{OrderUID: "1"})
+
+	CachSt := CacheStorage{
+		rediscache: MockRedisComander{
+			MGetFunc: func(ctx context.Context, keys ...string) *redis.SliceCmd {
+				cmd := redis.NewSliceCmd(ctx)
+				cmd.SetVal([]interface{}{string(cached), nil})
+				return cmd
+			},
+		},
+	}
+
+	orders, err := CachSt.GetOrdersByIDs(context.Background(), []string{"1", "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := orders["1"]; !ok || len(orders) != 1 {
+		t.Errorf("expected only order 1 to be found, got %v", orders)
+	}
+}
+
+func TestSaveOrdersCache(t *testing.T) {
+	var queued int
+
+	CachSt := CacheStorage{
+		rediscache: MockRedisComander{
+			PipelinedFunc: func(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+				pipe := redis.NewClient(&redis.Options{}).Pipeline()
+				if err := fn(pipe); err != nil {
+					return nil, err
+				}
+				queued = pipe.Len()
+				return nil, nil
+			},
+		},
+	}
+
+	orders := []models.Order{{OrderUID: "1"}, {OrderUID: "2"}}
+	if err := CachSt.SaveOrdersCache(context.Background(), orders); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if queued != len(orders) {
+		t.Errorf("expected %d queued commands, got %d", len(orders), queued)
+	}
+}
+
+func TestDeleteOrders(t *testing.T) {
+	var deleted []string
+
+	CachSt := NewCacheStorage(MockRedisComander{
+		DelFunc: func(ctx context.Context, keys ...string) *redis.IntCmd {
+			deleted = keys
+			return redis.NewIntResult(int64(len(keys)), nil)
+		},
+	}, nil)
+
+	if err := CachSt.DeleteOrders(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted != nil {
+		t.Fatal("nothing to delete must not call redis")
+	}
+
+	if err := CachSt.DeleteOrders(context.Background(), []string{"1", "2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("expected two keys deleted, got %v", deleted)
+	}
+}
+
+func TestEncryptedCache(t *testing.T) {
+
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := map[string]string{}
+	CachSt := NewCacheStorage(MockRedisComander{
+		SetFunc: func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+			stored[key] = string(value.([]byte))
+			return redis.NewStatusResult("OK", nil)
+		},
+		GetFunc: func(ctx context.Context, key string) *redis.StringCmd {
+			return redis.NewStringResult(stored[key], nil)
+		},
+	}, keyring)
+
+	order := models.Order{OrderUID: "1", Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000", City: "Kiryat Mozkin", Email: "test@gmail.com"}}
+	if err := CachSt.SaveOrderCache(context.Background(), order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, plain := range []string{"Test Testov", "+9720000000", "test@gmail.com"} {
+		if strings.Contains(stored["1"], plain) {
+			t.Errorf("%q is stored in plain text", plain)
+		}
+	}
+	if !strings.Contains(stored["1"], "Kiryat Mozkin") {
+		t.Error("city is not encrypted")
+	}
+
+	got, err := CachSt.GetOrderByID(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Delivery != order.Delivery {
+		t.Errorf("expected the decrypted delivery, got %+v", got.Delivery)
+	}
+}
@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/LootNex/OrderService/Consumer/internal/ratelimit"
+	"github.com/go-redis/redis/v8"
+)
+
+const rateLimitPrefix = "ratelimit:"
+
+// takeScript refills and takes from the bucket atomically. It uses the
+// clock of Redis, so replicas with skewed clocks still share one bucket.
+// Tokens are returned as a string, Redis would truncate a Lua number.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RateLimitStorage keeps token buckets in Redis, so all replicas enforce a
+// common limit.
+type RateLimitStorage struct {
+	redis redis.Scripter
+}
+
+func NewRateLimitStorage(conn redis.Scripter) *RateLimitStorage {
+	return &RateLimitStorage{redis: conn}
+}
+
+func (rs *RateLimitStorage) Take(ctx context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error) {
+
+	reply, err := takeScript.Run(ctx, rs.redis, []string{rateLimitPrefix + key}, l.Rate, l.Burst).Slice()
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("cannot take rate limit token err:%w", err)
+	}
+	if len(reply) != 2 {
+		return ratelimit.Result{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	raw, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("unexpected rate limit tokens %q err:%w", raw, err)
+	}
+
+	return ratelimit.NewResult(l, tokens, allowed == 1), nil
+}
@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/ratelimit"
+	"github.com/go-redis/redis/v8"
+)
+
+type MockScripter struct {
+	EvalShaFunc func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	EvalFunc    func(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+func (mS MockScripter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	return mS.EvalFunc(ctx, script, keys, args...)
+}
+
+func (mS MockScripter) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	return mS.EvalShaFunc(ctx, sha1, keys, args...)
+}
+
+func (mS MockScripter) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	return redis.NewBoolSliceResult(nil, errors.New("not implemented"))
+}
+
+func (mS MockScripter) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	return redis.NewStringResult("", errors.New("not implemented"))
+}
+
+func TestRateLimitTake(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		reply       []interface{}
+		evalShaErr  error
+		wantAllowed bool
+		wantErr     bool
+	}{
+		{
+			name:        "allowed",
+			reply:       []interface{}{int64(1), "4.5"},
+			wantAllowed: true,
+		},
+		{
+			name:  "limited",
+			reply: []interface{}{int64(0), "0.25"},
+		},
+		{
+			name:        "script not loaded yet",
+			reply:       []interface{}{int64(1), "9"},
+			evalShaErr:  errors.New("NOSCRIPT No matching script"),
+			wantAllowed: true,
+		},
+		{
+			name:    "redis down",
+			reply:   nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotKeys []string
+			result := func(keys []string) *redis.Cmd {
+				gotKeys = keys
+				if tt.reply == nil {
+					return redis.NewCmdResult(nil, errors.New("connection refused"))
+				}
+				return redis.NewCmdResult(tt.reply, nil)
+			}
+
+			rs := NewRateLimitStorage(MockScripter{
+				EvalShaFunc: func(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+					if tt.evalShaErr != nil {
+						return redis.NewCmdResult(nil, tt.evalShaErr)
+					}
+					return result(keys)
+				},
+				EvalFunc: func(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+					return result(keys)
+				},
+			})
+
+			lim := ratelimit.Limit{Rate: 1, Burst: 10}
+			res, err := rs.Take(context.Background(), "order:ip:1", lim)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if res.Allowed != tt.wantAllowed {
+				t.Errorf("expected allowed %v, got %+v", tt.wantAllowed, res)
+			}
+			if !tt.wantAllowed && res.RetryAfter != 750*time.Millisecond {
+				t.Errorf("unexpected retry after %v", res.RetryAfter)
+			}
+			if len(gotKeys) != 1 || gotKeys[0] != "ratelimit:order:ip:1" {
+				t.Errorf("unexpected keys %v", gotKeys)
+			}
+		})
+	}
+}
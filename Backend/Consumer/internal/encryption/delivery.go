@@ -0,0 +1,93 @@
+package encryption
+
+import (
+	"fmt"
+
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+const (
+	FieldName    = "delivery.name"
+	FieldPhone   = "delivery.phone"
+	FieldAddress = "delivery.address"
+	FieldEmail   = "delivery.email"
+)
+
+// deliveryFields are the delivery fields stored encrypted. Zip, city and
+// region stay searchable in plain text.
+func deliveryFields(d *models.Delivery) map[string]*string {
+	return map[string]*string{
+		FieldName:    &d.Name,
+		FieldPhone:   &d.Phone,
+		FieldAddress: &d.Address,
+		FieldEmail:   &d.Email,
+	}
+}
+
+// BlindIndexes are stored next to an encrypted delivery to look it up by
+// email or phone.
+type BlindIndexes struct {
+	Email string
+	Phone string
+}
+
+// EncryptDelivery returns a copy of d with the personal fields sealed.
+func (k *Keyring) EncryptDelivery(d models.Delivery) (models.Delivery, error) {
+	return k.transformDelivery(d, k.Encrypt)
+}
+
+func (k *Keyring) DecryptDelivery(d models.Delivery) (models.Delivery, error) {
+	return k.transformDelivery(d, k.Decrypt)
+}
+
+// RewrapDelivery moves a stored delivery to the primary key.
+func (k *Keyring) RewrapDelivery(d models.Delivery) (models.Delivery, error) {
+	return k.transformDelivery(d, k.Rewrap)
+}
+
+// DeliveryIndexes is computed from the plain text delivery.
+func (k *Keyring) DeliveryIndexes(d models.Delivery) BlindIndexes {
+	if k == nil {
+		return BlindIndexes{}
+	}
+	return BlindIndexes{
+		Email: k.BlindIndex(FieldEmail, d.Email),
+		Phone: k.BlindIndex(FieldPhone, d.Phone),
+	}
+}
+
+func (k *Keyring) transformDelivery(d models.Delivery, fn func(field, value string) (string, error)) (models.Delivery, error) {
+
+	if k == nil {
+		return d, nil
+	}
+
+	for field, value := range deliveryFields(&d) {
+		out, err := fn(field, *value)
+		if err != nil {
+			return models.Delivery{}, fmt.Errorf("%s err:%w", field, err)
+		}
+		*value = out
+	}
+
+	return d, nil
+}
+
+// EncryptOrder seals the delivery of a copy of order.
+func (k *Keyring) EncryptOrder(order models.Order) (models.Order, error) {
+	d, err := k.EncryptDelivery(order.Delivery)
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.Delivery = d
+	return order, nil
+}
+
+func (k *Keyring) DecryptOrder(order models.Order) (models.Order, error) {
+	d, err := k.DecryptDelivery(order.Delivery)
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.Delivery = d
+	return order, nil
+}
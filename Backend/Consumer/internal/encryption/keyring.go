@@ -0,0 +1,300 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Values are sealed with envelope encryption: every value gets its own
+// data key, which is stored next to it wrapped with a key of the keyring:
+//
+//	enc:v1:<key id>:<wrapped data key>:<nonce and ciphertext>
+//
+// Rotating the keyring only rewraps data keys, the data stays as it is.
+const envelopePrefix = "enc:v1:"
+
+var (
+	ErrUnknownKey = errors.New("value is sealed with a key missing from the keyring")
+	ErrMalformed  = errors.New("malformed encrypted value")
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9.-]+$`)
+
+// Keyring holds the key encryption keys and the key of the blind index. A
+// nil Keyring leaves values in plain text.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+	index   []byte
+}
+
+// keyringFile is the format of the keyring file. Keys are base64 encoded
+// 32-byte AES keys; the blind index key never rotates, indexes would have
+// to be rebuilt.
+type keyringFile struct {
+	Primary  string            `json:"primary"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"index_key"`
+}
+
+// LoadKeyring reads the keyring from a file, usually a mounted secret.
+func LoadKeyring(path string) (*Keyring, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read keyring err:%w", err)
+	}
+
+	return ParseKeyring(data)
+}
+
+// ParseKeyring reads the keyring from the JSON of a keyring file, e.g.
+// passed in an environment variable.
+func ParseKeyring(data []byte) (*Keyring, error) {
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse keyring err:%w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not base64", id)
+		}
+		keys[id] = key
+	}
+
+	index, err := base64.StdEncoding.DecodeString(file.IndexKey)
+	if err != nil {
+		return nil, errors.New("index_key is not base64")
+	}
+
+	return NewKeyring(file.Primary, keys, index)
+}
+
+// NewKeyring seals new values with the primary key; the other keys are
+// kept to open values sealed before a rotation.
+func NewKeyring(primary string, keys map[string][]byte, indexKey []byte) (*Keyring, error) {
+
+	k := &Keyring{primary: primary, keys: make(map[string]cipher.AEAD, len(keys)), index: indexKey}
+
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("key id %q may only contain letters, digits, dots and dashes", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+
+	if _, ok := k.keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the keyring", primary)
+	}
+	if len(indexKey) < 32 {
+		return nil, errors.New("index key must be at least 32 bytes")
+	}
+
+	return k, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot init aes err:%w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// SealedPrefix is how values sealed with the primary key begin.
+func (k *Keyring) SealedPrefix() string {
+	return envelopePrefix + k.primary + ":"
+}
+
+// Encrypt seals plaintext. The field name is authenticated with it, so a
+// value copied into another column does not open.
+func (k *Keyring) Encrypt(field, plaintext string) (string, error) {
+
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("cannot generate data key err:%w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(data, []byte(plaintext), []byte(field))
+	if err != nil {
+		return "", err
+	}
+
+	return k.wrap(k.primary, dataKey, sealed)
+}
+
+// Decrypt opens a sealed value. Values without the envelope prefix are
+// returned as they are: they were stored before encryption was enabled
+// and wait for the re-encrypt job.
+func (k *Keyring) Decrypt(field, value string) (string, error) {
+
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	_, dataKey, sealed, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(data, sealed, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt %s: %w", field, ErrMalformed)
+	}
+
+	return string(plaintext), nil
+}
+
+// Rewrap returns the value sealed with the primary key. Sealed values only
+// get their data key rewrapped; plain values are encrypted.
+func (k *Keyring) Rewrap(field, value string) (string, error) {
+
+	if !IsSealed(value) {
+		return k.Encrypt(field, value)
+	}
+
+	id, dataKey, sealed, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	if id == k.primary {
+		return value, nil
+	}
+
+	return k.wrap(k.primary, dataKey, sealed)
+}
+
+// BlindIndex is a keyed hash of the normalized value, so equal emails or
+// phones can be found without decrypting anything. Without a keyring it is
+// empty.
+func (k *Keyring) BlindIndex(field, value string) string {
+
+	value = Normalize(field, value)
+	if k == nil || value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, k.index)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// Normalize is the form a value is indexed in: emails are compared without
+// case, phones by digits only.
+func Normalize(field, value string) string {
+
+	value = strings.TrimSpace(value)
+
+	switch {
+	case strings.HasSuffix(field, "email"):
+		return strings.ToLower(value)
+	case strings.HasSuffix(field, "phone"):
+		return strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, value)
+	default:
+		return value
+	}
+}
+
+func (k *Keyring) wrap(id string, dataKey, sealed []byte) (string, error) {
+
+	wrapped, err := seal(k.keys[id], dataKey, []byte(id))
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + id + ":" + base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyring) unwrap(value string) (string, []byte, []byte, error) {
+
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, ErrMalformed
+	}
+	id := parts[0]
+
+	kek, ok := k.keys[id]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	wrapped, errW := base64.RawURLEncoding.DecodeString(parts[1])
+	sealed, errS := base64.RawURLEncoding.DecodeString(parts[2])
+	if errW != nil || errS != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	dataKey, err := open(kek, wrapped, []byte(id))
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	return id, dataKey, sealed, nil
+}
+
+func seal(aead cipher.AEAD, plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("cannot generate nonce err:%w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+func open(aead cipher.AEAD, sealed, ad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], ad)
+}
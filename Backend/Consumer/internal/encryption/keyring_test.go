@@ -0,0 +1,229 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+func testKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	t.Helper()
+
+	keys := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte(id[len(id)-1:]), 32)
+	}
+
+	k, err := NewKeyring(primary, keys, bytes.Repeat([]byte{0xaa}, 32))
+	if err != nil {
+		t.Fatalf("cannot create keyring: %v", err)
+	}
+	return k
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+
+	k := testKeyring(t, "k1", "k1")
+
+	sealed, err := k.Encrypt(FieldEmail, "test@gmail.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(sealed, k.SealedPrefix()) || strings.Contains(sealed, "gmail") {
+		t.Fatalf("unexpected sealed value %q", sealed)
+	}
+
+	again, _ := k.Encrypt(FieldEmail, "test@gmail.com")
+	if again == sealed {
+		t.Error("equal values must not produce equal ciphertexts")
+	}
+
+	plain, err := k.Decrypt(FieldEmail, sealed)
+	if err != nil || plain != "test@gmail.com" {
+		t.Fatalf("expected the email back, got %q, %v", plain, err)
+	}
+
+	if _, err := k.Decrypt(FieldPhone, sealed); !errors.Is(err, ErrMalformed) {
+		t.Errorf("a value moved to another field must not decrypt, got %v", err)
+	}
+
+	if plain, err := k.Decrypt(FieldEmail, "legacy@gmail.com"); err != nil || plain != "legacy@gmail.com" {
+		t.Errorf("plain text must pass through, got %q, %v", plain, err)
+	}
+
+	if sealed, _ := k.Encrypt(FieldName, ""); sealed != "" {
+		t.Errorf("empty values stay empty, got %q", sealed)
+	}
+}
+
+func TestRotation(t *testing.T) {
+
+	old := testKeyring(t, "k1", "k1")
+	sealed, _ := old.Encrypt(FieldName, "Test Testov")
+
+	rotated := testKeyring(t, "k2", "k1", "k2")
+
+	if plain, err := rotated.Decrypt(FieldName, sealed); err != nil || plain != "Test Testov" {
+		t.Fatalf("old values must still decrypt, got %q, %v", plain, err)
+	}
+
+	rewrapped, err := rotated.Rewrap(FieldName, sealed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(rewrapped, rotated.SealedPrefix()) {
+		t.Fatalf("expected the primary key, got %q", rewrapped)
+	}
+	// Only the data key is rewrapped, the ciphertext stays.
+	if rewrapped[strings.LastIndex(rewrapped, ":"):] != sealed[strings.LastIndex(sealed, ":"):] {
+		t.Error("ciphertext must not change on rewrap")
+	}
+	if again, _ := rotated.Rewrap(FieldName, rewrapped); again != rewrapped {
+		t.Error("values sealed with the primary key must be left alone")
+	}
+
+	retired := testKeyring(t, "k2", "k2")
+	if _, err := retired.Decrypt(FieldName, sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+	if plain, err := retired.Decrypt(FieldName, rewrapped); err != nil || plain != "Test Testov" {
+		t.Errorf("rewrapped values must decrypt without the old key, got %q, %v", plain, err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+
+	k := testKeyring(t, "k1", "k1")
+
+	tests := []struct {
+		name  string
+		field string
+		a, b  string
+		equal bool
+	}{
+		{name: "email case", field: FieldEmail, a: "Test@Gmail.com ", b: "test@gmail.com", equal: true},
+		{name: "phone format", field: FieldPhone, a: "+972 000-00-00", b: "9720000000", equal: true},
+		{name: "different emails", field: FieldEmail, a: "a@gmail.com", b: "b@gmail.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := k.BlindIndex(tt.field, tt.a), k.BlindIndex(tt.field, tt.b)
+			if (a == b) != tt.equal {
+				t.Errorf("expected equal: %v, got %q and %q", tt.equal, a, b)
+			}
+		})
+	}
+
+	if k.BlindIndex(FieldEmail, "x@y.z") == k.BlindIndex(FieldPhone, "x@y.z") {
+		t.Error("indexes of different fields must differ")
+	}
+	if k.BlindIndex(FieldPhone, "") != "" {
+		t.Error("empty values must not be indexed")
+	}
+
+	other, err := NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte("1"), 32)}, bytes.Repeat([]byte{0xbb}, 32))
+	if err != nil {
+		t.Fatalf("cannot create keyring: %v", err)
+	}
+	plain := sha256.Sum256([]byte("alice"))
+	if got := k.BlindIndex("audit.customer_id", "alice"); got == other.BlindIndex("audit.customer_id", "alice") || got == hex.EncodeToString(plain[:]) {
+		t.Error("indexes must depend on the index key")
+	}
+	if (*Keyring)(nil).BlindIndex(FieldEmail, "x@y.z") != "" {
+		t.Error("without a keyring nothing is indexed")
+	}
+}
+
+func TestDelivery(t *testing.T) {
+
+	d := models.Delivery{
+		Name:    "Test Testov",
+		Phone:   "+9720000000",
+		Zip:     "2639809",
+		City:    "Kiryat Mozkin",
+		Address: "Ploshad Mira 15",
+		Region:  "Kraiot",
+		Email:   "test@gmail.com",
+	}
+
+	var none *Keyring
+	if got, err := none.EncryptDelivery(d); err != nil || got != d {
+		t.Fatalf("a nil keyring must keep plain text, got %+v, %v", got, err)
+	}
+
+	k := testKeyring(t, "k1", "k1")
+	sealed, err := k.EncryptDelivery(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, v := range []string{sealed.Name, sealed.Phone, sealed.Address, sealed.Email} {
+		if !IsSealed(v) {
+			t.Errorf("expected a sealed value, got %q", v)
+		}
+	}
+	if sealed.City != d.City || sealed.Zip != d.Zip || sealed.Region != d.Region {
+		t.Errorf("zip, city and region must stay plain, got %+v", sealed)
+	}
+
+	if got, err := k.DecryptDelivery(sealed); err != nil || got != d {
+		t.Errorf("expected the delivery back, got %+v, %v", got, err)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			file: `{"primary": "2026-10", "keys": {"2026-10": "` + key + `"}, "index_key": "` + key + `"}`,
+		},
+		{
+			name:    "primary missing",
+			file:    `{"primary": "2026-11", "keys": {"2026-10": "` + key + `"}, "index_key": "` + key + `"}`,
+			wantErr: true,
+		},
+		{
+			name:    "short key",
+			file:    `{"primary": "a", "keys": {"a": "AAAA"}, "index_key": "` + key + `"}`,
+			wantErr: true,
+		},
+		{
+			name:    "bad key id",
+			file:    `{"primary": "a:b", "keys": {"a:b": "` + key + `"}, "index_key": "` + key + `"}`,
+			wantErr: true,
+		},
+		{
+			name:    "no index key",
+			file:    `{"primary": "a", "keys": {"a": "` + key + `"}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keyring.json")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadKeyring(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
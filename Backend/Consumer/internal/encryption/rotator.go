@@ -0,0 +1,90 @@
+package encryption
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval  = time.Hour
+	defaultBatchSize = 500
+)
+
+// RotationStore re-encrypts up to limit rows that are in plain text or
+// sealed with a key other than the primary one, and returns how many it
+// rewrote.
+type RotationStore interface {
+	ReencryptDeliveries(ctx context.Context, limit int) (int, error)
+}
+
+type RotatorOptions struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Rotator moves stored deliveries to the primary key after a rotation and
+// encrypts rows written before encryption was enabled. Old keys can be
+// removed from the keyring once it reports nothing left to do.
+type Rotator struct {
+	store RotationStore
+	opts  RotatorOptions
+	log   *zap.Logger
+}
+
+func NewRotator(store RotationStore, opts RotatorOptions, logg *zap.Logger) *Rotator {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	return &Rotator{
+		store: store,
+		opts:  opts,
+		log:   logg,
+	}
+}
+
+// Run re-encrypts once right away and then on every tick until ctx is
+// cancelled.
+func (r *Rotator) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	r.drain(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.drain(ctx)
+		}
+	}
+}
+
+func (r *Rotator) drain(ctx context.Context) {
+
+	total := 0
+	for ctx.Err() == nil {
+		n, err := r.store.ReencryptDeliveries(ctx, r.opts.BatchSize)
+		total += n
+		if err != nil {
+			if ctx.Err() == nil {
+				r.log.Warn("cannot re-encrypt deliveries", zap.Error(err))
+			}
+			break
+		}
+		if n < r.opts.BatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		r.log.Info("deliveries re-encrypted", zap.Int("count", total))
+	}
+}
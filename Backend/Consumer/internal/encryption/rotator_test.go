@@ -0,0 +1,60 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+type MockRotationStore struct {
+	ReencryptDeliveriesFunc func(ctx context.Context, limit int) (int, error)
+}
+
+func (ms MockRotationStore) ReencryptDeliveries(ctx context.Context, limit int) (int, error) {
+	return ms.ReencryptDeliveriesFunc(ctx, limit)
+}
+
+func TestRotatorDrain(t *testing.T) {
+	tests := []struct {
+		name      string
+		batches   []int
+		failAt    int
+		wantCalls int
+	}{
+		{
+			name:      "drains full batches",
+			batches:   []int{2, 2, 0},
+			failAt:    -1,
+			wantCalls: 3,
+		},
+		{
+			name:      "stops on error",
+			batches:   []int{2, 2, 1},
+			failAt:    1,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			store := MockRotationStore{
+				ReencryptDeliveriesFunc: func(ctx context.Context, limit int) (int, error) {
+					defer func() { calls++ }()
+					if calls == tt.failAt {
+						return 0, errors.New("unknown key")
+					}
+					return tt.batches[calls], nil
+				},
+			}
+
+			NewRotator(store, RotatorOptions{BatchSize: 2}, zap.NewNop()).drain(context.Background())
+
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d batches, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
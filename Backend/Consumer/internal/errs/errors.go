@@ -4,4 +4,8 @@ import "errors"
 
 var (
 	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderExists   = errors.New("order already exists")
+	ErrInvalidOrder  = errors.New("invalid order")
+
+	ErrWebhookNotFound = errors.New("webhook not found")
 )
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+const (
+	OrderCreated       = "order.created"
+	OrderStatusChanged = "order.status_changed"
+)
+
+// Types lists every event type subscribers can ask for.
+var Types = []string{OrderCreated, OrderStatusChanged}
+
+func Known(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type Event struct {
+	ID    string       `json:"id"`
+	Type  string       `json:"type"`
+	Time  time.Time    `json:"created_at"`
+	Order models.Order `json:"order"`
+}
+
+func New(eventType string, order models.Order) Event {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return Event{
+		ID:    hex.EncodeToString(id),
+		Type:  eventType,
+		Time:  time.Now().UTC(),
+		Order: order,
+	}
+}
+
+type Publisher interface {
+	Publish(e Event)
+}
+
+// Bus fans events out to in-process subscribers. Publish runs on the
+// ingestion path, so handlers must hand the event off without blocking.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []func(Event)
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, h := range b.handlers {
+		h(e)
+	}
+}
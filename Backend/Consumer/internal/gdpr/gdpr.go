@@ -0,0 +1,201 @@
+package gdpr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+const (
+	KindExport = "export"
+	KindErase  = "erase"
+)
+
+var ErrNoCustomer = errors.New("customer_id is required")
+
+// Audit records a data subject request. The customer is kept only as a
+// keyed hash of the customer_id: enough to prove a request was honored
+// without storing the identifier that was erased, and it cannot be matched
+// by hashing guessed customer_ids without the key.
+type Audit struct {
+	Kind        string
+	SubjectHash string
+	RequestedBy string
+	Orders      int
+}
+
+// Store is the persistent side of export and erasure.
+type Store interface {
+	CustomerOrderIDs(ctx context.Context, customerID string) ([]string, error)
+	GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, error)
+	// AnonymizeCustomer clears the delivery data of every order of the
+	// customer, replaces its customer_id with pseudonym, scrubs the same
+	// data from stored events and writes the audit record, all in one
+	// transaction. Payments and items are kept. It returns the orders
+	// changed.
+	AnonymizeCustomer(ctx context.Context, customerID, pseudonym string, audit Audit) ([]string, error)
+	SaveAudit(ctx context.Context, audit Audit) error
+}
+
+// Cache drops erased orders, so the next read loads them anonymized.
+type Cache interface {
+	DeleteOrders(ctx context.Context, orderIDs []string) error
+}
+
+// History holds recent orders in memory, like the order stream.
+type History interface {
+	Redact(orderIDs []string, customerID string)
+}
+
+// Hasher computes the subject hash. The keyring's blind index is used, so
+// the hash is an HMAC-SHA256 under the index key.
+type Hasher interface {
+	BlindIndex(field, value string) string
+}
+
+// FieldSubject separates subject hashes from the blind indexes of other
+// fields computed with the same key.
+const FieldSubject = "audit.customer_id"
+
+// Archive is the export of everything stored about a customer.
+type Archive struct {
+	CustomerID string         `json:"customer_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Orders     []models.Order `json:"orders"`
+}
+
+type ErasureReport struct {
+	Orders int `json:"orders"`
+	// CachePurged is false if Redis could not be cleared; the entries then
+	// expire with the cache TTL.
+	CachePurged bool `json:"cache_purged"`
+}
+
+type Manager interface {
+	Export(ctx context.Context, customerID, requestedBy string) (Archive, error)
+	Erase(ctx context.Context, customerID, requestedBy string) (ErasureReport, error)
+}
+
+type Service struct {
+	store   Store
+	cache   Cache
+	hasher  Hasher
+	history []History
+	log     *zap.Logger
+}
+
+func NewService(store Store, cache Cache, hasher Hasher, logg *zap.Logger, history ...History) *Service {
+	return &Service{
+		store:   store,
+		cache:   cache,
+		hasher:  hasher,
+		history: history,
+		log:     logg,
+	}
+}
+
+// Export returns all orders of the customer with delivery data in clear.
+// The request is audited before anything is returned.
+func (s *Service) Export(ctx context.Context, customerID, requestedBy string) (Archive, error) {
+
+	if customerID == "" {
+		return Archive{}, ErrNoCustomer
+	}
+
+	IDs, err := s.store.CustomerOrderIDs(ctx, customerID)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	orders, err := s.store.GetOrdersByIDs(ctx, IDs)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	err = s.store.SaveAudit(ctx, Audit{
+		Kind:        KindExport,
+		SubjectHash: s.hasher.BlindIndex(FieldSubject, customerID),
+		RequestedBy: requestedBy,
+		Orders:      len(orders),
+	})
+	if err != nil {
+		return Archive{}, err
+	}
+
+	s.log.Info("customer data exported", zap.String("requested_by", requestedBy), zap.Int("orders", len(orders)))
+
+	if orders == nil {
+		orders = []models.Order{}
+	}
+
+	return Archive{
+		CustomerID: customerID,
+		ExportedAt: time.Now().UTC(),
+		Orders:     orders,
+	}, nil
+}
+
+// Erase anonymizes the customer. Repeating it is harmless: the customer_id
+// no longer matches any order and only another audit record is written.
+func (s *Service) Erase(ctx context.Context, customerID, requestedBy string) (ErasureReport, error) {
+
+	if customerID == "" {
+		return ErasureReport{}, ErrNoCustomer
+	}
+
+	pseudonym, err := newPseudonym()
+	if err != nil {
+		return ErasureReport{}, err
+	}
+
+	IDs, err := s.store.AnonymizeCustomer(ctx, customerID, pseudonym, Audit{
+		Kind:        KindErase,
+		SubjectHash: s.hasher.BlindIndex(FieldSubject, customerID),
+		RequestedBy: requestedBy,
+	})
+	if err != nil {
+		return ErasureReport{}, err
+	}
+
+	report := ErasureReport{Orders: len(IDs), CachePurged: true}
+
+	if len(IDs) > 0 {
+		if err := s.cache.DeleteOrders(ctx, IDs); err != nil {
+			s.log.Warn("cannot purge erased orders from cache", zap.Error(err))
+			report.CachePurged = false
+		}
+		for _, h := range s.history {
+			h.Redact(IDs, pseudonym)
+		}
+	}
+
+	s.log.Info("customer data erased", zap.String("requested_by", requestedBy), zap.Int("orders", len(IDs)))
+
+	return report, nil
+}
+
+// pseudonymPrefix starts every pseudonym, so erased orders are recognized.
+const pseudonymPrefix = "erased-"
+
+// IsPseudonym reports whether customerID was set by an erasure.
+func IsPseudonym(customerID string) bool {
+	return strings.HasPrefix(customerID, pseudonymPrefix)
+}
+
+// newPseudonym replaces the customer_id of erased orders. It is random, so
+// it cannot be traced back to the customer, but shared by all orders of one
+// request, so they still belong together in reports.
+func newPseudonym() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate pseudonym err:%w", err)
+	}
+	return pseudonymPrefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,188 @@
+package gdpr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+type MockStore struct {
+	CustomerOrderIDsFunc  func(ctx context.Context, customerID string) ([]string, error)
+	GetOrdersByIDsFunc    func(ctx context.Context, orderIDs []string) ([]models.Order, error)
+	AnonymizeCustomerFunc func(ctx context.Context, customerID, pseudonym string, audit Audit) ([]string, error)
+	SaveAuditFunc         func(ctx context.Context, audit Audit) error
+}
+
+func (ms MockStore) CustomerOrderIDs(ctx context.Context, customerID string) ([]string, error) {
+	return ms.CustomerOrderIDsFunc(ctx, customerID)
+}
+
+func (ms MockStore) GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, error) {
+	return ms.GetOrdersByIDsFunc(ctx, orderIDs)
+}
+
+func (ms MockStore) AnonymizeCustomer(ctx context.Context, customerID, pseudonym string, audit Audit) ([]string, error) {
+	return ms.AnonymizeCustomerFunc(ctx, customerID, pseudonym, audit)
+}
+
+func (ms MockStore) SaveAudit(ctx context.Context, audit Audit) error {
+	return ms.SaveAuditFunc(ctx, audit)
+}
+
+type MockCache struct {
+	DeleteOrdersFunc func(ctx context.Context, orderIDs []string) error
+}
+
+func (mc MockCache) DeleteOrders(ctx context.Context, orderIDs []string) error {
+	return mc.DeleteOrdersFunc(ctx, orderIDs)
+}
+
+type MockHasher struct{}
+
+func (MockHasher) BlindIndex(field, value string) string {
+	return "hmac(" + field + "," + value + ")"
+}
+
+type MockHistory struct {
+	redacted  []string
+	pseudonym string
+}
+
+func (mh *MockHistory) Redact(orderIDs []string, customerID string) {
+	mh.redacted = orderIDs
+	mh.pseudonym = customerID
+}
+
+func TestExport(t *testing.T) {
+
+	var audits []Audit
+	store := MockStore{
+		CustomerOrderIDsFunc: func(ctx context.Context, customerID string) ([]string, error) {
+			if customerID == "alice" {
+				return []string{"a", "b"}, nil
+			}
+			return nil, nil
+		},
+		GetOrdersByIDsFunc: func(ctx context.Context, orderIDs []string) ([]models.Order, error) {
+			var orders []models.Order
+			for _, id := range orderIDs {
+				orders = append(orders, models.Order{OrderUID: id, CustomerID: "alice"})
+			}
+			return orders, nil
+		},
+		SaveAuditFunc: func(ctx context.Context, audit Audit) error {
+			audits = append(audits, audit)
+			return nil
+		},
+	}
+	s := NewService(store, nil, MockHasher{}, zap.NewNop())
+
+	archive, err := s.Export(context.Background(), "alice", "api_key:support")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if archive.CustomerID != "alice" || len(archive.Orders) != 2 {
+		t.Errorf("unexpected archive %+v", archive)
+	}
+
+	unknown, err := s.Export(context.Background(), "nobody", "api_key:support")
+	if err != nil || unknown.Orders == nil || len(unknown.Orders) != 0 {
+		t.Errorf("expected an empty archive, got %+v, %v", unknown, err)
+	}
+
+	want := Audit{Kind: KindExport, SubjectHash: MockHasher{}.BlindIndex(FieldSubject, "alice"), RequestedBy: "api_key:support", Orders: 2}
+	if len(audits) != 2 || audits[0] != want {
+		t.Errorf("unexpected audit records %+v", audits)
+	}
+
+	if _, err := s.Export(context.Background(), "", "api_key:support"); !errors.Is(err, ErrNoCustomer) {
+		t.Errorf("expected ErrNoCustomer, got %v", err)
+	}
+}
+
+func TestErase(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		orders      []string
+		storeErr    error
+		cacheErr    error
+		wantPurged  bool
+		wantRedact  bool
+		wantErr     bool
+		wantDeletes int
+	}{
+		{
+			name:        "success",
+			orders:      []string{"a", "b"},
+			wantPurged:  true,
+			wantRedact:  true,
+			wantDeletes: 1,
+		},
+		{
+			name:       "no orders",
+			wantPurged: true,
+		},
+		{
+			name:        "cache unavailable",
+			orders:      []string{"a"},
+			cacheErr:    errors.New("connection refused"),
+			wantRedact:  true,
+			wantDeletes: 1,
+		},
+		{
+			name:     "store fails",
+			storeErr: errors.New("deadlock"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAudit Audit
+			var pseudonym string
+			deletes := 0
+			history := &MockHistory{}
+
+			s := NewService(MockStore{
+				AnonymizeCustomerFunc: func(ctx context.Context, customerID, p string, audit Audit) ([]string, error) {
+					gotAudit, pseudonym = audit, p
+					return tt.orders, tt.storeErr
+				},
+			}, MockCache{
+				DeleteOrdersFunc: func(ctx context.Context, orderIDs []string) error {
+					deletes++
+					return tt.cacheErr
+				},
+			}, MockHasher{}, zap.NewNop(), history)
+
+			report, err := s.Erase(context.Background(), "alice", "jwt:dpo")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if report.Orders != len(tt.orders) || report.CachePurged != tt.wantPurged {
+				t.Errorf("unexpected report %+v", report)
+			}
+			if deletes != tt.wantDeletes {
+				t.Errorf("expected %d cache deletes, got %d", tt.wantDeletes, deletes)
+			}
+			if gotAudit.Kind != KindErase || gotAudit.SubjectHash != (MockHasher{}).BlindIndex(FieldSubject, "alice") || gotAudit.RequestedBy != "jwt:dpo" {
+				t.Errorf("unexpected audit %+v", gotAudit)
+			}
+			if !strings.HasPrefix(pseudonym, "erased-") {
+				t.Errorf("unexpected pseudonym %q", pseudonym)
+			}
+			if (history.redacted != nil) != tt.wantRedact || (tt.wantRedact && history.pseudonym != pseudonym) {
+				t.Errorf("unexpected redaction %+v", history)
+			}
+		})
+	}
+}
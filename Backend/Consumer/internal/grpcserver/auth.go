@@ -0,0 +1,94 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// orderServicePrefix marks the methods that need the read scope; reflection
+// stays open, it only describes the schema.
+const orderServicePrefix = "/order.v1.OrderService/"
+
+// WithAuth authenticates every call the same way as the HTTP API: an
+// x-api-key or authorization metadata entry, or anonymous access.
+func WithAuth(a auth.Authenticator) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := authorize(ctx, a, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authorize(ss.Context(), a, info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &ctxStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+func authorize(ctx context.Context, a auth.Authenticator, method string) (context.Context, error) {
+
+	if !strings.HasPrefix(method, orderServicePrefix) {
+		return ctx, nil
+	}
+
+	p, err := a.Authenticate(ctx, credentials(ctx))
+	if errors.Is(err, auth.ErrUnauthorized) {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, "authentication is unavailable, try again later")
+	}
+
+	if !p.HasScope(auth.ScopeReadOrders) {
+		if p.Anonymous() {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		return nil, status.Error(codes.PermissionDenied, "missing scope "+auth.ScopeReadOrders)
+	}
+
+	return auth.WithPrincipal(ctx, p), nil
+}
+
+func credentials(ctx context.Context) auth.Credentials {
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	creds := auth.Credentials{APIKey: first("x-api-key")}
+
+	scheme, value, _ := strings.Cut(first("authorization"), " ")
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		creds.Bearer = strings.TrimSpace(value)
+	case "apikey":
+		creds.APIKey = strings.TrimSpace(value)
+	}
+
+	return creds
+}
+
+type ctxStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *ctxStream) Context() context.Context {
+	return s.ctx
+}
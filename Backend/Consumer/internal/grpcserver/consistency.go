@@ -0,0 +1,35 @@
+package grpcserver
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// WithReadYourWrites serves calls with "x-read-your-writes: true" metadata
+// from the primary database, like the X-Read-Your-Writes HTTP header.
+func WithReadYourWrites() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(readYourWrites(ctx), req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &ctxStream{ServerStream: ss, ctx: readYourWrites(ss.Context())})
+		}),
+	}
+}
+
+func readYourWrites(ctx context.Context) context.Context {
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("x-read-your-writes"); len(v) > 0 {
+		if on, _ := strconv.ParseBool(v[0]); on {
+			return postgresql.ReadPrimary(ctx)
+		}
+	}
+
+	return ctx
+}
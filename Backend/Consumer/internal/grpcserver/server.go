@@ -0,0 +1,239 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+	"github.com/LootNex/OrderService/Consumer/internal/service"
+	"github.com/LootNex/OrderService/Consumer/internal/stream"
+	"github.com/LootNex/OrderService/Contract/api/orderservice"
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/LootNex/OrderService/Contract/models/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	maxBatchSize    = 500
+)
+
+// Server implements the gRPC OrderService on top of the same service layer
+// as the HTTP API.
+type Server struct {
+	orderservice.UnimplementedOrderServiceServer
+
+	Serv    service.ServiceManager
+	Broker  stream.Manager
+	Privacy *privacy.Policy
+	log     *zap.Logger
+}
+
+func NewServer(serv service.ServiceManager, broker stream.Manager, policy *privacy.Policy, logg *zap.Logger) *Server {
+	return &Server{
+		Serv:    serv,
+		Broker:  broker,
+		Privacy: policy,
+		log:     logg,
+	}
+}
+
+// Register creates a grpc.Server with the order service and reflection.
+func Register(srv *Server, opts ...grpc.ServerOption) *grpc.Server {
+	gs := grpc.NewServer(opts...)
+	orderservice.RegisterOrderServiceServer(gs, srv)
+	reflection.Register(gs)
+	return gs
+}
+
+func (s *Server) GetOrder(ctx context.Context, req *orderservice.GetOrderRequest) (*pb.Order, error) {
+
+	if req.GetOrderUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+	}
+
+	order, err := s.Serv.GetOrderByID(ctx, req.GetOrderUid())
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	return s.toProto(ctx, order), nil
+}
+
+func (s *Server) BatchGetOrders(ctx context.Context, req *orderservice.BatchGetOrdersRequest) (*orderservice.BatchGetOrdersResponse, error) {
+
+	if len(req.GetOrderUids()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d order_uids per request", maxBatchSize)
+	}
+
+	orders, missing, err := s.Serv.GetOrdersByIDs(ctx, req.GetOrderUids())
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	resp := &orderservice.BatchGetOrdersResponse{Missing: missing}
+	for i := range orders {
+		resp.Orders = append(resp.Orders, s.toProto(ctx, orders[i]))
+	}
+
+	return resp, nil
+}
+
+func (s *Server) ListOrders(ctx context.Context, req *orderservice.ListOrdersRequest) (*orderservice.ListOrdersResponse, error) {
+
+	filter, err := listFilter(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Searching by email or phone tells whether a person has orders, so it
+	// needs an identified caller even where reads are anonymous.
+	if filter.Email != "" || filter.Phone != "" {
+		if p, ok := auth.FromContext(ctx); !ok || p.Anonymous() {
+			return nil, status.Error(codes.PermissionDenied, "searching by email or phone requires credentials")
+		}
+	}
+
+	orders, err := s.Serv.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	resp := &orderservice.ListOrdersResponse{}
+	for i := range orders {
+		resp.Orders = append(resp.Orders, s.toProto(ctx, orders[i]))
+	}
+
+	if len(orders) == filter.Limit {
+		last := orders[len(orders)-1]
+		created, err := time.Parse(time.RFC3339, last.DateCreated)
+		if err == nil {
+			resp.NextPageToken = encodeToken(postgresql.Cursor{DateCreated: created, OrderUID: last.OrderUID})
+		}
+	}
+
+	return resp, nil
+}
+
+// WatchOrders streams accepted orders until the client goes away or the
+// server shuts down.
+func (s *Server) WatchOrders(req *orderservice.WatchOrdersRequest, srv grpc.ServerStreamingServer[pb.Order]) error {
+
+	sub, _, ok := s.Broker.Subscribe(0)
+	if !ok {
+		return status.Error(codes.Unavailable, "server is shutting down")
+	}
+	defer s.Broker.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+		case msg, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "stream closed, reconnect to continue")
+			}
+			if !matches(req, msg.Order) {
+				continue
+			}
+			if err := srv.Send(s.toProto(srv.Context(), msg.Order)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toProto converts an order with the delivery data the caller may see.
+func (s *Server) toProto(ctx context.Context, order models.Order) *pb.Order {
+	order = s.Privacy.Apply(s.Privacy.Role(ctx), order)
+	return order.ToProto()
+}
+
+func matches(req *orderservice.WatchOrdersRequest, order models.Order) bool {
+	return (req.GetCustomerId() == "" || order.CustomerID == req.GetCustomerId()) &&
+		(req.GetDeliveryService() == "" || order.DeliveryService == req.GetDeliveryService())
+}
+
+func listFilter(req *orderservice.ListOrdersRequest) (postgresql.ListFilter, error) {
+
+	filter := postgresql.ListFilter{
+		CustomerID:      req.GetCustomerId(),
+		DeliveryService: req.GetDeliveryService(),
+		Email:           req.GetEmail(),
+		Phone:           req.GetPhone(),
+		Limit:           int(req.GetPageSize()),
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+
+	var err error
+	if v := req.GetCreatedAfter(); v != "" {
+		if filter.CreatedAfter, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.New("created_after must be an RFC 3339 timestamp")
+		}
+	}
+	if v := req.GetCreatedBefore(); v != "" {
+		if filter.CreatedBefore, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.New("created_before must be an RFC 3339 timestamp")
+		}
+	}
+	if v := req.GetPageToken(); v != "" {
+		cursor, err := decodeToken(v)
+		if err != nil {
+			return filter, errors.New("invalid page_token")
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
+
+func encodeToken(c postgresql.Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeToken(token string) (postgresql.Cursor, error) {
+	var c postgresql.Cursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// toStatus maps service errors to gRPC codes: unknown orders are NotFound,
+// cancelled calls keep their code and storage failures are Unavailable so
+// clients know they can retry.
+func (s *Server) toStatus(err error) error {
+	switch {
+	case errors.Is(err, errs.ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, errs.ErrInvalidOrder):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	s.log.Error("grpc request failed", zap.Error(err))
+	return status.Error(codes.Unavailable, "storage is unavailable, try again later")
+}
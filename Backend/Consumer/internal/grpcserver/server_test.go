@@ -0,0 +1,257 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+	"github.com/LootNex/OrderService/Consumer/internal/service"
+	"github.com/LootNex/OrderService/Consumer/internal/stream"
+	"github.com/LootNex/OrderService/Contract/api/orderservice"
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type MockServiceManager struct {
+	service.ServiceManager
+
+	GetOrderByIDFunc   func(ctx context.Context, orderID string) (models.Order, error)
+	GetOrdersByIDsFunc func(ctx context.Context, orderIDs []string) ([]models.Order, []string, error)
+	ListOrdersFunc     func(ctx context.Context, filter postgresql.ListFilter) ([]models.Order, error)
+}
+
+func (mSM MockServiceManager) GetOrderByID(ctx context.Context, orderID string) (models.Order, error) {
+	return mSM.GetOrderByIDFunc(ctx, orderID)
+}
+
+func (mSM MockServiceManager) GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, []string, error) {
+	return mSM.GetOrdersByIDsFunc(ctx, orderIDs)
+}
+
+func (mSM MockServiceManager) ListOrders(ctx context.Context, filter postgresql.ListFilter) ([]models.Order, error) {
+	return mSM.ListOrdersFunc(ctx, filter)
+}
+
+func newClient(t *testing.T, serv service.ServiceManager, broker stream.Manager, opts ...grpc.ServerOption) orderservice.OrderServiceClient {
+	t.Helper()
+
+	policy, err := privacy.NewPolicy(privacy.Options{DefaultRole: privacy.RoleSupport})
+	if err != nil {
+		t.Fatalf("cannot init privacy policy: %v", err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	gs := Register(NewServer(serv, broker, policy, zap.NewNop()), opts...)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("cannot dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return orderservice.NewOrderServiceClient(conn)
+}
+
+func TestGetOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		uid      string
+		err      error
+		wantCode codes.Code
+	}{
+		{name: "found", uid: "123", wantCode: codes.OK},
+		{name: "not found", uid: "123", err: errs.ErrOrderNotFound, wantCode: codes.NotFound},
+		{name: "storage down", uid: "123", err: errors.New("connection refused"), wantCode: codes.Unavailable},
+		{name: "empty id", uid: "", wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(t, MockServiceManager{
+				GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+					return models.Order{OrderUID: orderID}, tt.err
+				},
+			}, nil)
+
+			order, err := client.GetOrder(context.Background(), &orderservice.GetOrderRequest{OrderUid: tt.uid})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("expected %v, got %v (%v)", tt.wantCode, code, err)
+			}
+			if tt.wantCode == codes.OK && order.GetOrderUid() != tt.uid {
+				t.Errorf("unexpected order %v", order)
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+
+	chain := auth.NewChain(nil, auth.NewAPIKeyAuthenticator(auth.StaticKeys{
+		{Name: "reader", Hash: auth.HashKey("reader-key"), Scopes: []string{auth.ScopeReadOrders}},
+		{Name: "ops", Hash: auth.HashKey("ops-key")},
+	}))
+
+	tests := []struct {
+		name     string
+		md       []string
+		wantCode codes.Code
+	}{
+		{name: "anonymous", wantCode: codes.Unauthenticated},
+		{name: "api key", md: []string{"x-api-key", "reader-key"}, wantCode: codes.OK},
+		{name: "authorization", md: []string{"authorization", "ApiKey reader-key"}, wantCode: codes.OK},
+		{name: "wrong key", md: []string{"x-api-key", "guess"}, wantCode: codes.Unauthenticated},
+		{name: "missing scope", md: []string{"x-api-key", "ops-key"}, wantCode: codes.PermissionDenied},
+	}
+
+	client := newClient(t, MockServiceManager{
+		GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+			return models.Order{OrderUID: orderID}, nil
+		},
+	}, nil, WithAuth(chain)...)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), tt.md...)
+			_, err := client.GetOrder(ctx, &orderservice.GetOrderRequest{OrderUid: "123"})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("expected %v, got %v (%v)", tt.wantCode, code, err)
+			}
+		})
+	}
+}
+
+func TestBatchGetOrders(t *testing.T) {
+	client := newClient(t, MockServiceManager{
+		GetOrdersByIDsFunc: func(ctx context.Context, orderIDs []string) ([]models.Order, []string, error) {
+			return []models.Order{{OrderUID: "a"}}, []string{"b"}, nil
+		},
+	}, nil)
+
+	resp, err := client.BatchGetOrders(context.Background(), &orderservice.BatchGetOrdersRequest{OrderUids: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.GetOrders()) != 1 || len(resp.GetMissing()) != 1 || resp.GetMissing()[0] != "b" {
+		t.Errorf("unexpected response %v", resp)
+	}
+}
+
+func TestListOrdersPagination(t *testing.T) {
+	var filters []postgresql.ListFilter
+	client := newClient(t, MockServiceManager{
+		ListOrdersFunc: func(ctx context.Context, filter postgresql.ListFilter) ([]models.Order, error) {
+			filters = append(filters, filter)
+			if filter.After != nil {
+				return nil, nil
+			}
+			return []models.Order{
+				{OrderUID: "b", DateCreated: "2021-11-26T06:22:19Z"},
+				{OrderUID: "a", DateCreated: "2021-11-25T06:22:19Z"},
+			}, nil
+		},
+	}, nil)
+
+	ctx := context.Background()
+	first, err := client.ListOrders(ctx, &orderservice.ListOrdersRequest{PageSize: 2, CustomerId: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.GetNextPageToken() == "" {
+		t.Fatal("expected a next page token")
+	}
+
+	second, err := client.ListOrders(ctx, &orderservice.ListOrdersRequest{PageSize: 2, CustomerId: "alice", PageToken: first.GetNextPageToken()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.GetNextPageToken() != "" {
+		t.Error("expected the last page")
+	}
+
+	after := filters[1].After
+	if after == nil || after.OrderUID != "a" || filters[1].CustomerID != "alice" {
+		t.Errorf("unexpected filter %+v", filters[1])
+	}
+
+	_, err = client.ListOrders(ctx, &orderservice.ListOrdersRequest{CreatedAfter: "yesterday"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestListOrdersByContact(t *testing.T) {
+
+	chain := auth.NewChain([]string{auth.ScopeReadOrders}, auth.NewAPIKeyAuthenticator(auth.StaticKeys{
+		{Name: "support", Hash: auth.HashKey("support-key"), Scopes: []string{auth.ScopeReadOrders}},
+	}))
+
+	var got postgresql.ListFilter
+	client := newClient(t, MockServiceManager{
+		ListOrdersFunc: func(ctx context.Context, filter postgresql.ListFilter) ([]models.Order, error) {
+			got = filter
+			return nil, nil
+		},
+	}, nil, WithAuth(chain)...)
+
+	req := &orderservice.ListOrdersRequest{Email: "Test@Gmail.com", Phone: "+9720000000"}
+
+	_, err := client.ListOrders(context.Background(), req)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("anonymous search: expected PermissionDenied, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "support-key")
+	if _, err := client.ListOrders(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Email != req.Email || got.Phone != req.Phone {
+		t.Errorf("unexpected filter %+v", got)
+	}
+}
+
+func TestWatchOrders(t *testing.T) {
+	broker := stream.NewBroker(10, 10)
+	client := newClient(t, MockServiceManager{}, broker)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watch, err := client.WatchOrders(ctx, &orderservice.WatchOrdersRequest{CustomerId: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The subscription is registered once the server handles the call.
+	go func() {
+		for ctx.Err() == nil {
+			broker.Notify(events.New(events.OrderCreated, models.Order{OrderUID: "bob-1", CustomerID: "bob"}))
+			broker.Notify(events.New(events.OrderCreated, models.Order{OrderUID: "alice-1", CustomerID: "alice"}))
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	order, err := watch.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.GetOrderUid() != "alice-1" {
+		t.Errorf("expected alice's order, got %v", order.GetOrderUid())
+	}
+}
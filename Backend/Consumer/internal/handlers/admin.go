@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/LootNex/OrderService/Consumer/internal/kafka/consumer"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	Replayer consumer.ReplayManager
+	Consumer consumer.ConsumerController
+	log      *zap.Logger
+}
+
+func NewAdminHandler(replayer consumer.ReplayManager, cons consumer.ConsumerController, logg *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		Replayer: replayer,
+		Consumer: cons,
+		log:      logg,
+	}
+}
+
+func (h AdminHandler) ConsumerStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.log, http.StatusOK, h.Consumer.Status())
+}
+
+func (h AdminHandler) PauseConsumer(w http.ResponseWriter, r *http.Request) {
+	h.Consumer.Pause()
+	writeJSON(w, h.log, http.StatusOK, h.Consumer.Status())
+}
+
+func (h AdminHandler) ResumeConsumer(w http.ResponseWriter, r *http.Request) {
+	h.Consumer.Resume()
+	writeJSON(w, h.log, http.StatusOK, h.Consumer.Status())
+}
+
+// Replay starts reading a range of the orders topic again in the
+// background and answers with the job. The report counts how many orders
+// were new, identical to the stored ones, conflicting with them or, with
+// apply=conflicting, overwritten.
+func (h AdminHandler) Replay(w http.ResponseWriter, r *http.Request) {
+
+	var req consumer.ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.Replayer.Start(req)
+	if errors.Is(err, consumer.ErrReplayRunning) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.log.Error("cannot start replay", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, h.log, http.StatusAccepted, job)
+}
+
+// ReplayStatus returns the last started replay with its report so far.
+func (h AdminHandler) ReplayStatus(w http.ResponseWriter, r *http.Request) {
+
+	job, ok := h.Replayer.Job()
+	if !ok {
+		http.Error(w, "no replay was started", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, h.log, http.StatusOK, job)
+}
+
+func writeJSON(w http.ResponseWriter, log *zap.Logger, status int, v any) {
+
+	resp, err := json.MarshalIndent(v, "", "   ")
+	if err != nil {
+		http.Error(w, "problems with server, try again later", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(resp); err != nil {
+		log.Error("failed to write response", zap.Error(err))
+	}
+}
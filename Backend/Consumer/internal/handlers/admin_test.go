@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LootNex/OrderService/Consumer/internal/kafka/consumer"
+	"go.uber.org/zap"
+)
+
+type MockReplayManager struct {
+	StartFunc func(req consumer.ReplayRequest) (consumer.ReplayJob, error)
+	JobFunc   func() (consumer.ReplayJob, bool)
+}
+
+func (mRM MockReplayManager) Start(req consumer.ReplayRequest) (consumer.ReplayJob, error) {
+	return mRM.StartFunc(req)
+}
+
+func (mRM MockReplayManager) Job() (consumer.ReplayJob, bool) {
+	return mRM.JobFunc()
+}
+
+type MockConsumerController struct {
+	paused bool
+}
+
+func (mCC *MockConsumerController) Pause()  { mCC.paused = true }
+func (mCC *MockConsumerController) Resume() { mCC.paused = false }
+
+func (mCC *MockConsumerController) Status() consumer.Status {
+	if mCC.paused {
+		return consumer.Status{State: "paused", Lag: 42}
+	}
+	return consumer.Status{State: "running", Lag: 42}
+}
+
+func TestConsumerPauseResume(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("cannot init logger err: %v", err)
+	}
+
+	ctrl := &MockConsumerController{}
+	h := NewAdminHandler(nil, ctrl, log)
+
+	steps := []struct {
+		handler   http.HandlerFunc
+		wantState string
+	}{
+		{handler: h.ConsumerStatus, wantState: "running"},
+		{handler: h.PauseConsumer, wantState: "paused"},
+		{handler: h.ConsumerStatus, wantState: "paused"},
+		{handler: h.ResumeConsumer, wantState: "running"},
+	}
+
+	for i, step := range steps {
+		w := httptest.NewRecorder()
+		step.handler(w, httptest.NewRequest(http.MethodPost, "/admin/consumer", nil))
+
+		var st consumer.Status
+		if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+			t.Fatalf("step %d: cannot decode status: %v", i, err)
+		}
+		if st.State != step.wantState || st.Lag != 42 {
+			t.Errorf("step %d: expected state %q, got %+v", i, step.wantState, st)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		startErr   error
+		wantStatus int
+		wantApply  string
+	}{
+		{
+			name:       "offsets",
+			body:       `{"offsets": {"0": 10, "2": 0}}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "timestamp",
+			body:       `{"from": "2025-01-01T00:00:00Z", "to": "2025-01-02T00:00:00Z"}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "apply conflicting",
+			body:       `{"offsets": {"0": 10}, "apply": "conflicting"}`,
+			wantStatus: http.StatusAccepted,
+			wantApply:  consumer.ApplyConflicting,
+		},
+		{
+			name:       "unknown apply mode",
+			body:       `{"offsets": {"0": 10}, "apply": "all"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty request",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed body",
+			body:       `{"offsets":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "already running",
+			body:       `{"offsets": {"0": 10}}`,
+			startErr:   consumer.ErrReplayRunning,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "shutting down",
+			body:       `{"offsets": {"0": 10}}`,
+			startErr:   consumer.ErrReplayerClosed,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	log, err := zap.NewDevelopment()
+	if err != nil {
+		t.Fatalf("cannot init logger err: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAdminHandler(MockReplayManager{
+				StartFunc: func(req consumer.ReplayRequest) (consumer.ReplayJob, error) {
+					return consumer.ReplayJob{ID: "1", State: consumer.JobRunning, Request: req}, tt.startErr
+				},
+			}, &MockConsumerController{}, log)
+
+			r := httptest.NewRequest(http.MethodPost, "/admin/replay", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.Replay(w, r)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("unexpected status %v, expected %v", res.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusAccepted {
+				var job consumer.ReplayJob
+				if err := json.NewDecoder(res.Body).Decode(&job); err != nil {
+					t.Fatalf("cannot decode job: %v", err)
+				}
+				if job.ID != "1" || job.State != consumer.JobRunning || job.Request.Apply != tt.wantApply {
+					t.Errorf("unexpected job %+v", job)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		job        *consumer.ReplayJob
+		wantStatus int
+	}{
+		{
+			name:       "no replay yet",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "finished",
+			job: &consumer.ReplayJob{ID: "2", State: consumer.JobDone,
+				Report: consumer.ReplayReport{Read: 3, New: 1, Identical: 2}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "failed",
+			job: &consumer.ReplayJob{ID: "3", State: consumer.JobFailed, Error: "broker down",
+				Report: consumer.ReplayReport{Read: 3, New: 1, Identical: 2}},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAdminHandler(MockReplayManager{
+				JobFunc: func() (consumer.ReplayJob, bool) {
+					if tt.job == nil {
+						return consumer.ReplayJob{}, false
+					}
+					return *tt.job, true
+				},
+			}, &MockConsumerController{}, zap.NewNop())
+
+			w := httptest.NewRecorder()
+			h.ReplayStatus(w, httptest.NewRequest(http.MethodGet, "/admin/replay", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("unexpected status %v, expected %v", w.Code, tt.wantStatus)
+			}
+			if tt.job == nil {
+				return
+			}
+
+			var job consumer.ReplayJob
+			if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+				t.Fatalf("cannot decode job: %v", err)
+			}
+			if job.ID != tt.job.ID || job.State != tt.job.State || job.Error != tt.job.Error || job.Report.Identical != 2 {
+				t.Errorf("unexpected job %+v", job)
+			}
+		})
+	}
+}
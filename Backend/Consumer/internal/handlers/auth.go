@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"go.uber.org/zap"
+)
+
+// CORS lets browsers on the allowed origins call the API. "*" allows any
+// origin.
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		allowed[strings.TrimSuffix(o, "/")] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+
+			if origin != "" && (allowed["*"] || allowed[origin]) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, Last-Event-ID, X-Read-Your-Writes")
+			}
+
+			// Preflights carry no credentials, so they are answered before
+			// authentication.
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Authenticate resolves the caller of every request and puts the principal
+// into its context. Requests without credentials continue as anonymous;
+// Require decides whether that is enough.
+func Authenticate(a auth.Authenticator, log *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			p, err := a.Authenticate(r.Context(), Credentials(r))
+			if errors.Is(err, auth.ErrUnauthorized) {
+				log.Warn("authentication failed", zap.String("remote_addr", r.RemoteAddr),
+					zap.String("path", r.URL.Path), zap.Error(err))
+				unauthorized(w)
+				return
+			}
+			if err != nil {
+				log.Error("cannot authenticate request", zap.Error(err))
+				http.Error(w, "authentication is unavailable, try again later", http.StatusServiceUnavailable)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// Require lets the request through only if its principal has the scope.
+func Require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		p, ok := auth.FromContext(r.Context())
+		if !ok || (p.Anonymous() && !p.HasScope(scope)) {
+			unauthorized(w)
+			return
+		}
+		if !p.HasScope(scope) {
+			http.Error(w, "missing scope "+scope, http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// Credentials reads an API key from X-API-Key or "Authorization: ApiKey"
+// and a JWT from "Authorization: Bearer".
+func Credentials(r *http.Request) auth.Credentials {
+
+	creds := auth.Credentials{APIKey: r.Header.Get("X-API-Key")}
+
+	scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		creds.Bearer = strings.TrimSpace(value)
+	case "apikey":
+		creds.APIKey = strings.TrimSpace(value)
+	}
+
+	return creds
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer, ApiKey`)
+	http.Error(w, "authentication required", http.StatusUnauthorized)
+}
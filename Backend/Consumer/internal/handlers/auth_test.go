@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+	"go.uber.org/zap"
+)
+
+func TestAuthenticate(t *testing.T) {
+
+	chain := auth.NewChain([]string{auth.ScopeReadOrders}, auth.NewAPIKeyAuthenticator(auth.StaticKeys{
+		{Name: "reader", Hash: auth.HashKey("reader-key"), Scopes: []string{auth.ScopeReadOrders}, Role: privacy.RoleSupport},
+		{Name: "ops", Hash: auth.HashKey("ops-key"), Scopes: []string{auth.ScopeAdmin}},
+	}))
+
+	var role privacy.Role
+	policy, err := privacy.NewPolicy(privacy.Options{})
+	if err != nil {
+		t.Fatalf("cannot init privacy policy err: %v", err)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		role = policy.Role(r.Context())
+	}
+
+	tests := []struct {
+		name       string
+		scope      string
+		header     string
+		value      string
+		wantStatus int
+		wantRole   privacy.Role
+	}{
+		{name: "anonymous read", scope: auth.ScopeReadOrders, wantStatus: http.StatusOK, wantRole: privacy.RoleAnonymous},
+		{name: "anonymous admin", scope: auth.ScopeAdmin, wantStatus: http.StatusUnauthorized},
+		{name: "api key header", scope: auth.ScopeReadOrders, header: "X-API-Key", value: "reader-key", wantStatus: http.StatusOK, wantRole: privacy.RoleSupport},
+		{name: "api key scheme", scope: auth.ScopeReadOrders, header: "Authorization", value: "ApiKey reader-key", wantStatus: http.StatusOK, wantRole: privacy.RoleSupport},
+		{name: "missing scope", scope: auth.ScopeAdmin, header: "X-API-Key", value: "reader-key", wantStatus: http.StatusForbidden},
+		{name: "admin", scope: auth.ScopeReadOrders, header: "X-API-Key", value: "ops-key", wantStatus: http.StatusOK, wantRole: privacy.RoleAnonymous},
+		{name: "wrong key", scope: auth.ScopeReadOrders, header: "X-API-Key", value: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "unsupported bearer", scope: auth.ScopeReadOrders, header: "Authorization", value: "Bearer x.y.z", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role = ""
+			h := Authenticate(chain, zap.NewNop())(Require(tt.scope, ok))
+
+			r := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if role != tt.wantRole {
+				t.Errorf("expected role %q, got %q", tt.wantRole, role)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowed    []string
+		method     string
+		origin     string
+		wantOrigin string
+		wantStatus int
+	}{
+		{name: "allowed origin", allowed: []string{"http://localhost:5500/"}, method: http.MethodGet, origin: "http://localhost:5500", wantOrigin: "http://localhost:5500", wantStatus: http.StatusOK},
+		{name: "other origin", allowed: []string{"http://localhost:5500"}, method: http.MethodGet, origin: "https://evil.example", wantStatus: http.StatusOK},
+		{name: "any origin", allowed: []string{"*"}, method: http.MethodGet, origin: "https://partner.example", wantOrigin: "https://partner.example", wantStatus: http.StatusOK},
+		{name: "preflight", allowed: []string{"http://localhost:5500"}, method: http.MethodOptions, origin: "http://localhost:5500", wantOrigin: "http://localhost:5500", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := CORS(tt.allowed)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(tt.method, "/order/1", nil)
+			r.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("expected allowed origin %q, got %q", tt.wantOrigin, got)
+			}
+		})
+	}
+}
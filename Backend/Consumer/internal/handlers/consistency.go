@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
+)
+
+// ReadYourWritesHeader asks for reads from the primary database, for a
+// client that has just sent an order and must not get a stale replica.
+const ReadYourWritesHeader = "X-Read-Your-Writes"
+
+// ReadYourWrites serves requests carrying ReadYourWritesHeader from the
+// primary; the rest may be served by read replicas.
+func ReadYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if on, _ := strconv.ParseBool(r.Header.Get(ReadYourWritesHeader)); on {
+			r = r.WithContext(postgresql.ReadPrimary(r.Context()))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
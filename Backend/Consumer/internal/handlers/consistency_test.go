@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
+)
+
+func TestReadYourWrites(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		value       string
+		wantPrimary bool
+	}{
+		{name: "no header"},
+		{name: "true", value: "true", wantPrimary: true},
+		{name: "one", value: "1", wantPrimary: true},
+		{name: "false", value: "false"},
+		{name: "garbage", value: "please"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var primary bool
+			h := ReadYourWrites(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				primary = postgresql.ReadsPrimary(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+			if tt.value != "" {
+				r.Header.Set(ReadYourWritesHeader, tt.value)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if primary != tt.wantPrimary {
+				t.Errorf("expected primary reads %v, got %v", tt.wantPrimary, primary)
+			}
+		})
+	}
+}
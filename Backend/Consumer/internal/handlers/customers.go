@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/gdpr"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// CustomerHandler serves data subject requests: export and erasure of
+// everything stored about one customer_id.
+type CustomerHandler struct {
+	GDPR gdpr.Manager
+	log  *zap.Logger
+}
+
+func NewCustomerHandler(manager gdpr.Manager, logg *zap.Logger) *CustomerHandler {
+	return &CustomerHandler{
+		GDPR: manager,
+		log:  logg,
+	}
+}
+
+// Export returns all orders of the customer, delivery data in clear, as a
+// JSON file to download.
+func (h CustomerHandler) Export(w http.ResponseWriter, r *http.Request) {
+
+	archive, err := h.GDPR.Export(r.Context(), mux.Vars(r)["id"], requester(r))
+	if errors.Is(err, gdpr.ErrNoCustomer) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error("customer export failed", zap.Error(err))
+		http.Error(w, "problems with server, try again later", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="customer-export.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, h.log, http.StatusOK, archive)
+}
+
+// Erase anonymizes the customer's personal data. Payments and items are
+// kept for accounting.
+func (h CustomerHandler) Erase(w http.ResponseWriter, r *http.Request) {
+
+	report, err := h.GDPR.Erase(r.Context(), mux.Vars(r)["id"], requester(r))
+	if errors.Is(err, gdpr.ErrNoCustomer) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error("customer erasure failed", zap.Error(err))
+		http.Error(w, "problems with server, try again later", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, h.log, http.StatusOK, report)
+}
+
+// requester names the caller in audit records.
+func requester(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok && !p.Anonymous() {
+		return p.Method + ":" + p.Subject
+	}
+	return auth.MethodAnonymous
+}
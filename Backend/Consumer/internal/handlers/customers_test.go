@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/gdpr"
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type MockGDPRManager struct {
+	ExportFunc func(ctx context.Context, customerID, requestedBy string) (gdpr.Archive, error)
+	EraseFunc  func(ctx context.Context, customerID, requestedBy string) (gdpr.ErasureReport, error)
+}
+
+func (m MockGDPRManager) Export(ctx context.Context, customerID, requestedBy string) (gdpr.Archive, error) {
+	return m.ExportFunc(ctx, customerID, requestedBy)
+}
+
+func (m MockGDPRManager) Erase(ctx context.Context, customerID, requestedBy string) (gdpr.ErasureReport, error) {
+	return m.EraseFunc(ctx, customerID, requestedBy)
+}
+
+func TestCustomerExport(t *testing.T) {
+
+	var requestedBy string
+	h := NewCustomerHandler(MockGDPRManager{
+		ExportFunc: func(ctx context.Context, customerID, by string) (gdpr.Archive, error) {
+			requestedBy = by
+			return gdpr.Archive{CustomerID: customerID, Orders: []models.Order{{OrderUID: "a"}}}, nil
+		},
+	}, zap.NewNop())
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/customers/alice/export", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "alice"})
+	r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Subject: "support", Method: auth.MethodAPIKey}))
+	w := httptest.NewRecorder()
+
+	h.Export(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Disposition") == "" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+
+	var archive gdpr.Archive
+	if err := json.Unmarshal(w.Body.Bytes(), &archive); err != nil {
+		t.Fatalf("cannot decode archive: %v", err)
+	}
+	if archive.CustomerID != "alice" || len(archive.Orders) != 1 {
+		t.Errorf("unexpected archive %+v", archive)
+	}
+	if requestedBy != "api_key:support" {
+		t.Errorf("unexpected requester %q", requestedBy)
+	}
+}
+
+func TestCustomerErase(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "success", wantCode: http.StatusOK},
+		{name: "no customer", err: gdpr.ErrNoCustomer, wantCode: http.StatusBadRequest},
+		{name: "store fails", err: errors.New("deadlock"), wantCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewCustomerHandler(MockGDPRManager{
+				EraseFunc: func(ctx context.Context, customerID, requestedBy string) (gdpr.ErasureReport, error) {
+					return gdpr.ErasureReport{Orders: 2, CachePurged: true}, tt.err
+				},
+			}, zap.NewNop())
+
+			r := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/admin/customers/alice/erase", nil), map[string]string{"id": "alice"})
+			w := httptest.NewRecorder()
+			h.Erase(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
@@ -2,45 +2,56 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+	"github.com/LootNex/OrderService/Consumer/internal/projection"
 	"github.com/LootNex/OrderService/Consumer/internal/service"
+	"github.com/LootNex/OrderService/Contract/models"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	Serv service.ServiceManager
-	log  *zap.Logger
+	Serv    service.ServiceManager
+	Privacy *privacy.Policy
+	log     *zap.Logger
 }
 
-func NewHandler(serv service.ServiceManager, logg *zap.Logger) *Handler {
+func NewHandler(serv service.ServiceManager, policy *privacy.Policy, logg *zap.Logger) *Handler {
 	return &Handler{
-		Serv: serv,
-		log:  logg,
+		Serv:    serv,
+		Privacy: policy,
+		log:     logg,
 	}
 }
 
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
+// GetOrder returns an order. Delivery data is cut down to what the caller's
+// role may see, the fields and include query parameters shape the response
+// (see projection.Parse) and format=compact drops indentation.
 func (h Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	orderID := vars["id"]
 
+	query := r.URL.Query()
+	proj, err := projection.Parse(query.Get("fields"), query.Get("include"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	compact := false
+	switch query.Get("format") {
+	case "", "pretty":
+	case "compact":
+		compact = true
+	default:
+		http.Error(w, "format must be pretty or compact", http.StatusBadRequest)
+		return
+	}
+
 	ctx := r.Context()
 
 	order, err := h.Serv.GetOrderByID(ctx, orderID)
@@ -50,7 +61,20 @@ func (h Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := json.MarshalIndent(order, "", "   ")
+	order = h.Privacy.Apply(h.Privacy.Role(ctx), order)
+
+	shaped, err := proj.Apply(order)
+	if err != nil {
+		http.Error(w, "problems with server, try again later", http.StatusInternalServerError)
+		return
+	}
+
+	var resp []byte
+	if compact {
+		resp, err = json.Marshal(shaped)
+	} else {
+		resp, err = json.MarshalIndent(shaped, "", "   ")
+	}
 	if err != nil {
 		http.Error(w, "problems with server, try again later", http.StatusInternalServerError)
 		return
@@ -61,3 +85,49 @@ func (h Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+const maxBatchIDs = 500
+
+type batchGetRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type batchGetResponse struct {
+	Orders  []models.Order `json:"orders"`
+	Missing []string       `json:"missing"`
+}
+
+// BatchGetOrders returns the orders for a list of IDs and the IDs that do
+// not exist, so clients need one call instead of one per order.
+func (h Handler) BatchGetOrders(w http.ResponseWriter, r *http.Request) {
+
+	var req batchGetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.IDs) == 0 {
+		http.Error(w, "ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) > maxBatchIDs {
+		http.Error(w, fmt.Sprintf("at most %d ids per request", maxBatchIDs), http.StatusBadRequest)
+		return
+	}
+
+	orders, missing, err := h.Serv.GetOrdersByIDs(r.Context(), req.IDs)
+	if err != nil {
+		h.log.Error("batch get failed", zap.Error(err))
+		http.Error(w, "problems with server, try again later", http.StatusInternalServerError)
+		return
+	}
+
+	if missing == nil {
+		missing = []string{}
+	}
+
+	orders = h.Privacy.ApplyAll(h.Privacy.Role(r.Context()), orders)
+
+	writeJSON(w, h.log, http.StatusOK, batchGetResponse{Orders: orders, Missing: missing})
+}
@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
 	"github.com/LootNex/OrderService/Consumer/internal/errs"
-	"github.com/LootNex/OrderService/Consumer/internal/models"
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+	"github.com/LootNex/OrderService/Consumer/internal/service"
+	"github.com/LootNex/OrderService/Contract/models"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
@@ -16,6 +21,10 @@ type MockServiceManager struct {
 	SaveNewOrderFunc func(ctx context.Context, val models.Validator) error
 	GetOrderByIDFunc func(ctx context.Context, orderID string) (models.Order, error)
 	LoadCacheFunc    func(ctx context.Context) error
+	ReplayOrderFunc  func(ctx context.Context, order models.Order, overwrite bool) (service.ReplayResult, error)
+
+	GetOrdersByIDsFunc func(ctx context.Context, orderIDs []string) ([]models.Order, []string, error)
+	ListOrdersFunc     func(ctx context.Context, filter postgresql.ListFilter) ([]models.Order, error)
 }
 
 func (mSM MockServiceManager) GetOrderByID(ctx context.Context, orderID string) (models.Order, error) {
@@ -30,6 +39,28 @@ func (mSM MockServiceManager) LoadCache(ctx context.Context) error {
 	return mSM.LoadCacheFunc(ctx)
 }
 
+func (mSM MockServiceManager) ReplayOrder(ctx context.Context, order models.Order, overwrite bool) (service.ReplayResult, error) {
+	return mSM.ReplayOrderFunc(ctx, order, overwrite)
+}
+
+func (mSM MockServiceManager) GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, []string, error) {
+	return mSM.GetOrdersByIDsFunc(ctx, orderIDs)
+}
+
+func (mSM MockServiceManager) ListOrders(ctx context.Context, filter postgresql.ListFilter) ([]models.Order, error) {
+	return mSM.ListOrdersFunc(ctx, filter)
+}
+
+func testPolicy(t *testing.T) *privacy.Policy {
+	t.Helper()
+
+	policy, err := privacy.NewPolicy(privacy.Options{})
+	if err != nil {
+		t.Fatalf("cannot init privacy policy err: %v", err)
+	}
+	return policy
+}
+
 func TestGetOrder_Success(t *testing.T) {
 
 	mock := MockServiceManager{
@@ -44,7 +75,7 @@ func TestGetOrder_Success(t *testing.T) {
 		return
 	}
 
-	h := NewHandler(mock, log)
+	h := NewHandler(mock, testPolicy(t), log)
 
 	r := httptest.NewRequest(http.MethodGet, "/order/12345", nil)
 	r = mux.SetURLVars(r, map[string]string{"id": "12345"})
@@ -74,7 +105,7 @@ func TestGetOrder_NotFound(t *testing.T) {
 		return
 	}
 
-	h := NewHandler(mock, log)
+	h := NewHandler(mock, testPolicy(t), log)
 
 	r := httptest.NewRequest(http.MethodGet, "/order/12345", nil)
 	r = mux.SetURLVars(r, map[string]string{"id": "12345"})
@@ -90,3 +121,166 @@ func TestGetOrder_NotFound(t *testing.T) {
 	}
 
 }
+
+func TestBatchGetOrders(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "success", body: `{"ids":["1","2"]}`, wantStatus: http.StatusOK},
+		{name: "no ids", body: `{"ids":[]}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid body", body: `ids`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := MockServiceManager{
+				GetOrdersByIDsFunc: func(ctx context.Context, orderIDs []string) ([]models.Order, []string, error) {
+					return []models.Order{{OrderUID: "1"}}, []string{"2"}, nil
+				},
+			}
+			h := NewHandler(mock, testPolicy(t), zap.NewNop())
+
+			w := httptest.NewRecorder()
+			h.BatchGetOrders(w, httptest.NewRequest(http.MethodPost, "/orders:batchGet", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp batchGetResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("cannot decode response: %v", err)
+			}
+			if len(resp.Orders) != 1 || len(resp.Missing) != 1 || resp.Missing[0] != "2" {
+				t.Errorf("unexpected response %+v", resp)
+			}
+		})
+	}
+}
+
+func TestGetOrder_Shaping(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "fields compact",
+			query:      "?fields=order_uid,payment.amount&format=compact",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"order_uid":"12345","payment":{"amount":100}}`,
+		},
+		{
+			name:       "unknown field",
+			query:      "?fields=secret",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown format",
+			query:      "?format=xml",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := MockServiceManager{
+				GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+					return models.Order{OrderUID: orderID, Payment: models.Payment{Amount: 100}}, nil
+				},
+			}
+			h := NewHandler(mock, testPolicy(t), zap.NewNop())
+
+			r := httptest.NewRequest(http.MethodGet, "/order/12345"+tt.query, nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "12345"})
+			w := httptest.NewRecorder()
+
+			h.GetOrder(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("got %s, want %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestGetOrder_Privacy(t *testing.T) {
+
+	full := models.Delivery{
+		DeliveryID: "7",
+		Name:       "Test Testov",
+		Phone:      "+9720000000",
+		Zip:        "2639809",
+		City:       "Kiryat Mozkin",
+		Address:    "Ploshad Mira 15",
+		Region:     "Kraiot",
+		Email:      "test@gmail.com",
+	}
+
+	tests := []struct {
+		name string
+		role privacy.Role
+		want models.Delivery
+	}{
+		{
+			name: "support",
+			role: privacy.RoleSupport,
+			want: full,
+		},
+		{
+			name: "analytics",
+			role: privacy.RoleAnalytics,
+			want: models.Delivery{
+				DeliveryID: "7",
+				Name:       "T***",
+				Phone:      "+********00",
+				Zip:        "2639809",
+				City:       "Kiryat Mozkin",
+				Address:    "P***",
+				Region:     "Kraiot",
+				Email:      "t***@gmail.com",
+			},
+		},
+		{
+			name: "no role",
+			want: models.Delivery{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := MockServiceManager{
+				GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+					return models.Order{OrderUID: orderID, Delivery: full}, nil
+				},
+			}
+			h := NewHandler(mock, testPolicy(t), zap.NewNop())
+
+			r := httptest.NewRequest(http.MethodGet, "/order/12345", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "12345"})
+			if tt.role != "" {
+				r = r.WithContext(privacy.WithRole(r.Context(), tt.role))
+			}
+			w := httptest.NewRecorder()
+
+			h.GetOrder(w, r)
+
+			var got models.Order
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("cannot decode response: %v", err)
+			}
+			if got.Delivery != tt.want {
+				t.Errorf("got %+v, want %+v", got.Delivery, tt.want)
+			}
+		})
+	}
+}
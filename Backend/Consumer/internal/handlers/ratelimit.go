@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// RateLimit returns a wrapper that limits a route per client: per API key
+// or token subject for authenticated callers, per IP for anonymous ones.
+// trustedHops is the number of proxies appending to X-Forwarded-For; 0
+// ignores the header. If the store fails, requests are let through rather
+// than rejected.
+func RateLimit(l *ratelimit.Limiter, trustedHops int, log *zap.Logger) func(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(route string, next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if allow(w, r, l, route, clientKey(r, trustedHops), log) {
+				next(w, r)
+			}
+		}
+	}
+}
+
+// RateLimitAuth limits requests that carry credentials per IP on route
+// before they are authenticated, so made-up API keys cannot reach the key
+// store unthrottled. It goes in front of Authenticate; the limits per
+// principal still apply behind it.
+func RateLimitAuth(l *ratelimit.Limiter, route string, trustedHops int, log *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if Credentials(r).Empty() || allow(w, r, l, route, clientIP(r, trustedHops), log) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allow counts the request and answers 429 if client is over the limit.
+func allow(w http.ResponseWriter, r *http.Request, l *ratelimit.Limiter, route, client string, log *zap.Logger) bool {
+
+	res, lim, err := l.Allow(r.Context(), route, client)
+	if err != nil {
+		log.Warn("rate limit unavailable, request allowed", zap.String("route", route), zap.Error(err))
+		return true
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(lim.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	w.Header().Set("RateLimit-Policy", strconv.Itoa(lim.Burst)+";w="+strconv.Itoa(ceilSeconds(time.Duration(float64(lim.Burst)/lim.Rate*float64(time.Second)))))
+
+	if !res.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
+
+func clientKey(r *http.Request, trustedHops int) string {
+
+	if p, ok := auth.FromContext(r.Context()); ok && !p.Anonymous() {
+		return p.Method + ":" + p.Subject
+	}
+
+	return clientIP(r, trustedHops)
+}
+
+// clientIP takes the X-Forwarded-For entry added by the outermost trusted
+// proxy, trustedHops from the right. The client controls everything to the
+// left of it, so those entries would give a new bucket per request.
+func clientIP(r *http.Request, trustedHops int) string {
+
+	if trustedHops > 0 {
+		var entries []string
+		for _, h := range r.Header.Values("X-Forwarded-For") {
+			entries = append(entries, strings.Split(h, ",")...)
+		}
+		if len(entries) >= trustedHops {
+			if ip := strings.TrimSpace(entries[len(entries)-trustedHops]); ip != "" {
+				return "ip:" + ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
+	"github.com/LootNex/OrderService/Consumer/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+type MockRateLimitStore struct {
+	TakeFunc func(ctx context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error)
+}
+
+func (m MockRateLimitStore) Take(ctx context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error) {
+	return m.TakeFunc(ctx, key, l)
+}
+
+func TestRateLimit(t *testing.T) {
+
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	t.Run("limited", func(t *testing.T) {
+		limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.5, Burst: 1}, nil)
+		h := RateLimit(limiter, 0, zap.NewNop())("order", ok)
+
+		codes := []int{}
+		var last *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			last = httptest.NewRecorder()
+			h(last, httptest.NewRequest(http.MethodGet, "/order/1", nil))
+			codes = append(codes, last.Code)
+		}
+
+		if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+			t.Fatalf("unexpected statuses %v", codes)
+		}
+		for header, want := range map[string]string{
+			"RateLimit-Limit":     "1",
+			"RateLimit-Remaining": "0",
+			"RateLimit-Reset":     "2",
+			"RateLimit-Policy":    "1;w=2",
+			"Retry-After":         "2",
+		} {
+			if got := last.Header().Get(header); got != want {
+				t.Errorf("%s: expected %q, got %q", header, want, got)
+			}
+		}
+	})
+
+	t.Run("client keys", func(t *testing.T) {
+		var keys []string
+		limiter := ratelimit.NewLimiter(MockRateLimitStore{
+			TakeFunc: func(ctx context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error) {
+				keys = append(keys, key)
+				return ratelimit.Result{Allowed: true}, nil
+			},
+		}, ratelimit.Limit{Rate: 1, Burst: 1}, nil)
+
+		anonymous := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+		anonymous.RemoteAddr = "10.0.0.1:5555"
+
+		forwarded := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+		forwarded.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+		withKey := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+		withKey = withKey.WithContext(auth.WithPrincipal(withKey.Context(), auth.Principal{Subject: "reports", Method: auth.MethodAPIKey}))
+
+		RateLimit(limiter, 0, zap.NewNop())("order", ok)(httptest.NewRecorder(), anonymous)
+		RateLimit(limiter, 1, zap.NewNop())("order", ok)(httptest.NewRecorder(), forwarded)
+		RateLimit(limiter, 2, zap.NewNop())("order", ok)(httptest.NewRecorder(), forwarded)
+		RateLimit(limiter, 3, zap.NewNop())("order", ok)(httptest.NewRecorder(), forwarded)
+		RateLimit(limiter, 0, zap.NewNop())("order", ok)(httptest.NewRecorder(), forwarded)
+		RateLimit(limiter, 0, zap.NewNop())("order", ok)(httptest.NewRecorder(), withKey)
+
+		want := []string{"order:ip:10.0.0.1", "order:ip:10.0.0.1", "order:ip:203.0.113.7", "order:ip:192.0.2.1", "order:ip:192.0.2.1", "order:api_key:reports"}
+		for i := range want {
+			if keys[i] != want[i] {
+				t.Errorf("request %d: expected key %q, got %q", i, want[i], keys[i])
+			}
+		}
+	})
+
+	t.Run("spoofed forwarded for", func(t *testing.T) {
+		limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.5, Burst: 1}, nil)
+		h := RateLimit(limiter, 1, zap.NewNop())("order", ok)
+
+		codes := []int{}
+		for _, spoofed := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+			r := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+			// The client sends its own header, the proxy appends the
+			// address it sees.
+			r.Header.Set("X-Forwarded-For", spoofed+", 203.0.113.7")
+			w := httptest.NewRecorder()
+			h(w, r)
+			codes = append(codes, w.Code)
+		}
+
+		want := []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}
+		for i := range want {
+			if codes[i] != want[i] {
+				t.Fatalf("unexpected statuses %v, expected %v", codes, want)
+			}
+		}
+	})
+
+	t.Run("credentials per ip", func(t *testing.T) {
+		limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.5, Burst: 2}, nil)
+
+		authenticated := 0
+		h := RateLimitAuth(limiter, "auth", 0, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authenticated++
+		}))
+
+		codes := []int{}
+		for _, key := range []string{"guess-1", "guess-2", "guess-3", ""} {
+			r := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+			r.RemoteAddr = "10.0.0.1:5555"
+			if key != "" {
+				r.Header.Set("X-API-Key", key)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			codes = append(codes, w.Code)
+		}
+
+		want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK}
+		for i := range want {
+			if codes[i] != want[i] {
+				t.Fatalf("unexpected statuses %v, expected %v", codes, want)
+			}
+		}
+		if authenticated != 3 {
+			t.Errorf("expected 3 requests to reach authentication, got %d", authenticated)
+		}
+	})
+
+	t.Run("store unavailable", func(t *testing.T) {
+		limiter := ratelimit.NewLimiter(MockRateLimitStore{
+			TakeFunc: func(ctx context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error) {
+				return ratelimit.Result{}, errors.New("connection refused")
+			},
+		}, ratelimit.Limit{Rate: 1, Burst: 1}, nil)
+
+		w := httptest.NewRecorder()
+		RateLimit(limiter, 0, zap.NewNop())("order", ok)(w, httptest.NewRequest(http.MethodGet, "/order/1", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected the request to pass, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/stream"
+	"go.uber.org/zap"
+)
+
+const defaultHeartbeat = 15 * time.Second
+
+type StreamHandler struct {
+	Broker    stream.Manager
+	heartbeat time.Duration
+	log       *zap.Logger
+}
+
+func NewStreamHandler(broker stream.Manager, heartbeat time.Duration, logg *zap.Logger) *StreamHandler {
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+
+	return &StreamHandler{
+		Broker:    broker,
+		heartbeat: heartbeat,
+		log:       logg,
+	}
+}
+
+// Stream pushes a summary of every accepted order as Server-Sent Events.
+// The customer and delivery_service query parameters filter the stream;
+// Last-Event-ID resumes it from the retained history.
+func (h StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastID uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	customer := r.URL.Query().Get("customer")
+	deliveryService := r.URL.Query().Get("delivery_service")
+	match := func(s stream.Summary) bool {
+		return (customer == "" || s.CustomerID == customer) &&
+			(deliveryService == "" || s.DeliveryService == deliveryService)
+	}
+
+	sub, backlog, ok := h.Broker.Subscribe(lastID)
+	if !ok {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.Broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, msg := range backlog {
+		if match(msg.Summary) {
+			if err := writeEvent(w, msg); err != nil {
+				return
+			}
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if !match(msg.Summary) {
+				continue
+			}
+			if err := writeEvent(w, msg); err != nil {
+				h.log.Debug("stream client gone", zap.Error(err))
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, msg stream.Message) error {
+
+	data, err := json.Marshal(msg.Summary)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: order\ndata: %s\n\n", msg.ID, data)
+	return err
+}
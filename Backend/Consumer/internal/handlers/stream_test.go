@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"github.com/LootNex/OrderService/Consumer/internal/stream"
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+func TestStream(t *testing.T) {
+	broker := stream.NewBroker(10, 10)
+	for _, o := range []models.Order{
+		{OrderUID: "1", CustomerID: "alice"},
+		{OrderUID: "2", CustomerID: "bob"},
+		{OrderUID: "3", CustomerID: "alice"},
+	} {
+		broker.Notify(events.New(events.OrderCreated, o))
+	}
+
+	h := NewStreamHandler(broker, 10*time.Millisecond, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/stream?customer=alice", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "1")
+	w := httptest.NewRecorder()
+
+	h.Stream(w, req)
+
+	body := w.Body.String()
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(body, "id: 3\n") {
+		t.Errorf("expected order 3 to be resumed, got %q", body)
+	}
+	if strings.Contains(body, `"order_uid":"1"`) || strings.Contains(body, `"order_uid":"2"`) {
+		t.Errorf("expected only alice's orders after id 1, got %q", body)
+	}
+	if !strings.Contains(body, ": heartbeat") {
+		t.Errorf("expected a heartbeat, got %q", body)
+	}
+}
+
+func TestStreamInvalidLastEventID(t *testing.T) {
+	h := NewStreamHandler(stream.NewBroker(10, 10), time.Second, zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/stream", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+
+	h.Stream(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/webhooks"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+const deliveriesLimit = 100
+
+type WebhookHandler struct {
+	Store webhooks.Store
+	log   *zap.Logger
+}
+
+func NewWebhookHandler(store webhooks.Store, logg *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		Store: store,
+		log:   logg,
+	}
+}
+
+// webhookRequest is the body of create and update calls. Omitted fields keep
+// their current value on update.
+type webhookRequest struct {
+	URL        *string   `json:"url"`
+	EventTypes *[]string `json:"event_types"`
+	Secret     *string   `json:"secret"`
+	Active     *bool     `json:"active"`
+}
+
+func (req webhookRequest) apply(sub *webhooks.Subscription) {
+	if req.URL != nil {
+		sub.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		sub.EventTypes = *req.EventTypes
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+}
+
+// Create registers a webhook. The secret is generated unless given and is
+// only returned in this response.
+func (h WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub := webhooks.Subscription{Active: true}
+	req.apply(&sub)
+	if sub.Secret == "" {
+		sub.Secret = webhooks.NewSecret()
+	}
+
+	if err := sub.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.Store.CreateWebhook(r.Context(), sub)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	writeJSON(w, h.log, http.StatusCreated, created)
+}
+
+func (h WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+
+	subs, err := h.Store.ListWebhooks(r.Context())
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	if subs == nil {
+		subs = []webhooks.Subscription{}
+	}
+
+	writeJSON(w, h.log, http.StatusOK, subs)
+}
+
+func (h WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.Store.GetWebhook(r.Context(), id)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	sub.Secret = ""
+	writeJSON(w, h.log, http.StatusOK, sub)
+}
+
+// Update changes a webhook. Setting "active": true re-enables a webhook that
+// was disabled after repeated failures.
+func (h WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.Store.GetWebhook(r.Context(), id)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	req.apply(&sub)
+	if err := sub.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.Store.UpdateWebhook(r.Context(), sub)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	updated.Secret = ""
+	writeJSON(w, h.log, http.StatusOK, updated)
+}
+
+func (h WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Store.DeleteWebhook(r.Context(), id); err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries returns the latest delivery attempts of a webhook.
+func (h WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.Store.GetWebhook(r.Context(), id); err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	deliveries, err := h.Store.ListDeliveries(r.Context(), id, deliveriesLimit)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []webhooks.Delivery{}
+	}
+
+	writeJSON(w, h.log, http.StatusOK, deliveries)
+}
+
+func (h WebhookHandler) fail(w http.ResponseWriter, err error) {
+	if errors.Is(err, errs.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.log.Error("webhook request failed", zap.Error(err))
+	http.Error(w, "problems with server, try again later", http.StatusInternalServerError)
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/webhooks"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type MockWebhookStore struct {
+	CreateWebhookFunc  func(ctx context.Context, sub webhooks.Subscription) (webhooks.Subscription, error)
+	GetWebhookFunc     func(ctx context.Context, id int64) (webhooks.Subscription, error)
+	ListWebhooksFunc   func(ctx context.Context) ([]webhooks.Subscription, error)
+	UpdateWebhookFunc  func(ctx context.Context, sub webhooks.Subscription) (webhooks.Subscription, error)
+	DeleteWebhookFunc  func(ctx context.Context, id int64) error
+	WebhooksForFunc    func(ctx context.Context, eventType string) ([]webhooks.Subscription, error)
+	LogDeliveryFunc    func(ctx context.Context, d webhooks.Delivery) error
+	ListDeliveriesFunc func(ctx context.Context, webhookID int64, limit int) ([]webhooks.Delivery, error)
+	RecordFailureFunc  func(ctx context.Context, id int64, disableAfter int) (bool, error)
+	RecordSuccessFunc  func(ctx context.Context, id int64) error
+}
+
+func (m MockWebhookStore) CreateWebhook(ctx context.Context, sub webhooks.Subscription) (webhooks.Subscription, error) {
+	return m.CreateWebhookFunc(ctx, sub)
+}
+
+func (m MockWebhookStore) GetWebhook(ctx context.Context, id int64) (webhooks.Subscription, error) {
+	return m.GetWebhookFunc(ctx, id)
+}
+
+func (m MockWebhookStore) ListWebhooks(ctx context.Context) ([]webhooks.Subscription, error) {
+	return m.ListWebhooksFunc(ctx)
+}
+
+func (m MockWebhookStore) UpdateWebhook(ctx context.Context, sub webhooks.Subscription) (webhooks.Subscription, error) {
+	return m.UpdateWebhookFunc(ctx, sub)
+}
+
+func (m MockWebhookStore) DeleteWebhook(ctx context.Context, id int64) error {
+	return m.DeleteWebhookFunc(ctx, id)
+}
+
+func (m MockWebhookStore) WebhooksFor(ctx context.Context, eventType string) ([]webhooks.Subscription, error) {
+	return m.WebhooksForFunc(ctx, eventType)
+}
+
+func (m MockWebhookStore) LogDelivery(ctx context.Context, d webhooks.Delivery) error {
+	return m.LogDeliveryFunc(ctx, d)
+}
+
+func (m MockWebhookStore) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]webhooks.Delivery, error) {
+	return m.ListDeliveriesFunc(ctx, webhookID, limit)
+}
+
+func (m MockWebhookStore) RecordFailure(ctx context.Context, id int64, disableAfter int) (bool, error) {
+	return m.RecordFailureFunc(ctx, id, disableAfter)
+}
+
+func (m MockWebhookStore) RecordSuccess(ctx context.Context, id int64) error {
+	return m.RecordSuccessFunc(ctx, id)
+}
+
+func TestCreateWebhook(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"url":"https://example.com/hook","event_types":["order.created"]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "invalid url",
+			body:       `{"url":"example.com","event_types":["order.created"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown event type",
+			body:       `{"url":"https://example.com/hook","event_types":["order.deleted"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := MockWebhookStore{
+				CreateWebhookFunc: func(ctx context.Context, sub webhooks.Subscription) (webhooks.Subscription, error) {
+					sub.ID = 1
+					return sub, nil
+				},
+			}
+			h := NewWebhookHandler(store, zap.NewNop())
+
+			w := httptest.NewRecorder()
+			h.Create(w, httptest.NewRequest(http.MethodPost, "/admin/webhooks", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+
+			if tt.wantStatus == http.StatusCreated {
+				var sub webhooks.Subscription
+				if err := json.NewDecoder(w.Body).Decode(&sub); err != nil {
+					t.Fatalf("cannot decode response: %v", err)
+				}
+				if sub.Secret == "" || !sub.Active {
+					t.Errorf("expected an active webhook with a generated secret, got %+v", sub)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	stored := webhooks.Subscription{ID: 7, URL: "https://example.com/hook",
+		EventTypes: []string{"order.created"}, Secret: "s", FailureCount: 10}
+
+	tests := []struct {
+		name       string
+		id         string
+		getErr     error
+		wantStatus int
+	}{
+		{
+			name:       "re-enable",
+			id:         "7",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not found",
+			id:         "8",
+			getErr:     errs.ErrWebhookNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "bad id",
+			id:         "abc",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated webhooks.Subscription
+			store := MockWebhookStore{
+				GetWebhookFunc: func(ctx context.Context, id int64) (webhooks.Subscription, error) {
+					return stored, tt.getErr
+				},
+				UpdateWebhookFunc: func(ctx context.Context, sub webhooks.Subscription) (webhooks.Subscription, error) {
+					updated = sub
+					return sub, nil
+				},
+			}
+			h := NewWebhookHandler(store, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/webhooks/"+tt.id, strings.NewReader(`{"active":true}`))
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+			h.Update(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if !updated.Active || updated.URL != stored.URL {
+					t.Errorf("unexpected update %+v", updated)
+				}
+				if strings.Contains(w.Body.String(), `"secret"`) {
+					t.Error("secret must not be returned after creation")
+				}
+			}
+		})
+	}
+}
@@ -2,61 +2,283 @@ package consumer
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"sync"
+	"time"
 
-	"github.com/LootNex/OrderService/Consumer/internal/models"
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
 	"github.com/LootNex/OrderService/Consumer/internal/service"
+	"github.com/LootNex/OrderService/Contract/models"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 )
 
-func StartConsumer(ctx context.Context, topic string, brokers []string, serv service.ServiceManager, log *zap.Logger) {
-	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:     brokers,
-		Topic:       topic,
-		GroupID:     "order-service",
-		StartOffset: kafka.FirstOffset,
-		MinBytes:    10e3,
-		MaxBytes:    10e6,
-	})
+const (
+	retryBackoff    = 500 * time.Millisecond
+	maxRetryBackoff = 30 * time.Second
+)
+
+// Consumer reads orders from Kafka. Ingestion can be paused manually
+// through the admin API or automatically while Postgres is unhealthy; the
+// HTTP read API keeps working in both cases.
+type Consumer struct {
+	reader *kafka.Reader
+	serv   service.ServiceManager
+	log    *zap.Logger
+	// backoff is the first pause between save attempts; it doubles up to
+	// maxRetryBackoff.
+	backoff time.Duration
+
+	mu        sync.Mutex
+	manual    bool
+	unhealthy bool
+	resumed   chan struct{}
+}
+
+type Status struct {
+	State     string `json:"state"`
+	Reason    string `json:"reason,omitempty"`
+	Lag       int64  `json:"lag"`
+	Offset    int64  `json:"offset"`
+	Partition string `json:"partition,omitempty"`
+}
+
+type ConsumerController interface {
+	Pause()
+	Resume()
+	Status() Status
+}
+
+func NewConsumer(topic string, brokers []string, serv service.ServiceManager, log *zap.Logger) *Consumer {
+	return &Consumer{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     brokers,
+			Topic:       topic,
+			GroupID:     "order-service",
+			StartOffset: kafka.FirstOffset,
+			MinBytes:    10e3,
+			MaxBytes:    10e6,
+		}),
+		serv:    serv,
+		log:     log,
+		backoff: retryBackoff,
+		resumed: make(chan struct{}),
+	}
+}
+
+// Run consumes orders until ctx is cancelled. Cancellation only stops the
+// next fetch: the message in flight is still saved and committed, so a
+// shutdown never leaves a half-processed order behind.
+func (c *Consumer) Run(ctx context.Context) error {
 	defer func() {
-		if err := r.Close(); err != nil {
-			log.Error("failed to close Kafka reader", zap.Error(err))
+		if err := c.reader.Close(); err != nil {
+			c.log.Error("failed to close Kafka reader", zap.Error(err))
 		}
 	}()
 
-	log.Info("Kafka consumer started")
+	c.log.Info("Kafka consumer started")
+
+	for {
+		if !c.waitResumed(ctx) {
+			c.log.Info("Kafka consumer stopping gracefully...")
+			return nil
+		}
+
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.log.Info("Kafka consumer stopping gracefully...")
+				return nil
+			}
+			c.log.Warn("Ошибка чтения из Kafka", zap.Error(err))
+			continue
+		}
+
+		c.process(ctx, msg)
+	}
+}
+
+func (c *Consumer) process(ctx context.Context, msg kafka.Message) {
+
+	drainCtx := context.WithoutCancel(ctx)
+
+	order, err := DecodeOrder(msg)
+	if err != nil {
+		c.log.Warn("Ошибка декодирования заказа", zap.Error(err))
+		c.commit(drainCtx, msg)
+		return
+	}
+
+	if err := c.saveWithRetry(ctx, drainCtx, &order); err != nil {
+		c.log.Warn("Ошибка сохранения заказа", zap.Error(err))
+		if !permanent(err) {
+			// Left uncommitted, so an order whose retries were cut short by a
+			// shutdown is redelivered after the restart.
+			return
+		}
+	} else {
+		c.log.Info("GET ORDER", zap.String("order_uid", order.OrderUID))
+	}
+
+	c.commit(drainCtx, msg)
+}
+
+func (c *Consumer) commit(ctx context.Context, msg kafka.Message) {
+	if err := c.reader.CommitMessages(ctx, msg); err != nil {
+		c.log.Error("error commit message err", zap.Error(err))
+	}
+}
+
+// Pause stops ingestion after the message that is being processed.
+func (c *Consumer) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.manual = true
+	c.log.Info("Kafka consumer paused")
+}
+
+// Resume lifts a manual pause. Ingestion stays paused while Postgres is
+// unhealthy.
+func (c *Consumer) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.manual = false
+	c.log.Info("Kafka consumer resumed")
+	c.signalLocked()
+}
+
+func (c *Consumer) Status() Status {
+	c.mu.Lock()
+	st := Status{State: "running"}
+	switch {
+	case c.manual:
+		st.State, st.Reason = "paused", "paused by admin"
+	case c.unhealthy:
+		st.State, st.Reason = "paused", "postgres unavailable"
+	}
+	c.mu.Unlock()
+
+	if c.reader != nil {
+		stats := c.reader.Stats()
+		st.Lag, st.Offset, st.Partition = stats.Lag, stats.Offset, stats.Partition
+	}
+
+	return st
+}
+
+func (c *Consumer) setHealthy(healthy bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.unhealthy == !healthy {
+		return
+	}
+
+	c.unhealthy = !healthy
+	if healthy {
+		c.log.Info("postgres is healthy again, resuming Kafka consumer")
+		c.signalLocked()
+	} else {
+		c.log.Warn("postgres health check failed, pausing Kafka consumer")
+	}
+}
+
+// signalLocked wakes up the loop if nothing keeps it paused any more.
+func (c *Consumer) signalLocked() {
+	if c.manual || c.unhealthy {
+		return
+	}
+	close(c.resumed)
+	c.resumed = make(chan struct{})
+}
+
+// waitResumed blocks while the consumer is paused and reports false if ctx
+// was cancelled in the meantime.
+func (c *Consumer) waitResumed(ctx context.Context) bool {
+	for {
+		c.mu.Lock()
+		paused, resumed := c.manual || c.unhealthy, c.resumed
+		c.mu.Unlock()
+
+		if !paused {
+			return ctx.Err() == nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-resumed:
+		}
+	}
+}
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// WatchHealth pings Postgres every interval and pauses ingestion while the
+// ping fails, so messages are not burned through retries during an outage.
+func (c *Consumer) WatchHealth(ctx context.Context, db Pinger, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info("Kafka consumer stopping gracefully...")
 			return
-		default:
-			msg, err := r.ReadMessage(ctx)
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, interval)
+			err := db.PingContext(pingCtx)
+			cancel()
+
+			if ctx.Err() != nil {
+				return
+			}
 			if err != nil {
-				log.Warn("Ошибка чтения из Kafka", zap.Error(err))
-				continue
+				c.log.Warn("postgres ping failed", zap.Error(err))
 			}
+			c.setHealthy(err == nil)
+		}
+	}
+}
 
-			var order models.Order
-
-			if err := json.Unmarshal(msg.Value, &order); err != nil {
-				log.Warn("Ошибка парсинга JSON", zap.Error(err))
-				continue
-			}
+// saveWithRetry retries transient failures for as long as ctx lives, so a
+// status update is never skipped while later updates of the same order,
+// which sit behind it in the partition, get applied. Only permanent errors
+// end the retries. Saves run on drainCtx, which outlives shutdown; ctx only
+// cuts the waiting between attempts short. A pause, manual or because
+// Postgres is down, also holds the retries.
+func (c *Consumer) saveWithRetry(ctx, drainCtx context.Context, order *models.Order) error {
 
-			if err := serv.SaveNewOrder(ctx, &order); err != nil {
-				log.Warn("Ошибка сохранения заказа", zap.Error(err))
+	backoff := c.backoff
 
-			} else {
-				if err = r.CommitMessages(ctx, msg); err != nil {
-					log.Error("error commit message err", zap.Error(err))
-				}
+	for attempt := 1; ; attempt++ {
+		err := c.serv.SaveNewOrder(drainCtx, order)
+		if err == nil || permanent(err) {
+			return err
+		}
 
-				log.Info("GET ORDER", zap.String("order_uid", order.OrderUID))
+		c.log.Warn("cannot save order, retrying", zap.String("order_uid", order.OrderUID),
+			zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
 
-			}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		if !c.waitResumed(ctx) {
+			return err
 		}
+
+		backoff = min(2*backoff, maxRetryBackoff)
 	}
 }
+
+// permanent reports whether saving again cannot succeed, so the message is
+// committed and skipped.
+func permanent(err error) bool {
+	return errors.Is(err, errs.ErrInvalidOrder) || errors.Is(err, errs.ErrOrderNotFound)
+}
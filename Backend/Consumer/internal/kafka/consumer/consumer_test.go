@@ -0,0 +1,203 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/service"
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+type MockPinger struct {
+	healthy atomic.Bool
+}
+
+func (mp *MockPinger) PingContext(ctx context.Context) error {
+	if mp.healthy.Load() {
+		return nil
+	}
+	return errors.New("connection refused")
+}
+
+// MockServiceManager only implements saving; the consumer calls nothing
+// else.
+type MockServiceManager struct {
+	service.ServiceManager
+	SaveNewOrderFunc func(ctx context.Context, val models.Validator) error
+}
+
+func (mSM MockServiceManager) SaveNewOrder(ctx context.Context, val models.Validator) error {
+	return mSM.SaveNewOrderFunc(ctx, val)
+}
+
+func newTestConsumer() *Consumer {
+	return &Consumer{log: zap.NewNop(), backoff: time.Millisecond, resumed: make(chan struct{})}
+}
+
+func waitsFor(c *Consumer, d time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return !c.waitResumed(ctx)
+}
+
+func TestPauseResume(t *testing.T) {
+	c := newTestConsumer()
+
+	if waitsFor(c, 10*time.Millisecond) {
+		t.Fatal("running consumer must not wait")
+	}
+
+	c.Pause()
+	if st := c.Status(); st.State != "paused" || st.Reason != "paused by admin" {
+		t.Errorf("unexpected status %+v", st)
+	}
+	if !waitsFor(c, 10*time.Millisecond) {
+		t.Fatal("paused consumer must wait")
+	}
+
+	done := make(chan bool)
+	go func() { done <- c.waitResumed(context.Background()) }()
+	c.Resume()
+
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("expected the loop to resume")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("loop was not woken up by Resume")
+	}
+}
+
+func TestWatchHealth(t *testing.T) {
+	c := newTestConsumer()
+	db := &MockPinger{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.WatchHealth(ctx, db, 5*time.Millisecond)
+
+	eventually := func(state string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for c.Status().State != state {
+			if time.Now().After(deadline) {
+				t.Fatalf("consumer did not become %s", state)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	eventually("paused")
+	if st := c.Status(); st.Reason != "postgres unavailable" {
+		t.Errorf("unexpected reason %q", st.Reason)
+	}
+
+	db.healthy.Store(true)
+	eventually("running")
+
+	c.Pause()
+	db.healthy.Store(false)
+	eventually("paused")
+	db.healthy.Store(true)
+	time.Sleep(20 * time.Millisecond)
+	if st := c.Status(); st.State != "paused" {
+		t.Error("recovery of postgres must not lift a manual pause")
+	}
+}
+
+func TestSaveWithRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "saved", wantAttempts: 1},
+		{name: "transient failures", failures: 8, err: errors.New("connection reset"), wantAttempts: 9},
+		{name: "invalid order", failures: 100, err: errs.ErrInvalidOrder, wantAttempts: 1, wantErr: errs.ErrInvalidOrder},
+		{name: "order not found", failures: 100, err: errs.ErrOrderNotFound, wantAttempts: 1, wantErr: errs.ErrOrderNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestConsumer()
+			attempts := 0
+			c.serv = MockServiceManager{SaveNewOrderFunc: func(ctx context.Context, val models.Validator) error {
+				attempts++
+				if attempts <= tt.failures {
+					return tt.err
+				}
+				return nil
+			}}
+
+			err := c.saveWithRetry(context.Background(), context.Background(), &models.Order{OrderUID: "1"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestSaveWithRetryStopsOnShutdown(t *testing.T) {
+	c := newTestConsumer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	c.serv = MockServiceManager{SaveNewOrderFunc: func(context.Context, models.Validator) error {
+		attempts++
+		if attempts == 3 {
+			cancel()
+		}
+		return errors.New("connection reset")
+	}}
+
+	err := c.saveWithRetry(ctx, context.Background(), &models.Order{OrderUID: "1"})
+	if err == nil || permanent(err) {
+		t.Fatalf("expected the transient error, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected retries to stop at shutdown, got %d attempts", attempts)
+	}
+}
+
+func TestSaveWithRetryHoldsWhilePaused(t *testing.T) {
+	c := newTestConsumer()
+	c.Pause()
+
+	saved := make(chan struct{})
+	attempts := 0
+	c.serv = MockServiceManager{SaveNewOrderFunc: func(context.Context, models.Validator) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("connection reset")
+		}
+		close(saved)
+		return nil
+	}}
+
+	done := make(chan error)
+	go func() {
+		done <- c.saveWithRetry(context.Background(), context.Background(), &models.Order{OrderUID: "1"})
+	}()
+
+	select {
+	case <-saved:
+		t.Fatal("a paused consumer must not retry")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	c.Resume()
+	if err := <-done; err != nil {
+		t.Errorf("expected the order saved after resume, got %v", err)
+	}
+}
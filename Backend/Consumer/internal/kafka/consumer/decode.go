@@ -0,0 +1,22 @@
+package consumer
+
+import (
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/segmentio/kafka-go"
+)
+
+// ContentType returns the encoding of the message taken from its
+// content-type header. Messages without the header are treated as JSON,
+// which is what producers sent before protobuf support was added.
+func ContentType(msg kafka.Message) string {
+	for _, h := range msg.Headers {
+		if h.Key == models.HeaderContentType {
+			return string(h.Value)
+		}
+	}
+	return models.ContentTypeJSON
+}
+
+func DecodeOrder(msg kafka.Message) (models.Order, error) {
+	return models.Unmarshal(msg.Value, ContentType(msg))
+}
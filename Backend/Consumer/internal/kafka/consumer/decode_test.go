@@ -0,0 +1,73 @@
+package consumer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestDecodeOrder(t *testing.T) {
+	order := models.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Delivery:    models.Delivery{Name: "Test Testov", Email: "test@gmail.com"},
+		Payment:     models.Payment{Transaction: "b563feb7b2b84b6test", Amount: 1817},
+		Items:       []models.Item{{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Status: 202}},
+		DateCreated: "2021-11-26T06:22:19Z",
+	}
+
+	jsonValue, err := models.Marshal(order, models.ContentTypeJSON)
+	if err != nil {
+		t.Fatalf("cannot marshal json: %v", err)
+	}
+	protoValue, err := models.Marshal(order, models.ContentTypeProtobuf)
+	if err != nil {
+		t.Fatalf("cannot marshal protobuf: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		msg     kafka.Message
+		wantErr bool
+	}{
+		{
+			name: "json without header",
+			msg:  kafka.Message{Value: jsonValue},
+		},
+		{
+			name: "json",
+			msg: kafka.Message{Value: jsonValue,
+				Headers: []kafka.Header{{Key: models.HeaderContentType, Value: []byte(models.ContentTypeJSON)}}},
+		},
+		{
+			name: "protobuf",
+			msg: kafka.Message{Value: protoValue,
+				Headers: []kafka.Header{{Key: models.HeaderContentType, Value: []byte(models.ContentTypeProtobuf)}}},
+		},
+		{
+			name:    "protobuf without header",
+			msg:     kafka.Message{Value: protoValue},
+			wantErr: true,
+		},
+		{
+			name: "unknown content type",
+			msg: kafka.Message{Value: jsonValue,
+				Headers: []kafka.Header{{Key: models.HeaderContentType, Value: []byte("text/xml")}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeOrder(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected err:%v, got err:%v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, order) {
+				t.Errorf("decoded order mismatch:\n got %+v\nwant %+v", got, order)
+			}
+		})
+	}
+}
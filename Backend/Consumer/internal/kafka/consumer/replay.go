@@ -0,0 +1,410 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+	"github.com/LootNex/OrderService/Consumer/internal/service"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// maxReportedConflicts bounds the order ids listed in a replay report.
+const maxReportedConflicts = 100
+
+// ApplyConflicting makes a replay overwrite stored orders that differ from
+// the replayed ones.
+const ApplyConflicting = "conflicting"
+
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+var (
+	ErrReplayRunning  = errors.New("replay already running")
+	ErrReplayerClosed = errors.New("replayer is not running")
+)
+
+// ReplayRequest selects what to read again. Either Offsets (partition to
+// first offset) or From must be set. Reading stops at To, if set, and in
+// any case at the end of each partition as seen when the replay started.
+// Apply set to ApplyConflicting overwrites conflicting orders; by default
+// they are only reported.
+type ReplayRequest struct {
+	Offsets map[int]int64 `json:"offsets,omitempty"`
+	From    time.Time     `json:"from,omitempty"`
+	To      time.Time     `json:"to,omitempty"`
+	Apply   string        `json:"apply,omitempty"`
+}
+
+func (rr ReplayRequest) Validate() error {
+	if len(rr.Offsets) == 0 && rr.From.IsZero() {
+		return errors.New("either offsets or from must be set")
+	}
+	if len(rr.Offsets) > 0 && !rr.From.IsZero() {
+		return errors.New("offsets and from cannot be used together")
+	}
+	if !rr.To.IsZero() && !rr.From.IsZero() && rr.To.Before(rr.From) {
+		return errors.New("to is before from")
+	}
+	if rr.Apply != "" && rr.Apply != ApplyConflicting {
+		return fmt.Errorf("unknown apply mode %q", rr.Apply)
+	}
+	for p, offset := range rr.Offsets {
+		if p < 0 || offset < 0 {
+			return fmt.Errorf("invalid offset %d for partition %d", offset, p)
+		}
+	}
+	return nil
+}
+
+type PartitionRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+type ReplayReport struct {
+	Partitions     map[string]PartitionRange `json:"partitions"`
+	Read           int                       `json:"read"`
+	New            int                       `json:"new"`
+	Identical      int                       `json:"identical"`
+	Conflicting    int                       `json:"conflicting"`
+	Overwritten    int                       `json:"overwritten"`
+	Invalid        int                       `json:"invalid"`
+	Failed         int                       `json:"failed"`
+	ConflictingIDs []string                  `json:"conflicting_ids,omitempty"`
+	OverwrittenIDs []string                  `json:"overwritten_ids,omitempty"`
+	Elapsed        string                    `json:"elapsed"`
+}
+
+func (rr ReplayReport) clone() ReplayReport {
+	res := rr
+	res.Partitions = make(map[string]PartitionRange, len(rr.Partitions))
+	for p, rng := range rr.Partitions {
+		res.Partitions[p] = rng
+	}
+	res.ConflictingIDs = append([]string(nil), rr.ConflictingIDs...)
+	res.OverwrittenIDs = append([]string(nil), rr.OverwrittenIDs...)
+	return res
+}
+
+// ReplayJob is a replay running in the background. Report is updated as
+// messages are read, so polling the job shows the progress.
+type ReplayJob struct {
+	ID         string        `json:"id"`
+	State      string        `json:"state"`
+	Request    ReplayRequest `json:"request"`
+	Report     ReplayReport  `json:"report"`
+	Error      string        `json:"error,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+type ReplayManager interface {
+	// Start validates req and runs the replay in the background.
+	Start(req ReplayRequest) (ReplayJob, error)
+	// Job returns the last started replay, if any.
+	Job() (ReplayJob, bool)
+}
+
+// Replayer reprocesses a range of the topic with its own partition
+// readers. It does not join the consumer group, so the committed offsets
+// of the main consumer stay untouched. One replay runs at a time, as a
+// job bound to the context passed to Run.
+type Replayer struct {
+	brokers []string
+	topic   string
+	serv    service.ServiceManager
+	privacy *privacy.Policy
+	log     *zap.Logger
+
+	mu   sync.Mutex
+	ctx  context.Context
+	job  *ReplayJob
+	seq  int
+	jobs sync.WaitGroup
+}
+
+func NewReplayer(brokers []string, topic string, serv service.ServiceManager, policy *privacy.Policy, log *zap.Logger) *Replayer {
+	return &Replayer{
+		brokers: brokers,
+		topic:   topic,
+		serv:    serv,
+		privacy: policy,
+		log:     log,
+	}
+}
+
+// Run accepts replays until ctx is cancelled, which also stops the running
+// one, and returns when it has finished.
+func (rp *Replayer) Run(ctx context.Context) error {
+
+	rp.mu.Lock()
+	rp.ctx = ctx
+	rp.mu.Unlock()
+
+	<-ctx.Done()
+	rp.jobs.Wait()
+
+	return nil
+}
+
+func (rp *Replayer) Start(req ReplayRequest) (ReplayJob, error) {
+
+	if err := req.Validate(); err != nil {
+		return ReplayJob{}, err
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if rp.ctx == nil || rp.ctx.Err() != nil {
+		return ReplayJob{}, ErrReplayerClosed
+	}
+	if rp.job != nil && rp.job.State == JobRunning {
+		return ReplayJob{}, ErrReplayRunning
+	}
+
+	rp.seq++
+	rp.job = &ReplayJob{
+		ID:        strconv.Itoa(rp.seq),
+		State:     JobRunning,
+		Request:   req,
+		Report:    ReplayReport{Partitions: map[string]PartitionRange{}},
+		StartedAt: time.Now().UTC(),
+	}
+
+	rp.jobs.Add(1)
+	go func(ctx context.Context) {
+		defer rp.jobs.Done()
+		rp.finish(rp.replay(ctx, req))
+	}(rp.ctx)
+
+	return rp.snapshot(), nil
+}
+
+func (rp *Replayer) Job() (ReplayJob, bool) {
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if rp.job == nil {
+		return ReplayJob{}, false
+	}
+	return rp.snapshot(), true
+}
+
+// snapshot copies the job, so callers can read it while the replay goes
+// on. rp.mu must be held.
+func (rp *Replayer) snapshot() ReplayJob {
+	job := *rp.job
+	job.Report = rp.job.Report.clone()
+	return job
+}
+
+// progress publishes the report of the running job.
+func (rp *Replayer) progress(report *ReplayReport) {
+	rp.mu.Lock()
+	rp.job.Report = report.clone()
+	rp.mu.Unlock()
+}
+
+func (rp *Replayer) finish(report ReplayReport, err error) {
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	now := time.Now().UTC()
+	rp.job.Report = report
+	rp.job.FinishedAt = &now
+	rp.job.State = JobDone
+	if err != nil {
+		rp.job.State = JobFailed
+		rp.job.Error = err.Error()
+		rp.log.Error("replay failed", zap.String("job", rp.job.ID), zap.Error(err))
+	}
+}
+
+func (rp *Replayer) replay(ctx context.Context, req ReplayRequest) (ReplayReport, error) {
+
+	report := ReplayReport{Partitions: map[string]PartitionRange{}}
+
+	start := time.Now()
+	defer func() { report.Elapsed = time.Since(start).Round(time.Millisecond).String() }()
+
+	partitions, err := rp.partitions(req)
+	if err != nil {
+		return report, err
+	}
+
+	for _, p := range partitions {
+		rng, err := rp.partitionRange(ctx, p, req)
+		if err != nil {
+			return report, err
+		}
+		report.Partitions[strconv.Itoa(p)] = rng
+
+		if err := rp.replayPartition(ctx, p, rng, req, &report); err != nil {
+			return report, err
+		}
+	}
+
+	rp.log.Info("replay finished", zap.Int("read", report.Read), zap.Int("new", report.New),
+		zap.Int("identical", report.Identical), zap.Int("conflicting", report.Conflicting),
+		zap.Int("overwritten", report.Overwritten))
+
+	return report, nil
+}
+
+func (rp *Replayer) partitions(req ReplayRequest) ([]int, error) {
+
+	if len(req.Offsets) > 0 {
+		res := make([]int, 0, len(req.Offsets))
+		for p := range req.Offsets {
+			res = append(res, p)
+		}
+		sort.Ints(res)
+		return res, nil
+	}
+
+	conn, err := kafka.Dial("tcp", rp.brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial kafka: %w", err)
+	}
+	defer conn.Close()
+
+	parts, err := conn.ReadPartitions(rp.topic)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read partitions err:%w", err)
+	}
+
+	res := make([]int, 0, len(parts))
+	for _, p := range parts {
+		res = append(res, p.ID)
+	}
+	sort.Ints(res)
+	return res, nil
+}
+
+// partitionRange resolves the first offset to read and the high watermark
+// at the moment of the call, which is where the replay stops.
+func (rp *Replayer) partitionRange(ctx context.Context, partition int, req ReplayRequest) (PartitionRange, error) {
+
+	var rng PartitionRange
+
+	conn, err := kafka.DialLeader(ctx, "tcp", rp.brokers[0], rp.topic, partition)
+	if err != nil {
+		return rng, fmt.Errorf("cannot dial leader of partition %d err:%w", partition, err)
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return rng, fmt.Errorf("cannot read offsets of partition %d err:%w", partition, err)
+	}
+
+	start, ok := req.Offsets[partition]
+	if !ok {
+		if start, err = conn.ReadOffset(req.From); err != nil {
+			return rng, fmt.Errorf("cannot find offset at %s in partition %d err:%w", req.From, partition, err)
+		}
+		// Kafka answers -1 when every message is older than From.
+		if start < 0 {
+			start = last
+		}
+	}
+
+	rng.Start = min(max(start, first), last)
+	rng.End = last
+	return rng, nil
+}
+
+func (rp *Replayer) replayPartition(ctx context.Context, partition int, rng PartitionRange, req ReplayRequest, report *ReplayReport) error {
+
+	if rng.Start >= rng.End {
+		return nil
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   rp.brokers,
+		Topic:     rp.topic,
+		Partition: partition,
+		MinBytes:  10e3,
+		MaxBytes:  10e6,
+		MaxWait:   time.Second,
+	})
+	defer func() {
+		if err := r.Close(); err != nil {
+			rp.log.Error("failed to close replay reader", zap.Error(err))
+		}
+	}()
+
+	if err := r.SetOffset(rng.Start); err != nil {
+		return fmt.Errorf("cannot set offset err:%w", err)
+	}
+
+	for {
+		msg, err := r.ReadMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot read partition %d err:%w", partition, err)
+		}
+
+		if !req.To.IsZero() && msg.Time.After(req.To) {
+			return nil
+		}
+
+		report.Read++
+		rp.replayMessage(ctx, msg, req.Apply == ApplyConflicting, report)
+		rp.progress(report)
+
+		if msg.Offset >= rng.End-1 {
+			return nil
+		}
+	}
+}
+
+func (rp *Replayer) replayMessage(ctx context.Context, msg kafka.Message, overwrite bool, report *ReplayReport) {
+
+	order, err := DecodeOrder(msg)
+	if err != nil {
+		report.Invalid++
+		return
+	}
+
+	res, err := rp.serv.ReplayOrder(ctx, order, overwrite)
+	switch {
+	case errors.Is(err, errs.ErrInvalidOrder):
+		report.Invalid++
+	case err != nil:
+		report.Failed++
+		rp.log.Warn("cannot replay order", zap.String("order_uid", order.OrderUID),
+			zap.Int64("offset", msg.Offset), zap.Error(err))
+	case res == service.ReplayNew:
+		report.New++
+	case res == service.ReplayIdentical:
+		report.Identical++
+	case res == service.ReplayConflicting:
+		report.Conflicting++
+		rp.log.Warn("replayed order differs from the stored one", zap.String("order_uid", order.OrderUID),
+			zap.Int64("offset", msg.Offset), rp.privacy.LogDelivery(order.Delivery))
+		if len(report.ConflictingIDs) < maxReportedConflicts {
+			report.ConflictingIDs = append(report.ConflictingIDs, order.OrderUID)
+		}
+	case res == service.ReplayOverwritten:
+		report.Overwritten++
+		rp.log.Info("stored order overwritten by replay", zap.String("order_uid", order.OrderUID),
+			zap.Int64("offset", msg.Offset))
+		if len(report.OverwrittenIDs) < maxReportedConflicts {
+			report.OverwrittenIDs = append(report.OverwrittenIDs, order.OrderUID)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestReplayerStart(t *testing.T) {
+
+	rp := NewReplayer([]string{"127.0.0.1:1"}, "orders", nil, nil, zap.NewNop())
+	req := ReplayRequest{Offsets: map[int]int64{0: 0}}
+
+	if _, err := rp.Start(req); !errors.Is(err, ErrReplayerClosed) {
+		t.Fatalf("expected ErrReplayerClosed before Run, got %v", err)
+	}
+	if _, ok := rp.Job(); ok {
+		t.Fatalf("expected no job before the first replay")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = rp.Run(ctx)
+	}()
+
+	// Run stores its context asynchronously.
+	var job ReplayJob
+	var err error
+	for i := 0; i < 100; i++ {
+		if job, err = rp.Start(req); !errors.Is(err, ErrReplayerClosed) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.ID != "1" || job.State != JobRunning {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	if _, err := rp.Start(ReplayRequest{}); err == nil {
+		t.Errorf("expected invalid request to be rejected")
+	}
+
+	// No broker listens, so the replay fails and the job reports it.
+	for i := 0; i < 100; i++ {
+		if job, _ = rp.Job(); job.State != JobRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job.State != JobFailed || job.Error == "" || job.FinishedAt == nil {
+		t.Errorf("expected failed job, got %+v", job)
+	}
+
+	cancel()
+	<-done
+
+	if _, err := rp.Start(req); !errors.Is(err, ErrReplayerClosed) {
+		t.Errorf("expected ErrReplayerClosed after shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// Component is one part of the service. All hooks are optional.
+type Component struct {
+	Name string
+	// Start prepares the component. Components start one by one in the
+	// order they were added; nothing runs until every Start succeeded.
+	Start func(ctx context.Context) error
+	// Run blocks until its context is cancelled. Returning earlier, with or
+	// without an error, shuts the whole service down.
+	Run func(ctx context.Context) error
+	// Stop releases the component. It is called after the components added
+	// later have stopped and their Run has returned.
+	Stop func(ctx context.Context) error
+}
+
+// Manager starts components in order, supervises them like an errgroup and
+// stops them in reverse order, waiting for each to drain before the next.
+type Manager struct {
+	components      []Component
+	shutdownTimeout time.Duration
+	log             *zap.Logger
+}
+
+func NewManager(shutdownTimeout time.Duration, logg *zap.Logger) *Manager {
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+
+	return &Manager{
+		shutdownTimeout: shutdownTimeout,
+		log:             logg,
+	}
+}
+
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+type running struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Run blocks until ctx is cancelled or a component fails, then shuts
+// everything down. It returns the error that caused the shutdown, if any.
+func (m *Manager) Run(ctx context.Context) error {
+
+	for i, c := range m.components {
+		if c.Start == nil {
+			continue
+		}
+		if err := c.Start(ctx); err != nil {
+			m.stop(m.components[:i], nil)
+			return fmt.Errorf("cannot start %s: %w", c.Name, err)
+		}
+		m.log.Info("component started", zap.String("component", c.Name))
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	runs := make([]running, len(m.components))
+
+	for i, c := range m.components {
+		// Each component gets its own context so that shutdown can cancel
+		// them one at a time instead of all at once.
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		runs[i] = running{cancel: cancel, done: make(chan struct{})}
+
+		if c.Run == nil {
+			close(runs[i].done)
+			continue
+		}
+
+		g.Go(func() (err error) {
+			defer close(runs[i].done)
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%s panicked: %v", c.Name, r)
+				}
+			}()
+
+			err = c.Run(runCtx)
+			if runCtx.Err() != nil {
+				return nil
+			}
+			if err == nil {
+				err = errors.New("stopped unexpectedly")
+			}
+			return fmt.Errorf("%s: %w", c.Name, err)
+		})
+	}
+
+	<-gctx.Done()
+	m.log.Info("shutting down gracefully...")
+
+	if !m.stop(m.components, runs) {
+		// A component is stuck; waiting for the group would hang the process.
+		return errors.New("graceful shutdown timed out")
+	}
+
+	err := g.Wait()
+	if err != nil {
+		m.log.Error("service stopped because of a failed component", zap.Error(err))
+	}
+	return err
+}
+
+// stop reports whether every component finished within the shutdown timeout.
+func (m *Manager) stop(components []Component, runs []running) bool {
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	stopped := true
+
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+
+		if runs != nil {
+			runs[i].cancel()
+		}
+
+		if c.Stop != nil {
+			if err := c.Stop(ctx); err != nil {
+				m.log.Error("cannot stop component", zap.String("component", c.Name), zap.Error(err))
+			}
+		}
+
+		if runs != nil {
+			select {
+			case <-runs[i].done:
+			case <-ctx.Done():
+				m.log.Error("component did not stop in time", zap.String("component", c.Name))
+				stopped = false
+				continue
+			}
+		}
+
+		m.log.Info("component stopped", zap.String("component", c.Name))
+	}
+
+	return stopped
+}
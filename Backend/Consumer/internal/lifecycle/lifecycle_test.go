@@ -0,0 +1,152 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// component records its hooks; its Run finishes "draining" only after its
+// context is cancelled.
+func component(name string, rec *recorder) Component {
+	return Component{
+		Name: name,
+		Start: func(context.Context) error {
+			rec.add("start " + name)
+			return nil
+		},
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(5 * time.Millisecond)
+			rec.add("drained " + name)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			rec.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func TestManagerOrderedShutdown(t *testing.T) {
+	rec := &recorder{}
+	m := NewManager(time.Second, zap.NewNop())
+	m.Add(component("store", rec))
+	m.Add(component("consumer", rec))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	if err := m.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"start store", "start consumer",
+		"stop consumer", "drained consumer",
+		"stop store", "drained store",
+	}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestManagerFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		failing Component
+		wantErr string
+		want    []string
+	}{
+		{
+			name:    "run error stops the rest",
+			failing: Component{Name: "broken", Run: func(context.Context) error { return errors.New("boom") }},
+			wantErr: "broken: boom",
+			want:    []string{"start store", "stop store", "drained store"},
+		},
+		{
+			name:    "unexpected return stops the rest",
+			failing: Component{Name: "broken", Run: func(context.Context) error { return nil }},
+			wantErr: "broken: stopped unexpectedly",
+			want:    []string{"start store", "stop store", "drained store"},
+		},
+		{
+			name:    "panic stops the rest",
+			failing: Component{Name: "broken", Run: func(context.Context) error { panic("oops") }},
+			wantErr: "broken panicked: oops",
+			want:    []string{"start store", "stop store", "drained store"},
+		},
+		{
+			name:    "start error stops started components",
+			failing: Component{Name: "broken", Start: func(context.Context) error { return errors.New("boom") }},
+			wantErr: "cannot start broken: boom",
+			want:    []string{"start store", "stop store"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			m := NewManager(time.Second, zap.NewNop())
+			m.Add(component("store", rec))
+			m.Add(tt.failing)
+
+			err := m.Run(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+			if got := rec.get(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerShutdownTimeout(t *testing.T) {
+	m := NewManager(20*time.Millisecond, zap.NewNop())
+	m.Add(Component{
+		Name: "stuck",
+		Run: func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := m.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("shutdown did not respect the timeout")
+	}
+}
@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+const (
+	EventOrderAccepted = "order.accepted"
+	EventOrderReplaced = "order.replaced"
+)
+
+// Message is one outbox row. Key is the order_uid, so events of one order
+// keep their order on the downstream topic.
+type Message struct {
+	ID        int64
+	EventType string
+	Key       string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// OrderAccepted is published once an order has been stored.
+type OrderAccepted struct {
+	EventType  string       `json:"event_type"`
+	OrderUID   string       `json:"order_uid"`
+	CustomerID string       `json:"customer_id"`
+	AcceptedAt time.Time    `json:"accepted_at"`
+	Order      models.Order `json:"order"`
+}
+
+func NewOrderAccepted(order models.Order, acceptedAt time.Time) (Message, error) {
+
+	payload, err := json.Marshal(OrderAccepted{
+		EventType:  EventOrderAccepted,
+		OrderUID:   order.OrderUID,
+		CustomerID: order.CustomerID,
+		AcceptedAt: acceptedAt.UTC(),
+		Order:      order,
+	})
+	if err != nil {
+		return Message{}, fmt.Errorf("cannot marshal %s event err:%w", EventOrderAccepted, err)
+	}
+
+	return Message{
+		EventType: EventOrderAccepted,
+		Key:       order.OrderUID,
+		Payload:   payload,
+		CreatedAt: acceptedAt,
+	}, nil
+}
+
+// OrderReplaced is published when a stored order is overwritten, e.g. by a
+// replay that applies corrected orders.
+type OrderReplaced struct {
+	EventType  string       `json:"event_type"`
+	OrderUID   string       `json:"order_uid"`
+	CustomerID string       `json:"customer_id"`
+	ReplacedAt time.Time    `json:"replaced_at"`
+	Order      models.Order `json:"order"`
+}
+
+func NewOrderReplaced(order models.Order, replacedAt time.Time) (Message, error) {
+
+	payload, err := json.Marshal(OrderReplaced{
+		EventType:  EventOrderReplaced,
+		OrderUID:   order.OrderUID,
+		CustomerID: order.CustomerID,
+		ReplacedAt: replacedAt.UTC(),
+		Order:      order,
+	})
+	if err != nil {
+		return Message{}, fmt.Errorf("cannot marshal %s event err:%w", EventOrderReplaced, err)
+	}
+
+	return Message{
+		EventType: EventOrderReplaced,
+		Key:       order.OrderUID,
+		Payload:   payload,
+		CreatedAt: replacedAt,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	HeaderEventType = "event-type"
+	HeaderEventID   = "event-id"
+)
+
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// Publish writes the batch synchronously. Consumers deduplicate by the
+// event-id header, since a batch can be published again after a crash.
+func (p *KafkaPublisher) Publish(ctx context.Context, msgs []Message) error {
+
+	batch := make([]kafka.Message, 0, len(msgs))
+	for _, m := range msgs {
+		batch = append(batch, kafka.Message{
+			Key:   []byte(m.Key),
+			Value: m.Payload,
+			Time:  m.CreatedAt,
+			Headers: []kafka.Header{
+				{Key: HeaderEventType, Value: []byte(m.EventType)},
+				{Key: HeaderEventID, Value: []byte(strconv.FormatInt(m.ID, 10))},
+				{Key: models.HeaderContentType, Value: []byte(models.ContentTypeJSON)},
+			},
+		})
+	}
+
+	return p.writer.WriteMessages(ctx, batch...)
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,190 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval  = time.Second
+	defaultBatchSize = 100
+	defaultRetention = 24 * time.Hour
+	cleanupInterval  = 10 * time.Minute
+)
+
+// Store hands out pending rows. PublishPending must mark the rows delivered
+// only after publish succeeded, so every event is published at least once.
+type Store interface {
+	PublishPending(ctx context.Context, limit int, publish func(ctx context.Context, msgs []Message) error) (int, error)
+	DeleteDelivered(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, msgs []Message) error
+}
+
+// Keyring opens the delivery of an event's order, which is stored sealed.
+type Keyring interface {
+	DecryptOrder(order models.Order) (models.Order, error)
+}
+
+// Exporter shapes the order the way it may leave the service.
+type Exporter interface {
+	Export(order models.Order) models.Order
+}
+
+type Options struct {
+	Interval  time.Duration
+	BatchSize int
+	// Retention is how long delivered rows are kept before cleanup.
+	Retention time.Duration
+}
+
+// Relay moves events from the outbox table to Kafka.
+type Relay struct {
+	store   Store
+	pub     Publisher
+	keyring Keyring
+	policy  Exporter
+	opts    Options
+	log     *zap.Logger
+}
+
+func NewRelay(store Store, pub Publisher, keyring Keyring, policy Exporter, opts Options, logg *zap.Logger) *Relay {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = defaultRetention
+	}
+
+	return &Relay{
+		store:   store,
+		pub:     pub,
+		keyring: keyring,
+		policy:  policy,
+		opts:    opts,
+		log:     logg,
+	}
+}
+
+// Run publishes pending events until ctx is cancelled. A failed batch stays
+// pending and is retried on the next tick.
+func (r *Relay) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.drain(ctx)
+
+			if time.Since(lastCleanup) >= cleanupInterval {
+				r.cleanup(ctx)
+				lastCleanup = time.Now()
+			}
+		}
+	}
+}
+
+// drain publishes full batches back to back so a backlog does not wait for
+// one tick per batch.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.store.PublishPending(ctx, r.opts.BatchSize, r.publish)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.log.Warn("cannot relay outbox events", zap.Error(err))
+			}
+			return
+		}
+		if n > 0 {
+			r.log.Debug("outbox events published", zap.Int("count", n))
+		}
+		if n < r.opts.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, msgs []Message) error {
+
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, r.prepare(m))
+	}
+
+	return r.pub.Publish(ctx, out)
+}
+
+// prepare opens the sealed delivery of the event's order and applies the
+// export role of the privacy policy, like webhook payloads. An event whose
+// delivery cannot be opened, e.g. after its key left the keyring, is
+// published without it rather than blocking the outbox.
+func (r *Relay) prepare(m Message) Message {
+
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(m.Payload, &event); err != nil {
+		return m
+	}
+	raw, ok := event["order"]
+	if !ok {
+		return m
+	}
+
+	var order models.Order
+	if err := json.Unmarshal(raw, &order); err != nil {
+		return m
+	}
+
+	if r.keyring != nil {
+		opened, err := r.keyring.DecryptOrder(order)
+		if err != nil {
+			r.log.Warn("cannot decrypt outbox event, publishing it without delivery",
+				zap.Int64("event_id", m.ID), zap.Error(err))
+			opened = order
+			opened.Delivery = models.Delivery{}
+		}
+		order = opened
+	}
+	if r.policy != nil {
+		order = r.policy.Export(order)
+	}
+
+	encoded, err := json.Marshal(order)
+	if err != nil {
+		return m
+	}
+	event["order"] = encoded
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return m
+	}
+	m.Payload = payload
+
+	return m
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	n, err := r.store.DeleteDelivered(ctx, time.Now().Add(-r.opts.Retention))
+	if err != nil {
+		r.log.Warn("cannot clean up delivered outbox events", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		r.log.Info("delivered outbox events removed", zap.Int64("count", n))
+	}
+}
@@ -0,0 +1,215 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/encryption"
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+type MockStore struct {
+	PublishPendingFunc  func(ctx context.Context, limit int, publish func(ctx context.Context, msgs []Message) error) (int, error)
+	DeleteDeliveredFunc func(ctx context.Context, before time.Time) (int64, error)
+}
+
+func (ms MockStore) PublishPending(ctx context.Context, limit int, publish func(ctx context.Context, msgs []Message) error) (int, error) {
+	return ms.PublishPendingFunc(ctx, limit, publish)
+}
+
+func (ms MockStore) DeleteDelivered(ctx context.Context, before time.Time) (int64, error) {
+	return ms.DeleteDeliveredFunc(ctx, before)
+}
+
+type MockPublisher struct {
+	PublishFunc func(ctx context.Context, msgs []Message) error
+}
+
+func (mp MockPublisher) Publish(ctx context.Context, msgs []Message) error {
+	return mp.PublishFunc(ctx, msgs)
+}
+
+func TestRelayDrain(t *testing.T) {
+	tests := []struct {
+		name      string
+		batches   []int
+		failAt    int
+		wantCalls int
+	}{
+		{
+			name:      "drains full batches",
+			batches:   []int{2, 2, 1},
+			failAt:    -1,
+			wantCalls: 3,
+		},
+		{
+			name:      "stops on error",
+			batches:   []int{2, 2, 1},
+			failAt:    1,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			store := MockStore{
+				PublishPendingFunc: func(ctx context.Context, limit int, publish func(ctx context.Context, msgs []Message) error) (int, error) {
+					defer func() { calls++ }()
+					if calls == tt.failAt {
+						return 0, errors.New("broker unavailable")
+					}
+					msgs := make([]Message, tt.batches[calls])
+					if err := publish(ctx, msgs); err != nil {
+						return 0, err
+					}
+					return len(msgs), nil
+				},
+			}
+			pub := MockPublisher{PublishFunc: func(ctx context.Context, msgs []Message) error { return nil }}
+
+			r := NewRelay(store, pub, nil, nil, Options{BatchSize: 2}, zap.NewNop())
+			r.drain(context.Background())
+
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d batches, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestNewOrderAccepted(t *testing.T) {
+	order := models.Order{OrderUID: "b563feb7b2b84b6test", CustomerID: "test"}
+
+	msg, err := NewOrderAccepted(order, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if msg.EventType != EventOrderAccepted || msg.Key != order.OrderUID {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestRelayOpensDelivery(t *testing.T) {
+
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("cannot create keyring: %v", err)
+	}
+	other, err := encryption.NewKeyring("k2", map[string][]byte{"k2": bytes.Repeat([]byte{3}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("cannot create keyring: %v", err)
+	}
+
+	delivery := models.Delivery{Name: "Test Testov", Phone: "+9720000000", City: "Kiryat Mozkin", Email: "test@gmail.com"}
+	order := models.Order{OrderUID: "b563feb7b2b84b6test", CustomerID: "test", Delivery: delivery}
+
+	tests := []struct {
+		name    string
+		keyring Keyring
+		want    models.Delivery
+	}{
+		{
+			name:    "opened",
+			keyring: keyring,
+			want:    delivery,
+		},
+		{
+			name:    "key missing",
+			keyring: other,
+			want:    models.Delivery{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := keyring.EncryptOrder(order)
+			if err != nil {
+				t.Fatalf("cannot seal order: %v", err)
+			}
+			msg, err := NewOrderAccepted(sealed, time.Now())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			store := MockStore{
+				PublishPendingFunc: func(ctx context.Context, limit int, publish func(ctx context.Context, msgs []Message) error) (int, error) {
+					return 1, publish(ctx, []Message{msg})
+				},
+			}
+			var published []Message
+			pub := MockPublisher{PublishFunc: func(ctx context.Context, msgs []Message) error {
+				published = msgs
+				return nil
+			}}
+
+			NewRelay(store, pub, tt.keyring, nil, Options{BatchSize: 2}, zap.NewNop()).drain(context.Background())
+
+			if len(published) != 1 {
+				t.Fatalf("expected one message, got %d", len(published))
+			}
+			var event OrderAccepted
+			if err := json.Unmarshal(published[0].Payload, &event); err != nil {
+				t.Fatalf("cannot decode event: %v", err)
+			}
+			if event.Order.Delivery != tt.want || event.OrderUID != order.OrderUID || event.EventType != EventOrderAccepted {
+				t.Errorf("unexpected event %+v", event)
+			}
+		})
+	}
+}
+
+type MockExporter struct {
+	ExportFunc func(order models.Order) models.Order
+}
+
+func (me MockExporter) Export(order models.Order) models.Order {
+	return me.ExportFunc(order)
+}
+
+func TestRelayExportsOrder(t *testing.T) {
+
+	order := models.Order{OrderUID: "b563feb7b2b84b6test", CustomerID: "test",
+		Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000", City: "Kiryat Mozkin"}}
+	msg, err := NewOrderAccepted(order, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store := MockStore{
+		PublishPendingFunc: func(ctx context.Context, limit int, publish func(ctx context.Context, msgs []Message) error) (int, error) {
+			return 1, publish(ctx, []Message{msg})
+		},
+	}
+	var published []Message
+	pub := MockPublisher{PublishFunc: func(ctx context.Context, msgs []Message) error {
+		published = msgs
+		return nil
+	}}
+	policy := MockExporter{ExportFunc: func(order models.Order) models.Order {
+		order.Delivery = models.Delivery{City: order.Delivery.City}
+		return order
+	}}
+
+	NewRelay(store, pub, nil, policy, Options{BatchSize: 2}, zap.NewNop()).drain(context.Background())
+
+	if len(published) != 1 {
+		t.Fatalf("expected one message, got %d", len(published))
+	}
+	var event OrderAccepted
+	if err := json.Unmarshal(published[0].Payload, &event); err != nil {
+		t.Fatalf("cannot decode event: %v", err)
+	}
+	if event.Order.Delivery != (models.Delivery{City: "Kiryat Mozkin"}) {
+		t.Errorf("expected the exported delivery, got %+v", event.Order.Delivery)
+	}
+	if published[0].Key != order.OrderUID || event.CustomerID != order.CustomerID {
+		t.Errorf("unexpected message %+v", published[0])
+	}
+}
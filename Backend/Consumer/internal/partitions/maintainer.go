@@ -0,0 +1,101 @@
+package partitions
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval    = 24 * time.Hour
+	defaultMonthsAhead = 3
+)
+
+var (
+	createdTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orderservice_partitions_created_total",
+		Help: "Monthly partitions created by the maintenance job.",
+	})
+	runsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orderservice_partitions_runs_total",
+		Help: "Partition maintenance runs by result.",
+	}, []string{"result"})
+)
+
+// Store creates the monthly partitions for months months starting with the
+// month of from and returns how many were new.
+type Store interface {
+	CreatePartitions(ctx context.Context, from time.Time, months int) (int, error)
+}
+
+type Options struct {
+	Interval time.Duration
+	// MonthsAhead is how many months after the current one must have
+	// partitions.
+	MonthsAhead int
+}
+
+// Maintainer keeps partitions for the current and the next months in
+// place, so new orders never land in the default partitions.
+type Maintainer struct {
+	store Store
+	opts  Options
+	log   *zap.Logger
+	now   func() time.Time
+}
+
+func NewMaintainer(store Store, opts Options, logg *zap.Logger) *Maintainer {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.MonthsAhead <= 0 {
+		opts.MonthsAhead = defaultMonthsAhead
+	}
+
+	return &Maintainer{
+		store: store,
+		opts:  opts,
+		log:   logg,
+		now:   time.Now,
+	}
+}
+
+// Run creates partitions once right away and then on every tick until ctx
+// is cancelled.
+func (m *Maintainer) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+
+	m.ensure(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.ensure(ctx)
+		}
+	}
+}
+
+func (m *Maintainer) ensure(ctx context.Context) {
+
+	n, err := m.store.CreatePartitions(ctx, m.now(), m.opts.MonthsAhead+1)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.log.Warn("cannot create partitions", zap.Error(err))
+			runsTotal.WithLabelValues("error").Inc()
+		}
+		return
+	}
+
+	runsTotal.WithLabelValues("success").Inc()
+	createdTotal.Add(float64(n))
+	if n > 0 {
+		m.log.Info("partitions created", zap.Int("count", n))
+	}
+}
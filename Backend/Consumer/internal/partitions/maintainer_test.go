@@ -0,0 +1,62 @@
+package partitions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+type MockStore struct {
+	CreatePartitionsFunc func(ctx context.Context, from time.Time, months int) (int, error)
+}
+
+func (ms MockStore) CreatePartitions(ctx context.Context, from time.Time, months int) (int, error) {
+	return ms.CreatePartitionsFunc(ctx, from, months)
+}
+
+func TestMaintainerEnsure(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		created     int
+		err         error
+		wantCreated float64
+		wantResult  string
+	}{
+		{name: "creates missing months", created: 4, wantCreated: 4, wantResult: "success"},
+		{name: "nothing to create", wantResult: "success"},
+		{name: "store fails", err: errors.New("permission denied"), wantResult: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := MockStore{
+				CreatePartitionsFunc: func(ctx context.Context, from time.Time, months int) (int, error) {
+					if !from.Equal(now) || months != 3 {
+						t.Errorf("unexpected range %v + %d months", from, months)
+					}
+					return tt.created, tt.err
+				},
+			}
+
+			created := testutil.ToFloat64(createdTotal)
+			runs := testutil.ToFloat64(runsTotal.WithLabelValues(tt.wantResult))
+
+			m := NewMaintainer(store, Options{MonthsAhead: 2}, zap.NewNop())
+			m.now = func() time.Time { return now }
+			m.ensure(context.Background())
+
+			if got := testutil.ToFloat64(createdTotal) - created; got != tt.wantCreated {
+				t.Errorf("expected %v partitions counted, got %v", tt.wantCreated, got)
+			}
+			if got := testutil.ToFloat64(runsTotal.WithLabelValues(tt.wantResult)); got != runs+1 {
+				t.Errorf("expected one %s run recorded, got %v", tt.wantResult, got-runs)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Role decides how much of the delivery data a caller may see.
+type Role string
+
+const (
+	RoleSupport   Role = "support"
+	RoleAnalytics Role = "analytics"
+	RoleAnonymous Role = "anonymous"
+)
+
+type Action string
+
+const (
+	Show Action = "show"
+	Mask Action = "mask"
+	Hide Action = "hide"
+)
+
+// Fields lists the delivery fields a rule may name. The "*" key of a rule
+// sets the action for every field the rule does not name.
+var Fields = []string{"name", "phone", "zip", "city", "address", "region", "email"}
+
+const anyField = "*"
+
+// Rule maps a delivery field to the action applied to it.
+type Rule map[string]Action
+
+type Policy struct {
+	rules       map[Role]Rule
+	defaultRole Role
+	exportRole  Role
+	logRole     Role
+}
+
+type Options struct {
+	Rules map[Role]Rule
+	// DefaultRole is given to callers whose request carries no role.
+	DefaultRole Role
+	// ExportRole decides what leaves the service without a caller, such as
+	// webhook payloads.
+	ExportRole Role
+	LogRole    Role
+}
+
+// DefaultRules are used when the config defines no roles: support agents
+// see everything, analytics clients get masked contacts and anonymous
+// callers get no delivery data at all.
+func DefaultRules() map[Role]Rule {
+	return map[Role]Rule{
+		RoleSupport: {anyField: Show},
+		RoleAnalytics: {
+			anyField:  Show,
+			"name":    Mask,
+			"phone":   Mask,
+			"email":   Mask,
+			"address": Mask,
+		},
+		RoleAnonymous: {anyField: Hide},
+	}
+}
+
+func NewPolicy(opts Options) (*Policy, error) {
+
+	if len(opts.Rules) == 0 {
+		opts.Rules = DefaultRules()
+	}
+	if opts.DefaultRole == "" {
+		opts.DefaultRole = RoleAnonymous
+	}
+	if opts.ExportRole == "" {
+		opts.ExportRole = RoleAnalytics
+	}
+	if opts.LogRole == "" {
+		opts.LogRole = RoleAnalytics
+	}
+
+	for role, rule := range opts.Rules {
+		for field, action := range rule {
+			if field != anyField && !knownField(field) {
+				return nil, fmt.Errorf("role %q: unknown delivery field %q", role, field)
+			}
+			switch action {
+			case Show, Mask, Hide:
+			default:
+				return nil, fmt.Errorf("role %q: unknown action %q for %s, expected show, mask or hide", role, action, field)
+			}
+		}
+	}
+
+	for _, role := range []Role{opts.DefaultRole, opts.ExportRole, opts.LogRole} {
+		if _, ok := opts.Rules[role]; !ok {
+			return nil, fmt.Errorf("role %q has no rule", role)
+		}
+	}
+
+	return &Policy{
+		rules:       opts.Rules,
+		defaultRole: opts.DefaultRole,
+		exportRole:  opts.ExportRole,
+		logRole:     opts.LogRole,
+	}, nil
+}
+
+// ParseRules converts the roles section of the config.
+func ParseRules(raw map[string]map[string]string) map[Role]Rule {
+
+	rules := make(map[Role]Rule, len(raw))
+	for role, fields := range raw {
+		rule := make(Rule, len(fields))
+		for field, action := range fields {
+			rule[strings.ToLower(field)] = Action(strings.ToLower(action))
+		}
+		rules[Role(strings.ToLower(role))] = rule
+	}
+	return rules
+}
+
+type roleKey struct{}
+
+func WithRole(ctx context.Context, role Role) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// Role returns the role of the caller, or the default role if the request
+// was not assigned one.
+func (p *Policy) Role(ctx context.Context) Role {
+	if role, ok := ctx.Value(roleKey{}).(Role); ok {
+		return role
+	}
+	return p.defaultRole
+}
+
+// Apply returns a copy of the order with the delivery data the role may
+// see. Roles without a rule see nothing.
+func (p *Policy) Apply(role Role, order models.Order) models.Order {
+	order.Delivery = p.delivery(role, order.Delivery)
+	return order
+}
+
+func (p *Policy) ApplyAll(role Role, orders []models.Order) []models.Order {
+
+	if orders == nil {
+		return nil
+	}
+
+	res := make([]models.Order, len(orders))
+	for i := range orders {
+		res[i] = p.Apply(role, orders[i])
+	}
+	return res
+}
+
+// Export returns the order as it may be sent to other systems.
+func (p *Policy) Export(order models.Order) models.Order {
+	return p.Apply(p.exportRole, order)
+}
+
+// LogDelivery is a zap field with the delivery data as seen by the log
+// role, so log lines follow the same policy as responses.
+func (p *Policy) LogDelivery(d models.Delivery) zap.Field {
+	return zap.Object("delivery", logDelivery(p.delivery(p.logRole, d)))
+}
+
+func (p *Policy) delivery(role Role, d models.Delivery) models.Delivery {
+
+	rule := p.rules[role]
+	action := func(field string) Action {
+		if a, ok := rule[field]; ok {
+			return a
+		}
+		if a, ok := rule[anyField]; ok {
+			return a
+		}
+		return Hide
+	}
+
+	d.Name = apply(action("name"), d.Name, maskText)
+	d.Phone = apply(action("phone"), d.Phone, maskPhone)
+	d.Zip = apply(action("zip"), d.Zip, maskText)
+	d.City = apply(action("city"), d.City, maskText)
+	d.Address = apply(action("address"), d.Address, maskText)
+	d.Region = apply(action("region"), d.Region, maskText)
+	d.Email = apply(action("email"), d.Email, maskEmail)
+
+	// The storage id leads to the person's delivery row, so a role that sees
+	// none of the data does not get it either.
+	hidden := true
+	for _, f := range Fields {
+		if action(f) != Hide {
+			hidden = false
+		}
+	}
+	if hidden {
+		d.DeliveryID = ""
+	}
+
+	return d
+}
+
+func apply(action Action, value string, mask func(string) string) string {
+	switch action {
+	case Show:
+		return value
+	case Mask:
+		return mask(value)
+	default:
+		return ""
+	}
+}
+
+// maskText keeps the first letter: "Ploshad Mira 15" -> "P***".
+func maskText(s string) string {
+	if s == "" {
+		return ""
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return string(r) + "***"
+}
+
+// maskPhone keeps the leading plus and the last two digits:
+// "+9720000000" -> "+********00".
+func maskPhone(s string) string {
+
+	if s == "" {
+		return ""
+	}
+
+	runes := []rune(s)
+	for i := range runes {
+		if i == 0 && runes[i] == '+' {
+			continue
+		}
+		if i >= len(runes)-2 {
+			break
+		}
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+// maskEmail keeps the first letter and the domain: "test@gmail.com" ->
+// "t***@gmail.com".
+func maskEmail(s string) string {
+
+	at := strings.LastIndex(s, "@")
+	if at <= 0 {
+		return maskText(s)
+	}
+	return maskText(s[:at]) + s[at:]
+}
+
+func knownField(field string) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+type logDelivery models.Delivery
+
+func (d logDelivery) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range []struct{ key, value string }{
+		{"name", d.Name}, {"phone", d.Phone}, {"zip", d.Zip}, {"city", d.City},
+		{"address", d.Address}, {"region", d.Region}, {"email", d.Email},
+	} {
+		if f.value != "" {
+			enc.AddString(f.key, f.value)
+		}
+	}
+	return nil
+}
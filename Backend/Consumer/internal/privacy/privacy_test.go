@@ -0,0 +1,143 @@
+package privacy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+func TestMasking(t *testing.T) {
+	tests := []struct {
+		name  string
+		mask  func(string) string
+		value string
+		want  string
+	}{
+		{name: "phone", mask: maskPhone, value: "+9720000000", want: "+********00"},
+		{name: "phone without plus", mask: maskPhone, value: "89001234567", want: "*********67"},
+		{name: "short phone", mask: maskPhone, value: "12", want: "12"},
+		{name: "email", mask: maskEmail, value: "test@gmail.com", want: "t***@gmail.com"},
+		{name: "email without local part", mask: maskEmail, value: "@gmail.com", want: "@***"},
+		{name: "text", mask: maskText, value: "Ploshad Mira 15", want: "P***"},
+		{name: "cyrillic text", mask: maskText, value: "Площадь Мира", want: "П***"},
+		{name: "empty", mask: maskText, value: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mask(tt.value); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       map[string]map[string]string
+		defaultRole Role
+		wantErr     bool
+	}{
+		{name: "defaults"},
+		{
+			name:        "custom",
+			rules:       map[string]map[string]string{"Partner": {"*": "hide", "City": "show"}},
+			defaultRole: "partner",
+		},
+		{
+			name:    "unknown field",
+			rules:   map[string]map[string]string{"anonymous": {"iban": "hide"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			rules:   map[string]map[string]string{"anonymous": {"*": "blur"}},
+			wantErr: true,
+		},
+		{
+			name:        "default role without rule",
+			defaultRole: "partner",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPolicy(Options{Rules: ParseRules(tt.rules), DefaultRole: tt.defaultRole, ExportRole: tt.defaultRole, LogRole: tt.defaultRole})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+
+	policy, err := NewPolicy(Options{
+		Rules: ParseRules(map[string]map[string]string{
+			"support":   {"*": "show"},
+			"partner":   {"city": "show", "region": "mask"},
+			"anonymous": {"*": "hide"},
+		}),
+		DefaultRole: RoleAnonymous,
+		ExportRole:  "partner",
+		LogRole:     "partner",
+	})
+	if err != nil {
+		t.Fatalf("cannot init policy: %v", err)
+	}
+
+	order := models.Order{OrderUID: "1", Delivery: models.Delivery{
+		DeliveryID: "7",
+		Name:       "Test Testov",
+		Phone:      "+9720000000",
+		City:       "Kiryat Mozkin",
+		Region:     "Kraiot",
+		Email:      "test@gmail.com",
+	}}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want models.Delivery
+	}{
+		{
+			name: "support",
+			ctx:  WithRole(context.Background(), RoleSupport),
+			want: order.Delivery,
+		},
+		{
+			name: "unnamed fields are hidden",
+			ctx:  WithRole(context.Background(), "partner"),
+			want: models.Delivery{DeliveryID: "7", City: "Kiryat Mozkin", Region: "K***"},
+		},
+		{
+			name: "unknown role",
+			ctx:  WithRole(context.Background(), "intruder"),
+			want: models.Delivery{},
+		},
+		{
+			name: "default role",
+			ctx:  context.Background(),
+			want: models.Delivery{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Apply(policy.Role(tt.ctx), order)
+			if got.Delivery != tt.want {
+				t.Errorf("got %+v, want %+v", got.Delivery, tt.want)
+			}
+			if got.OrderUID != order.OrderUID {
+				t.Error("fields outside delivery must not change")
+			}
+		})
+	}
+	exported := policy.Export(order).Delivery
+	if want := (models.Delivery{DeliveryID: "7", City: "Kiryat Mozkin", Region: "K***"}); exported != want {
+		t.Errorf("export: got %+v, want %+v", exported, want)
+	}
+}
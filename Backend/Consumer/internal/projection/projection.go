@@ -0,0 +1,174 @@
+package projection
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+// Heavy lists the sub-objects that can be requested with include.
+var Heavy = []string{"delivery", "payment", "items"}
+
+// schema mirrors the JSON shape of models.Order; leaves are nil.
+type schema map[string]schema
+
+var orderSchema = schemaOf(reflect.TypeOf(models.Order{}))
+
+// Projection selects parts of an order. A nil tree keeps everything.
+type Projection struct {
+	tree schema
+}
+
+// Parse builds a projection from the fields and include query parameters.
+// Both are comma separated; fields are dotted JSON paths such as
+// payment.amount or items.name. With neither parameter the whole order is
+// returned. include=items keeps the light top-level fields plus the items.
+func Parse(fields, include string) (*Projection, error) {
+
+	if fields == "" && include == "" {
+		return &Projection{}, nil
+	}
+
+	tree := schema{}
+
+	for _, path := range split(fields) {
+		if err := tree.add(strings.Split(path, "."), orderSchema); err != nil {
+			return nil, fmt.Errorf("unknown field %q", path)
+		}
+	}
+
+	if include != "" {
+		for name, sub := range orderSchema {
+			if sub == nil {
+				tree[name] = nil
+			}
+		}
+		for _, name := range split(include) {
+			if !isHeavy(name) {
+				return nil, fmt.Errorf("cannot include %q, expected one of %s", name, strings.Join(Heavy, ", "))
+			}
+			tree[name] = nil
+		}
+	}
+
+	return &Projection{tree: tree}, nil
+}
+
+// Apply returns v as a JSON-compatible value with only the selected fields.
+func (p *Projection) Apply(v any) (any, error) {
+
+	if p.tree == nil {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return prune(doc, p.tree), nil
+}
+
+// add marks path as selected. Selecting a whole object (nil node) wins over
+// selecting some of its fields.
+func (s schema) add(path []string, types schema) error {
+
+	sub, ok := types[path[0]]
+	if !ok {
+		return fmt.Errorf("unknown field")
+	}
+
+	if len(path) == 1 {
+		s[path[0]] = nil
+		return nil
+	}
+	if sub == nil {
+		return fmt.Errorf("%s has no fields", path[0])
+	}
+
+	child, selected := s[path[0]]
+	if selected && child == nil {
+		return nil
+	}
+	if child == nil {
+		child = schema{}
+		s[path[0]] = child
+	}
+
+	return child.add(path[1:], sub)
+}
+
+func prune(v any, tree schema) any {
+
+	if tree == nil {
+		return v
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(tree))
+		for name, sub := range tree {
+			if field, ok := val[name]; ok {
+				out[name] = prune(field, sub)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, elem := range val {
+			out[i] = prune(elem, tree)
+		}
+		return out
+	}
+
+	return v
+}
+
+func schemaOf(t reflect.Type) schema {
+
+	for t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	s := schema{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		s[name] = schemaOf(f.Type)
+	}
+
+	return s
+}
+
+func split(list string) []string {
+	var out []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func isHeavy(name string) bool {
+	for _, h := range Heavy {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
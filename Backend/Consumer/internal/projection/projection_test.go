@@ -0,0 +1,112 @@
+package projection
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+func TestProjection(t *testing.T) {
+	order := models.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Delivery:    models.Delivery{Name: "Test Testov", Email: "test@gmail.com"},
+		Payment:     models.Payment{Amount: 1817, Currency: "USD"},
+		Items: []models.Item{
+			{ChrtID: 9934930, Name: "Mascaras", Price: 453},
+			{ChrtID: 9934931, Name: "Lipstick", Price: 200},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		fields  string
+		include string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "nested fields",
+			fields: "order_uid,payment.amount,items.name",
+			want:   `{"items":[{"name":"Mascaras"},{"name":"Lipstick"}],"order_uid":"b563feb7b2b84b6test","payment":{"amount":1817}}`,
+		},
+		{
+			name:   "whole object wins",
+			fields: "payment.amount,payment",
+			want:   `{"payment":{"amount":1817,"bank":"","currency":"USD","custom_fee":0,"delivery_cost":0,"goods_total":0,"payment_dt":0,"provider":"","request_id":"","transaction":""}}`,
+		},
+		{
+			name:    "include with fields",
+			fields:  "delivery.name",
+			include: "items",
+		},
+		{
+			name:    "unknown field",
+			fields:  "payment.iban",
+			wantErr: true,
+		},
+		{
+			name:    "field of a leaf",
+			fields:  "order_uid.x",
+			wantErr: true,
+		},
+		{
+			name:    "include light field",
+			include: "order_uid",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(tt.fields, tt.include)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			shaped, err := p.Apply(order)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, _ := json.Marshal(shaped)
+
+			if tt.include == "" {
+				if string(got) != tt.want {
+					t.Errorf("got %s, want %s", got, tt.want)
+				}
+				return
+			}
+
+			doc := shaped.(map[string]any)
+			if _, ok := doc["items"]; !ok {
+				t.Error("expected included items")
+			}
+			if _, ok := doc["payment"]; ok {
+				t.Error("payment was not requested")
+			}
+			if doc["track_number"] != "WBILMTESTTRACK" {
+				t.Error("expected light top-level fields with include")
+			}
+			if d := doc["delivery"].(map[string]any); len(d) != 1 || d["name"] != "Test Testov" {
+				t.Errorf("unexpected delivery %v", d)
+			}
+		})
+	}
+}
+
+func TestProjectionDefault(t *testing.T) {
+	p, err := Parse("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	order := models.Order{OrderUID: "1"}
+	shaped, _ := p.Apply(order)
+	if _, ok := shaped.(models.Order); !ok {
+		t.Errorf("expected the order unchanged, got %T", shaped)
+	}
+}
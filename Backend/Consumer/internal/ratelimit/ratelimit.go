@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests at once, refilled at Rate
+// requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Validate() error {
+	if l.Rate <= 0 || l.Burst < 1 {
+		return errors.New("rate must be positive and burst at least 1")
+	}
+	return nil
+}
+
+// Result describes the bucket after a request was counted.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long a rejected client has to wait for a token.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// NewResult derives the result from the tokens left in a bucket.
+func NewResult(l Limit, tokens float64, allowed bool) Result {
+
+	res := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(l.Burst) - tokens) / l.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / l.Rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps the buckets. Take counts one request against key.
+type Store interface {
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+type Limiter struct {
+	store  Store
+	def    Limit
+	routes map[string]Limit
+}
+
+// NewLimiter uses def for every route that has no limit of its own.
+func NewLimiter(store Store, def Limit, routes map[string]Limit) *Limiter {
+
+	lower := make(map[string]Limit, len(routes))
+	for name, l := range routes {
+		lower[strings.ToLower(name)] = l
+	}
+
+	return &Limiter{store: store, def: def, routes: lower}
+}
+
+func (l *Limiter) Limit(route string) Limit {
+	if lim, ok := l.routes[strings.ToLower(route)]; ok {
+		return lim
+	}
+	return l.def
+}
+
+// Allow counts a request of client on route. Every route has its own
+// bucket, so a burst of batch calls does not block single lookups.
+func (l *Limiter) Allow(ctx context.Context, route, client string) (Result, Limit, error) {
+	lim := l.Limit(route)
+	res, err := l.store.Take(ctx, strings.ToLower(route)+":"+client, lim)
+	return res, lim, err
+}
+
+// MemoryStore keeps buckets in the process; every replica limits on its
+// own.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	full   time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (ms *MemoryStore) Take(ctx context.Context, key string, l Limit) (Result, error) {
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := ms.now()
+	ms.sweep(now)
+
+	b, ok := ms.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), at: now}
+		ms.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.at).Seconds()*l.Rate)
+	b.at = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	res := NewResult(l, b.tokens, allowed)
+	b.full = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// sweep drops buckets that refilled completely; they are the same as no
+// bucket at all.
+func (ms *MemoryStore) sweep(now time.Time) {
+
+	if now.Sub(ms.lastSweep) < sweepInterval {
+		return
+	}
+	ms.lastSweep = now
+
+	for key, b := range ms.buckets {
+		if !now.Before(b.full) {
+			delete(ms.buckets, key)
+		}
+	}
+}
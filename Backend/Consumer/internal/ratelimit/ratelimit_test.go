@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+	ms := NewMemoryStore()
+	ms.now = func() time.Time { return now }
+
+	lim := Limit{Rate: 2, Burst: 3}
+	take := func() Result {
+		t.Helper()
+		res, err := ms.Take(context.Background(), "ip:1", lim)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return res
+	}
+
+	for i := 2; i >= 0; i-- {
+		if res := take(); !res.Allowed || res.Remaining != i {
+			t.Fatalf("burst request: unexpected result %+v", res)
+		}
+	}
+
+	res := take()
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond || res.Reset != 1500*time.Millisecond {
+		t.Fatalf("expected rejection, got %+v", res)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if res := take(); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected a refilled token, got %+v", res)
+	}
+
+	now = now.Add(time.Hour)
+	if res := take(); !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("bucket must not grow beyond the burst, got %+v", res)
+	}
+
+	if len(ms.buckets) != 1 {
+		t.Fatalf("expected one bucket, got %d", len(ms.buckets))
+	}
+	now = now.Add(time.Hour)
+	if _, err := ms.Take(context.Background(), "ip:2", lim); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := ms.buckets["ip:1"]; ok {
+		t.Error("full bucket must be swept")
+	}
+}
+
+func TestLimiterRoutes(t *testing.T) {
+
+	l := NewLimiter(NewMemoryStore(), Limit{Rate: 1, Burst: 5}, map[string]Limit{"Batch": {Rate: 1, Burst: 1}})
+
+	if _, lim, _ := l.Allow(context.Background(), "order", "ip:1"); lim.Burst != 5 {
+		t.Errorf("expected the default limit, got %+v", lim)
+	}
+
+	if res, _, _ := l.Allow(context.Background(), "batch", "ip:1"); !res.Allowed {
+		t.Fatal("first batch call must pass")
+	}
+	if res, _, _ := l.Allow(context.Background(), "batch", "ip:1"); res.Allowed {
+		t.Fatal("second batch call must be limited")
+	}
+	if res, _, _ := l.Allow(context.Background(), "order", "ip:1"); !res.Allowed {
+		t.Error("limited batch calls must not block other routes")
+	}
+	if res, _, _ := l.Allow(context.Background(), "batch", "ip:2"); !res.Allowed {
+		t.Error("clients must have separate buckets")
+	}
+}
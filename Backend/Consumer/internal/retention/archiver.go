@@ -0,0 +1,154 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAfter     = 365 * 24 * time.Hour
+	defaultInterval  = time.Hour
+	defaultBatchSize = 500
+)
+
+var (
+	archivedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orderservice_retention_orders_archived_total",
+		Help: "Orders moved to the archive.",
+	})
+	runsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orderservice_retention_runs_total",
+		Help: "Retention runs by result.",
+	}, []string{"result"})
+	runDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "orderservice_retention_run_duration_seconds",
+		Help:    "Duration of a retention run.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	})
+	lastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "orderservice_retention_last_success_timestamp_seconds",
+		Help: "Unix time of the last retention run that finished without errors.",
+	})
+)
+
+// Store moves up to limit orders created before the cutoff to the archive
+// and returns their IDs.
+type Store interface {
+	ArchiveOrders(ctx context.Context, before time.Time, limit int) ([]string, error)
+}
+
+type Cache interface {
+	DeleteOrders(ctx context.Context, orderIDs []string) error
+}
+
+type Options struct {
+	// After is the age at which an order is archived.
+	After     time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Archiver moves orders past the retention period out of the live tables
+// and evicts them from the cache. Archived orders stay readable through the
+// store.
+type Archiver struct {
+	store Store
+	cache Cache
+	opts  Options
+	log   *zap.Logger
+	now   func() time.Time
+}
+
+func NewArchiver(store Store, cache Cache, opts Options, logg *zap.Logger) *Archiver {
+	if opts.After <= 0 {
+		opts.After = defaultAfter
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	return &Archiver{
+		store: store,
+		cache: cache,
+		opts:  opts,
+		log:   logg,
+		now:   time.Now,
+	}
+}
+
+// Run archives once right away and then on every tick until ctx is
+// cancelled.
+func (a *Archiver) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+
+	a.drain(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			a.drain(ctx)
+		}
+	}
+}
+
+func (a *Archiver) drain(ctx context.Context) {
+
+	start := a.now()
+	before := start.Add(-a.opts.After)
+	total := 0
+	failed := false
+
+	for ctx.Err() == nil {
+		IDs, err := a.store.ArchiveOrders(ctx, before, a.opts.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				a.log.Warn("cannot archive orders", zap.Error(err))
+				failed = true
+			}
+			break
+		}
+		total += len(IDs)
+		archivedTotal.Add(float64(len(IDs)))
+
+		// The orders are already archived; a stale cache entry only means
+		// the order is served from Redis until it expires.
+		if len(IDs) > 0 {
+			if err := a.cache.DeleteOrders(ctx, IDs); err != nil {
+				a.log.Warn("cannot evict archived orders from cache", zap.Error(err))
+				failed = true
+			}
+		}
+
+		if len(IDs) < a.opts.BatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		a.log.Info("orders archived", zap.Int("count", total), zap.Time("before", before))
+	}
+
+	// A run cut short by shutdown is neither a success nor a failure.
+	if ctx.Err() != nil {
+		return
+	}
+
+	runDuration.Observe(a.now().Sub(start).Seconds())
+	if failed {
+		runsTotal.WithLabelValues("error").Inc()
+	} else {
+		runsTotal.WithLabelValues("success").Inc()
+		lastSuccess.Set(float64(a.now().Unix()))
+	}
+}
@@ -0,0 +1,112 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+type MockStore struct {
+	ArchiveOrdersFunc func(ctx context.Context, before time.Time, limit int) ([]string, error)
+}
+
+func (ms MockStore) ArchiveOrders(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	return ms.ArchiveOrdersFunc(ctx, before, limit)
+}
+
+type MockCache struct {
+	DeleteOrdersFunc func(ctx context.Context, orderIDs []string) error
+}
+
+func (mc MockCache) DeleteOrders(ctx context.Context, orderIDs []string) error {
+	return mc.DeleteOrdersFunc(ctx, orderIDs)
+}
+
+func TestArchiverDrain(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		batches     [][]string
+		storeErr    error
+		cacheErr    error
+		wantCalls   int
+		wantEvicted int
+		wantResult  string
+	}{
+		{
+			name:        "drains full batches",
+			batches:     [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+			wantCalls:   3,
+			wantEvicted: 5,
+			wantResult:  "success",
+		},
+		{
+			name:       "nothing to archive",
+			batches:    [][]string{nil},
+			wantCalls:  1,
+			wantResult: "success",
+		},
+		{
+			name:       "stops on store error",
+			batches:    [][]string{{"a", "b"}},
+			storeErr:   errors.New("deadlock"),
+			wantCalls:  2,
+			wantResult: "error",
+			// The first batch was archived before the error.
+			wantEvicted: 2,
+		},
+		{
+			name:        "cache error does not stop archiving",
+			batches:     [][]string{{"a", "b"}, {"c"}},
+			cacheErr:    errors.New("connection refused"),
+			wantCalls:   2,
+			wantEvicted: 3,
+			wantResult:  "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, evicted := 0, 0
+			store := MockStore{
+				ArchiveOrdersFunc: func(ctx context.Context, before time.Time, limit int) ([]string, error) {
+					defer func() { calls++ }()
+					if want := now.Add(-30 * 24 * time.Hour); !before.Equal(want) {
+						t.Errorf("expected cutoff %v, got %v", want, before)
+					}
+					if calls >= len(tt.batches) {
+						return nil, tt.storeErr
+					}
+					return tt.batches[calls], nil
+				},
+			}
+			cache := MockCache{
+				DeleteOrdersFunc: func(ctx context.Context, orderIDs []string) error {
+					evicted += len(orderIDs)
+					return tt.cacheErr
+				},
+			}
+
+			runs := testutil.ToFloat64(runsTotal.WithLabelValues(tt.wantResult))
+
+			a := NewArchiver(store, cache, Options{After: 30 * 24 * time.Hour, BatchSize: 2}, zap.NewNop())
+			a.now = func() time.Time { return now }
+			a.drain(context.Background())
+
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d batches, got %d", tt.wantCalls, calls)
+			}
+			if evicted != tt.wantEvicted {
+				t.Errorf("expected %d orders evicted, got %d", tt.wantEvicted, evicted)
+			}
+			if got := testutil.ToFloat64(runsTotal.WithLabelValues(tt.wantResult)); got != runs+1 {
+				t.Errorf("expected one %s run recorded, got %v", tt.wantResult, got-runs)
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,13 +14,28 @@ import (
 	"time"
 
 	config "github.com/LootNex/OrderService/Consumer/configs"
+	"github.com/LootNex/OrderService/Consumer/internal/auth"
 	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
 	"github.com/LootNex/OrderService/Consumer/internal/db/redis"
+	"github.com/LootNex/OrderService/Consumer/internal/encryption"
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"github.com/LootNex/OrderService/Consumer/internal/gdpr"
+	"github.com/LootNex/OrderService/Consumer/internal/grpcserver"
 	"github.com/LootNex/OrderService/Consumer/internal/handlers"
 	"github.com/LootNex/OrderService/Consumer/internal/kafka/consumer"
+	"github.com/LootNex/OrderService/Consumer/internal/lifecycle"
 	"github.com/LootNex/OrderService/Consumer/internal/logger"
+	"github.com/LootNex/OrderService/Consumer/internal/outbox"
+	"github.com/LootNex/OrderService/Consumer/internal/partitions"
+	"github.com/LootNex/OrderService/Consumer/internal/privacy"
+	"github.com/LootNex/OrderService/Consumer/internal/ratelimit"
+	"github.com/LootNex/OrderService/Consumer/internal/retention"
 	"github.com/LootNex/OrderService/Consumer/internal/service"
+	"github.com/LootNex/OrderService/Consumer/internal/stream"
+	"github.com/LootNex/OrderService/Consumer/internal/webhooks"
+	goredis "github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -46,55 +64,518 @@ func StartServer() error {
 		return err
 	}
 
-	pgstorage := postgresql.NewPGStorage(PgConn, log)
-	CacheStorage := redis.NewCacheStorage(RedisConn)
-	serv := service.NewOrderService(pgstorage, CacheStorage, log)
-	OrderHandler := handlers.NewHandler(serv, log)
+	policy, err := privacy.NewPolicy(privacy.Options{
+		Rules:       privacy.ParseRules(cfg.Privacy.Roles),
+		DefaultRole: privacy.Role(cfg.Privacy.DefaultRole),
+		ExportRole:  privacy.Role(cfg.Privacy.ExportRole),
+		LogRole:     privacy.Role(cfg.Privacy.LogRole),
+	})
+	if err != nil {
+		return fmt.Errorf("invalid privacy config err:%w", err)
+	}
+
+	authn, err := initAuth(cfg, PgConn)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := loadKeyring(cfg, log)
+	if err != nil {
+		return err
+	}
 
-	if err = serv.LoadCache(ctx); err != nil {
-		return fmt.Errorf("cannot load cache: %w", err)
+	pgstorage := postgresql.NewPGStorage(PgConn, keyring, log)
+	pgstorage.SetQueryTimeout(cfg.Postgres.QueryTimeout)
+	if err := postgresql.RegisterPoolMetrics(PgConn, "main"); err != nil {
+		return err
 	}
 
-	go consumer.StartConsumer(ctx, cfg.Kafka.Topic, cfg.Kafka.Brokers, serv, log)
+	databases := []database{{conn: PgConn, storage: pgstorage, replicas: cfg.Postgres.Replicas}}
+	var repo orderStore = pgstorage
+	var sharded *postgresql.ShardedStorage
+	if len(cfg.Postgres.Shards) > 0 {
+		databases, err = openShards(cfg, keyring, log)
+		if err != nil {
+			return err
+		}
+
+		shards := make([]postgresql.Shard, 0, len(databases))
+		for _, d := range databases {
+			shards = append(shards, postgresql.Shard{Name: d.name, Storage: d.storage})
+		}
+		directory := postgresql.NewShardDirectory(PgConn)
+		directory.SetQueryTimeout(cfg.Postgres.QueryTimeout)
+		sharded, err = postgresql.NewShardedStorage(pgstorage, directory, shards, log)
+		if err != nil {
+			return fmt.Errorf("invalid shards config err:%w", err)
+		}
+		repo = sharded
+	}
+
+	CacheStorage := redis.NewCacheStorage(RedisConn, keyring)
+	bus := events.NewBus()
+	serv := service.NewOrderService(repo, CacheStorage, bus, log)
+	webhookStorage := postgresql.NewWebhookStorage(PgConn, log)
+	dispatcher := webhooks.NewDispatcher(webhookStorage, webhooks.Options{
+		Workers:      cfg.Webhooks.Workers,
+		QueueSize:    cfg.Webhooks.QueueSize,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		Timeout:      cfg.Webhooks.Timeout,
+		Backoff:      cfg.Webhooks.Backoff,
+		DisableAfter: cfg.Webhooks.DisableAfter,
+	}, log)
+	bus.Subscribe(func(e events.Event) {
+		e.Order = policy.Export(e.Order)
+		dispatcher.Notify(e)
+	})
+	broker := stream.NewBroker(cfg.Stream.History, cfg.Stream.ClientBuffer)
+	bus.Subscribe(broker.Notify)
+	OrderHandler := handlers.NewHandler(serv, policy, log)
+	WebhookHandler := handlers.NewWebhookHandler(webhookStorage, log)
+	CustomerHandler := handlers.NewCustomerHandler(gdpr.NewService(repo, CacheStorage, keyring, log, broker), log)
+	StreamHandler := handlers.NewStreamHandler(broker, cfg.Stream.Heartbeat, log)
+	KafkaConsumer := consumer.NewConsumer(cfg.Kafka.Topic, cfg.Kafka.Brokers, serv, log)
+	Replayer := consumer.NewReplayer(cfg.Kafka.Brokers, cfg.Kafka.Topic, serv, policy, log)
+	AdminHandler := handlers.NewAdminHandler(Replayer, KafkaConsumer, log)
 
 	r := mux.NewRouter()
 
+	limit, limitAuth, err := initRateLimit(cfg, RedisConn, log)
+	if err != nil {
+		return err
+	}
+
 	HttpServer := http.Server{
 		Addr:    ":" + cfg.Server.Port,
-		Handler: handlers.CORS(r),
+		Handler: handlers.CORS(cfg.CORS.AllowedOrigins)(limitAuth(handlers.Authenticate(authn, log)(handlers.ReadYourWrites(r)))),
 	}
 
-	r.HandleFunc("/order/{id}", OrderHandler.GetOrder).Methods("GET")
+	HttpServer.RegisterOnShutdown(broker.Close)
 
-	go func() {
+	read := func(route string, h http.HandlerFunc) http.HandlerFunc {
+		return limit(route, handlers.Require(auth.ScopeReadOrders, h))
+	}
+	admin := func(h http.HandlerFunc) http.HandlerFunc {
+		return limit("admin", handlers.Require(auth.ScopeAdmin, h))
+	}
+
+	r.HandleFunc("/order/{id}", read("order", OrderHandler.GetOrder)).Methods("GET")
+	r.HandleFunc("/orders/stream", read("stream", StreamHandler.Stream)).Methods("GET")
+	r.HandleFunc("/orders:batchGet", read("batch", OrderHandler.BatchGetOrders)).Methods("POST")
+	r.HandleFunc("/admin/replay", admin(AdminHandler.Replay)).Methods("POST")
+	r.HandleFunc("/admin/replay", admin(AdminHandler.ReplayStatus)).Methods("GET")
+	r.HandleFunc("/admin/consumer", admin(AdminHandler.ConsumerStatus)).Methods("GET")
+	r.HandleFunc("/admin/consumer/pause", admin(AdminHandler.PauseConsumer)).Methods("POST")
+	r.HandleFunc("/admin/consumer/resume", admin(AdminHandler.ResumeConsumer)).Methods("POST")
+	r.HandleFunc("/admin/customers/{id}/export", admin(CustomerHandler.Export)).Methods("GET")
+	r.HandleFunc("/admin/customers/{id}/erase", admin(CustomerHandler.Erase)).Methods("POST")
+	r.HandleFunc("/admin/webhooks", admin(WebhookHandler.Create)).Methods("POST")
+	r.HandleFunc("/admin/webhooks", admin(WebhookHandler.List)).Methods("GET")
+	r.HandleFunc("/admin/webhooks/{id}", admin(WebhookHandler.Get)).Methods("GET")
+	r.HandleFunc("/admin/webhooks/{id}", admin(WebhookHandler.Update)).Methods("PUT")
+	r.HandleFunc("/admin/webhooks/{id}", admin(WebhookHandler.Delete)).Methods("DELETE")
+	r.HandleFunc("/admin/webhooks/{id}/deliveries", admin(WebhookHandler.Deliveries)).Methods("GET")
 
-		log.Info("server running on port" + cfg.Server.Port)
+	// Components stop in reverse order: HTTP first, then the consumer
+	// finishes and commits its current message, and only then the stores
+	// close.
+	lc := lifecycle.NewManager(cfg.Server.ShutdownTimeout, log)
 
-		if err := HttpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Error("cannot start http server err:%v", zap.Error(err))
-			stop()
+	lc.Add(lifecycle.Component{
+		Name: "postgres",
+		Stop: func(context.Context) error { return PgConn.Close() },
+	})
+	lc.Add(lifecycle.Component{
+		Name: "redis",
+		Stop: func(context.Context) error { return RedisConn.Close() },
+	})
+	if sharded != nil {
+		for _, d := range databases {
+			lc.Add(lifecycle.Component{
+				Name: d.label("postgres"),
+				Stop: func(context.Context) error { return d.conn.Close() },
+			})
 		}
-	}()
+		lc.Add(lifecycle.Component{
+			Name:  "shard directory",
+			Start: sharded.Backfill,
+		})
+	}
+	// Each database holding orders has its own outbox and runs its own
+	// maintenance.
+	for _, d := range databases {
+		if len(d.replicas) > 0 {
+			replicas, err := openReplicas(d, cfg, log)
+			if err != nil {
+				return err
+			}
+			d.storage.UseReplicas(replicas)
 
-	<-ctx.Done()
+			lc.Add(lifecycle.Component{
+				Name: d.label("replica health"),
+				Run:  replicas.Run,
+				Stop: func(context.Context) error { return replicas.Close() },
+			})
+		}
+		if cfg.Outbox.Topic != "" {
+			publisher := outbox.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Outbox.Topic)
+			relay := outbox.NewRelay(postgresql.NewOutboxStorage(d.conn, log), publisher, keyring, policy, outbox.Options{
+				Interval:  cfg.Outbox.Interval,
+				BatchSize: cfg.Outbox.BatchSize,
+				Retention: cfg.Outbox.Retention,
+			}, log)
 
-	log.Info("shutting down gracefully...")
+			lc.Add(lifecycle.Component{
+				Name: d.label("outbox relay"),
+				Run: func(ctx context.Context) error {
+					defer publisher.Close()
+					return relay.Run(ctx)
+				},
+			})
+		}
+		if keyring != nil {
+			rotator := encryption.NewRotator(d.storage, encryption.RotatorOptions{
+				Interval:  cfg.Encryption.ReencryptInterval,
+				BatchSize: cfg.Encryption.ReencryptBatch,
+			}, log)
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+			lc.Add(lifecycle.Component{
+				Name: d.label("pii re-encryption"),
+				Run:  rotator.Run,
+			})
+		}
+		maintainer := partitions.NewMaintainer(d.storage, partitions.Options{
+			Interval:    cfg.Partitions.Interval,
+			MonthsAhead: cfg.Partitions.MonthsAhead,
+		}, log)
+
+		lc.Add(lifecycle.Component{
+			Name: d.label("partition maintenance"),
+			Run:  maintainer.Run,
+		})
+		if cfg.Retention.Enabled {
+			archiver := retention.NewArchiver(d.storage, CacheStorage, retention.Options{
+				After:     time.Duration(cfg.Retention.ArchiveAfterDays) * 24 * time.Hour,
+				Interval:  cfg.Retention.Interval,
+				BatchSize: cfg.Retention.BatchSize,
+			}, log)
 
-	if err := HttpServer.Shutdown(shutdownCtx); err != nil {
-		return fmt.Errorf("cannot stop server err:%w", err)
+			lc.Add(lifecycle.Component{
+				Name: d.label("order retention"),
+				Run:  archiver.Run,
+			})
+		}
+	}
+	lc.Add(lifecycle.Component{
+		Name: "cache",
+		Start: func(ctx context.Context) error {
+			if err := serv.LoadCache(ctx); err != nil {
+				return fmt.Errorf("cannot load cache: %w", err)
+			}
+			return nil
+		},
+	})
+	lc.Add(lifecycle.Component{
+		Name: "webhook dispatcher",
+		Run:  dispatcher.Run,
+	})
+	if cfg.Kafka.HealthCheckInterval > 0 {
+		lc.Add(lifecycle.Component{
+			Name: "postgres health check",
+			Run: func(ctx context.Context) error {
+				KafkaConsumer.WatchHealth(ctx, PgConn, cfg.Kafka.HealthCheckInterval)
+				return nil
+			},
+		})
 	}
+	lc.Add(lifecycle.Component{
+		Name: "kafka consumer",
+		Run:  KafkaConsumer.Run,
+	})
+	lc.Add(lifecycle.Component{
+		Name: "kafka replay",
+		Run:  Replayer.Run,
+	})
+	if cfg.GRPC.Port != "" {
+		GrpcServer := grpcserver.Register(grpcserver.NewServer(serv, broker, policy, log),
+			append(grpcserver.WithAuth(authn), grpcserver.WithReadYourWrites()...)...)
 
-	if err := PgConn.Close(); err != nil {
-		log.Error("cannot close postgres", zap.Error(err))
+		lc.Add(lifecycle.Component{
+			Name: "grpc server",
+			Run: func(context.Context) error {
+				lis, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+				if err != nil {
+					return fmt.Errorf("cannot listen on grpc port err:%w", err)
+				}
+
+				log.Info("grpc server running on port" + cfg.GRPC.Port)
+				return GrpcServer.Serve(lis)
+			},
+			Stop: func(ctx context.Context) error {
+				done := make(chan struct{})
+				go func() {
+					GrpcServer.GracefulStop()
+					close(done)
+				}()
+
+				select {
+				case <-done:
+				case <-ctx.Done():
+					GrpcServer.Stop()
+				}
+				return nil
+			},
+		})
 	}
-	if err := RedisConn.Close(); err != nil {
-		log.Error("cannot close redis", zap.Error(err))
+	if cfg.Metrics.Port != "" {
+		MetricsServer := http.Server{
+			Addr:    ":" + cfg.Metrics.Port,
+			Handler: promhttp.Handler(),
+		}
+
+		lc.Add(lifecycle.Component{
+			Name: "metrics server",
+			Run: func(context.Context) error {
+				if err := MetricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					return fmt.Errorf("cannot start metrics server err:%w", err)
+				}
+				return nil
+			},
+			Stop: MetricsServer.Shutdown,
+		})
+	}
+	lc.Add(lifecycle.Component{
+		Name: "http server",
+		Run: func(context.Context) error {
+			log.Info("server running on port" + cfg.Server.Port)
+
+			if err := HttpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("cannot start http server err:%w", err)
+			}
+			return nil
+		},
+		Stop: HttpServer.Shutdown,
+	})
+
+	if err := lc.Run(ctx); err != nil {
+		return err
 	}
 
 	log.Info("Server stopped")
 	return nil
 
 }
+
+// orderStore is the order repository together with what the privacy
+// service needs from it.
+type orderStore interface {
+	postgresql.RepManager
+	gdpr.Store
+}
+
+// database is a Postgres database holding orders: the main one, or a shard.
+type database struct {
+	name     string
+	conn     *sql.DB
+	storage  *postgresql.PGStorage
+	replicas []string
+}
+
+func (d database) label(component string) string {
+	if d.name == "" {
+		return component
+	}
+	return component + " " + d.name
+}
+
+// poolName labels the pool metrics of the database.
+func (d database) poolName() string {
+	if d.name == "" {
+		return "main"
+	}
+	return d.name
+}
+
+// loadKeyring takes the keyring from ORDERSERVICE_KEYRING or the keyring
+// file. A missing keyring fails the startup, so personal data is never
+// stored in plain text by accident; only the development mode goes on
+// without encryption.
+func loadKeyring(cfg *config.Config, log *zap.Logger) (*encryption.Keyring, error) {
+
+	if cfg.Encryption.Keyring != "" {
+		keyring, err := encryption.ParseKeyring([]byte(cfg.Encryption.Keyring))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ORDERSERVICE_KEYRING err:%w", err)
+		}
+		return keyring, nil
+	}
+
+	if cfg.Encryption.KeyringFile != "" {
+		keyring, err := encryption.LoadKeyring(cfg.Encryption.KeyringFile)
+		if err == nil {
+			return keyring, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) || !cfg.Encryption.AllowPlaintext {
+			return nil, err
+		}
+	}
+
+	if !cfg.Encryption.AllowPlaintext {
+		return nil, errors.New("no keyring: set ORDERSERVICE_KEYRING or mount encryption.keyringFile")
+	}
+
+	log.Warn("no keyring, delivery personal data is stored in plain text (development mode)")
+	return nil, nil
+}
+
+// openShards connects to every shard and applies migrations there.
+func openShards(cfg *config.Config, keyring *encryption.Keyring, log *zap.Logger) ([]database, error) {
+
+	databases := make([]database, 0, len(cfg.Postgres.Shards))
+	for _, shard := range cfg.Postgres.Shards {
+		dsn, err := postgresql.MergeConnString(shard.DSN, cfg)
+		if err != nil {
+			for _, d := range databases {
+				d.conn.Close()
+			}
+			return nil, fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+		conn, err := postgresql.OpenPostgres(dsn, postgresql.NewPoolOptions(cfg), log.With(zap.String("shard", shard.Name)))
+		if err != nil {
+			for _, d := range databases {
+				d.conn.Close()
+			}
+			return nil, fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+		d := database{
+			name:     shard.Name,
+			conn:     conn,
+			storage:  postgresql.NewPGStorage(conn, keyring, log),
+			replicas: shard.Replicas,
+		}
+		d.storage.SetQueryTimeout(cfg.Postgres.QueryTimeout)
+		databases = append(databases, d)
+
+		if err := postgresql.RegisterPoolMetrics(conn, d.poolName()); err != nil {
+			for _, d := range databases {
+				d.conn.Close()
+			}
+			return nil, err
+		}
+	}
+
+	return databases, nil
+}
+
+// openReplicas connects to the read replicas of the database.
+func openReplicas(d database, cfg *config.Config, log *zap.Logger) (*postgresql.ReplicaSet, error) {
+
+	conns := make([]*sql.DB, 0, len(d.replicas))
+	for i, dsn := range d.replicas {
+		dsn, err := postgresql.MergeConnString(dsn, cfg)
+		if err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return nil, fmt.Errorf("%s %d: %w", d.label("replica"), i, err)
+		}
+		conn, err := postgresql.OpenReplica(dsn, postgresql.NewPoolOptions(cfg))
+		if err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return nil, fmt.Errorf("%s %d: %w", d.label("replica"), i, err)
+		}
+		conns = append(conns, conn)
+
+		if err := postgresql.RegisterPoolMetrics(conn, fmt.Sprintf("%s/replica%d", d.poolName(), i)); err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return nil, err
+		}
+	}
+
+	return postgresql.NewReplicaSet(conns, postgresql.ReplicaOptions{
+		Name:                d.name,
+		HealthCheckInterval: cfg.Postgres.Replica.HealthCheckInterval,
+		MaxLag:              cfg.Postgres.Replica.MaxLag,
+	}, log), nil
+}
+
+// initAuth builds the authenticators enabled in the config: static keys,
+// keys from Postgres and JWTs, tried in this order.
+func initAuth(cfg *config.Config, db *sql.DB) (auth.Authenticator, error) {
+
+	if err := auth.ValidateScopes(cfg.Auth.AnonymousScopes); err != nil {
+		return nil, fmt.Errorf("invalid anonymous scopes err:%w", err)
+	}
+
+	static := make(auth.StaticKeys, 0, len(cfg.Auth.APIKeys))
+	for _, k := range cfg.Auth.APIKeys {
+		static = append(static, auth.StaticKey{Name: k.Name, Hash: k.Hash, Scopes: k.Scopes, Role: privacy.Role(k.Role)})
+	}
+	if err := static.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid api keys config err:%w", err)
+	}
+
+	stores := []auth.KeyStore{static}
+	if cfg.Auth.APIKeysTable {
+		stores = append(stores, postgresql.NewAPIKeyStorage(db))
+	}
+	authenticators := []auth.Authenticator{auth.NewAPIKeyAuthenticator(stores...)}
+
+	if cfg.Auth.JWT.JWKSFile != "" {
+		jwt, err := auth.NewJWTAuthenticator(auth.JWTOptions{
+			JWKSFile:  cfg.Auth.JWT.JWKSFile,
+			Issuer:    cfg.Auth.JWT.Issuer,
+			Audience:  cfg.Auth.JWT.Audience,
+			RoleClaim: cfg.Auth.JWT.RoleClaim,
+			Leeway:    cfg.Auth.JWT.Leeway,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot init jwt auth err:%w", err)
+		}
+		authenticators = append(authenticators, jwt)
+	}
+
+	return auth.NewChain(cfg.Auth.AnonymousScopes, authenticators...), nil
+}
+
+// initRateLimit returns the limit per route and principal and the limit
+// per IP that runs before authentication. With limiting disabled both pass
+// requests through.
+func initRateLimit(cfg *config.Config, conn *goredis.Client, log *zap.Logger) (func(string, http.HandlerFunc) http.HandlerFunc, func(http.Handler) http.Handler, error) {
+
+	if !cfg.RateLimit.Enabled {
+		return func(_ string, next http.HandlerFunc) http.HandlerFunc { return next },
+			func(next http.Handler) http.Handler { return next }, nil
+	}
+
+	def := ratelimit.Limit(cfg.RateLimit.Default)
+	if err := def.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid default rate limit err:%w", err)
+	}
+	routes := make(map[string]ratelimit.Limit, len(cfg.RateLimit.Routes))
+	for name, rule := range cfg.RateLimit.Routes {
+		lim := ratelimit.Limit(rule)
+		if err := lim.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid rate limit for %s err:%w", name, err)
+		}
+		routes[name] = lim
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Redis {
+		store = redis.NewRateLimitStorage(conn)
+	}
+
+	hops := 0
+	if cfg.RateLimit.TrustForwardedFor {
+		hops = max(1, cfg.RateLimit.TrustedHops)
+	}
+
+	limiter := ratelimit.NewLimiter(store, def, routes)
+	return handlers.RateLimit(limiter, hops, log),
+		handlers.RateLimitAuth(limiter, "auth", hops, log), nil
+}
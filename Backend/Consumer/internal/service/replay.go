@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Consumer/internal/gdpr"
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+type ReplayResult string
+
+const (
+	ReplayNew         ReplayResult = "new"
+	ReplayIdentical   ReplayResult = "identical"
+	ReplayConflicting ReplayResult = "conflicting"
+	// ReplayOverwritten is a conflicting order that replaced the stored one.
+	ReplayOverwritten ReplayResult = "overwritten"
+)
+
+// ReplayOrder stores an order read again from Kafka. Unknown orders are
+// saved as usual; stored ones are only compared, so running the same
+// replay twice changes nothing. With overwrite, a conflicting order replaces
+// the stored one, which is how orders fixed upstream are reprocessed.
+// Orders of erased customers are never overwritten, or the replay would
+// bring their personal data back.
+func (os *OrderService) ReplayOrder(ctx context.Context, order models.Order, overwrite bool) (ReplayResult, error) {
+
+	if err := order.Validate(); err != nil {
+		return "", fmt.Errorf("%w: %v", errs.ErrInvalidOrder, err)
+	}
+
+	// Read from the primary, so an order saved moments ago by the consumer
+	// is not saved again.
+	stored, err := os.Rep.GetOrderByID(postgresql.ReadPrimary(ctx), order.OrderUID)
+	if errors.Is(err, errs.ErrOrderNotFound) {
+		if err := os.SaveNewOrder(ctx, &order); err != nil {
+			return "", err
+		}
+		return ReplayNew, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if sameOrder(stored, order) {
+		return ReplayIdentical, nil
+	}
+	if !overwrite || gdpr.IsPseudonym(stored.CustomerID) {
+		return ReplayConflicting, nil
+	}
+
+	if err := os.Rep.ReplaceOrder(ctx, order); err != nil {
+		return "", err
+	}
+	if err := os.Cach.SaveOrderCache(ctx, order); err != nil {
+		os.log.Warn("cannot save order in cache", zap.Error(err))
+	}
+
+	return ReplayOverwritten, nil
+}
+
+// sameOrder compares orders the way they come back from storage: the
+// delivery id is assigned by the database, dates may be printed in another
+// time zone and items have no stable order.
+func sameOrder(a, b models.Order) bool {
+
+	ta, errA := time.Parse(time.RFC3339, a.DateCreated)
+	tb, errB := time.Parse(time.RFC3339, b.DateCreated)
+	if errA != nil || errB != nil || !ta.Equal(tb) {
+		return false
+	}
+
+	a, b = normalize(a), normalize(b)
+	return reflect.DeepEqual(a, b)
+}
+
+func normalize(order models.Order) models.Order {
+	order.DateCreated = ""
+	order.Delivery.DeliveryID = ""
+	order.Items = append([]models.Item{}, order.Items...)
+	sort.Slice(order.Items, func(i, j int) bool { return order.Items[i].ChrtID < order.Items[j].ChrtID })
+	return order
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/LootNex/OrderService/Consumer/internal/errs"
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+func replayOrder() models.Order {
+	return models.Order{
+		OrderUID:        "123",
+		TrackNumber:     "TRACK123",
+		CustomerID:      "cust1",
+		DeliveryService: "meest",
+		DateCreated:     "2021-11-26T06:22:19Z",
+		Delivery: models.Delivery{
+			Name: "Test", Phone: "123", Email: "test@test.com",
+		},
+		Payment: models.Payment{
+			Transaction: "tr1", Amount: 100, Currency: "USD",
+		},
+		Items: []models.Item{
+			{ChrtID: 1, TrackNumber: "T1", Price: 100, TotalPrice: 100},
+			{ChrtID: 2, TrackNumber: "T1", Price: 50, TotalPrice: 50},
+		},
+	}
+}
+
+func TestReplayOrder(t *testing.T) {
+
+	identical := replayOrder()
+	identical.Delivery.DeliveryID = "17"
+	identical.DateCreated = "2021-11-26T09:22:19+03:00"
+	identical.Items[0], identical.Items[1] = identical.Items[1], identical.Items[0]
+
+	conflicting := replayOrder()
+	conflicting.Payment.Amount = 200
+
+	erased := conflicting
+	erased.CustomerID = "erased-0011223344556677"
+	erased.Delivery = models.Delivery{}
+
+	tests := []struct {
+		name      string
+		order     models.Order
+		overwrite bool
+		stored    models.Order
+		getErr    error
+		saveErr   error
+		want      ReplayResult
+		wantErrIs error
+	}{
+		{
+			name:   "new",
+			order:  replayOrder(),
+			getErr: errs.ErrOrderNotFound,
+			want:   ReplayNew,
+		},
+		{
+			name:   "identical",
+			order:  replayOrder(),
+			stored: identical,
+			want:   ReplayIdentical,
+		},
+		{
+			name:   "conflicting",
+			order:  replayOrder(),
+			stored: conflicting,
+			want:   ReplayConflicting,
+		},
+		{
+			name:      "conflicting overwritten",
+			order:     replayOrder(),
+			overwrite: true,
+			stored:    conflicting,
+			want:      ReplayOverwritten,
+		},
+		{
+			name:      "identical with overwrite",
+			order:     replayOrder(),
+			overwrite: true,
+			stored:    identical,
+			want:      ReplayIdentical,
+		},
+		{
+			name:      "erased customer not overwritten",
+			order:     replayOrder(),
+			overwrite: true,
+			stored:    erased,
+			want:      ReplayConflicting,
+		},
+		{
+			name:      "invalid",
+			order:     models.Order{OrderUID: "123"},
+			wantErrIs: errs.ErrInvalidOrder,
+		},
+		{
+			name:      "storage error",
+			order:     replayOrder(),
+			getErr:    errs.ErrOrderNotFound,
+			saveErr:   errors.New("db down"),
+			wantErrIs: errors.New("db down"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			log, err := zap.NewDevelopment()
+			if err != nil {
+				t.Errorf("cannot init logger err: %v", err)
+			}
+
+			saved, replaced, cached := false, false, false
+			orderServ := OrderService{
+				Rep: MockRepManager{
+					GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+						return tt.stored, tt.getErr
+					},
+					SaveNewOrderFunc: func(ctx context.Context, order models.Order) error {
+						saved = true
+						return tt.saveErr
+					},
+					ReplaceOrderFunc: func(ctx context.Context, order models.Order) error {
+						replaced = true
+						return nil
+					}},
+				Cach: MockCacheManager{SaveOrderCacheFunc: func(ctx context.Context, order models.Order) error {
+					cached = true
+					return nil
+				}},
+				log: log,
+			}
+
+			got, err := orderServ.ReplayOrder(context.Background(), tt.order, tt.overwrite)
+			if (err != nil) != (tt.wantErrIs != nil) {
+				t.Fatalf("expected err:%v, got err:%v", tt.wantErrIs, err)
+			}
+			if tt.wantErrIs == errs.ErrInvalidOrder && !errors.Is(err, errs.ErrInvalidOrder) {
+				t.Errorf("expected ErrInvalidOrder, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if saved != (tt.getErr != nil) {
+				t.Errorf("order must be saved only when it was not stored, saved=%v", saved)
+			}
+			if replaced != (tt.want == ReplayOverwritten) {
+				t.Errorf("order must be replaced only when overwritten, replaced=%v", replaced)
+			}
+			if tt.want == ReplayOverwritten && !cached {
+				t.Errorf("overwritten order must be cached")
+			}
+		})
+	}
+}
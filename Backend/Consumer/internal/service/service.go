@@ -3,55 +3,99 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
 	"github.com/LootNex/OrderService/Consumer/internal/db/redis"
 	"github.com/LootNex/OrderService/Consumer/internal/errs"
-	"github.com/LootNex/OrderService/Consumer/internal/models"
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"github.com/LootNex/OrderService/Contract/models"
 	"go.uber.org/zap"
 )
 
 type OrderService struct {
-	Rep  postgresql.RepManager
-	Cach redis.CacheManager
-	log  *zap.Logger
+	Rep    postgresql.RepManager
+	Cach   redis.CacheManager
+	Events events.Publisher
+	log    *zap.Logger
 }
 
 type ServiceManager interface {
 	SaveNewOrder(ctx context.Context, val models.Validator) error
 	GetOrderByID(ctx context.Context, orderID string) (models.Order, error)
 	LoadCache(ctx context.Context) error
+	ReplayOrder(ctx context.Context, order models.Order, overwrite bool) (ReplayResult, error)
+	GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, []string, error)
+	ListOrders(ctx context.Context, filter postgresql.ListFilter) ([]models.Order, error)
 }
 
-func NewOrderService(rep postgresql.RepManager, cach redis.CacheManager, logg *zap.Logger) *OrderService {
+func NewOrderService(rep postgresql.RepManager, cach redis.CacheManager, pub events.Publisher, logg *zap.Logger) *OrderService {
 	return &OrderService{
-		Rep:  rep,
-		Cach: cach,
-		log:  logg,
+		Rep:    rep,
+		Cach:   cach,
+		Events: pub,
+		log:    logg,
 	}
 }
 
 func (os *OrderService) SaveNewOrder(ctx context.Context, val models.Validator) error {
 
 	if err := val.Validate(); err != nil {
-		return err
+		return fmt.Errorf("%w: %v", errs.ErrInvalidOrder, err)
 	}
 
 	switch order := val.(type) {
 	case *models.Order:
-		if err := os.Rep.SaveNewOrder(ctx, *order); err != nil {
+		err := os.Rep.SaveNewOrder(ctx, *order)
+		if errors.Is(err, errs.ErrOrderExists) {
+			return os.updateOrderStatus(ctx, *order)
+		}
+		if err != nil {
 			return err
 		}
 
 		if err := os.Cach.SaveOrderCache(ctx, *order); err != nil {
 			return err
 		}
+
+		os.publish(events.OrderCreated, *order)
+	}
+
+	return nil
+
+}
+
+// updateOrderStatus handles a repeated message for a stored order. The
+// producer keys messages by order_uid, so updates of one order are consumed
+// in the order they were sent and each one simply overwrites the previous.
+func (os *OrderService) updateOrderStatus(ctx context.Context, order models.Order) error {
+
+	if err := os.Rep.UpdateOrderStatus(ctx, order); err != nil {
+		return err
+	}
+
+	// A replica may not have the update yet.
+	orderData, err := os.Rep.GetOrderByID(postgresql.ReadPrimary(ctx), order.OrderUID)
+	if err != nil {
+		return err
 	}
 
+	if err = os.Cach.SaveOrderCache(ctx, orderData); err != nil {
+		os.log.Warn("cannot save order in cache", zap.Error(err))
+	}
+
+	os.publish(events.OrderStatusChanged, orderData)
+
 	return nil
 
 }
 
+func (os *OrderService) publish(eventType string, order models.Order) {
+	if os.Events != nil {
+		os.Events.Publish(events.New(eventType, order))
+	}
+}
+
 func (os *OrderService) GetOrderByID(ctx context.Context, orderID string) (models.Order, error) {
 
 	orderData, err := os.Cach.GetOrderByID(ctx, orderID)
@@ -61,13 +105,18 @@ func (os *OrderService) GetOrderByID(ctx context.Context, orderID string) (model
 		return orderData, err
 	}
 
-	orderData, err = os.Rep.GetOrderByID(ctx, orderID)
+	// Only orders read from the primary are cached: a lagging replica could
+	// still hold data erased moments ago, and the cache would keep it.
+	readCtx, src := postgresql.TrackReads(ctx)
+	orderData, err = os.Rep.GetOrderByID(readCtx, orderID)
 	if err != nil {
 		return models.Order{}, err
 	}
 
-	if err = os.Cach.SaveOrderCache(ctx, orderData); err != nil {
-		os.log.Warn("cannot save order in cache", zap.Error(err))
+	if !src.Replica() {
+		if err = os.Cach.SaveOrderCache(ctx, orderData); err != nil {
+			os.log.Warn("cannot save order in cache", zap.Error(err))
+		}
 	}
 
 	return orderData, nil
@@ -81,9 +130,16 @@ func (os *OrderService) LoadCache(ctx context.Context) error {
 		return err
 	}
 
+	// The IDs may come from a replica, the orders put into the cache are
+	// read from the primary. An order gone from the primary since is
+	// skipped.
+	primary := postgresql.ReadPrimary(ctx)
 	for _, id := range Ids {
 
-		orderData, err := os.Rep.GetOrderByID(ctx, id)
+		orderData, err := os.Rep.GetOrderByID(primary, id)
+		if errors.Is(err, errs.ErrOrderNotFound) {
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -96,3 +152,79 @@ func (os *OrderService) LoadCache(ctx context.Context) error {
 	return nil
 
 }
+
+// GetOrdersByIDs returns the orders found, in request order, and the IDs
+// that do not exist. The cache is read with one round trip, misses are
+// loaded with one query and written back to the cache unless a replica
+// served them.
+func (os *OrderService) GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, []string, error) {
+
+	IDs := make([]string, 0, len(orderIDs))
+	seen := make(map[string]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		if !seen[id] {
+			seen[id] = true
+			IDs = append(IDs, id)
+		}
+	}
+
+	found, err := os.Cach.GetOrdersByIDs(ctx, IDs)
+	if err != nil {
+		os.log.Warn("cannot read orders from cache", zap.Error(err))
+		found = make(map[string]models.Order, len(IDs))
+	}
+
+	var misses []string
+	for _, id := range IDs {
+		if _, ok := found[id]; !ok {
+			misses = append(misses, id)
+		}
+	}
+
+	if len(misses) > 0 {
+		readCtx, src := postgresql.TrackReads(ctx)
+		loaded, err := os.Rep.GetOrdersByIDs(readCtx, misses)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, order := range loaded {
+			found[order.OrderUID] = order
+		}
+
+		if !src.Replica() {
+			if err = os.Cach.SaveOrdersCache(ctx, loaded); err != nil {
+				os.log.Warn("cannot save orders in cache", zap.Error(err))
+			}
+		}
+	}
+
+	orders := make([]models.Order, 0, len(found))
+	var missing []string
+	for _, id := range IDs {
+		if order, ok := found[id]; ok {
+			orders = append(orders, order)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	return orders, missing, nil
+
+}
+
+func (os *OrderService) ListOrders(ctx context.Context, filter postgresql.ListFilter) ([]models.Order, error) {
+
+	IDs, err := os.Rep.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, _, err := os.GetOrdersByIDs(ctx, IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+
+}
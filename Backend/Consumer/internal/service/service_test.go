@@ -3,22 +3,39 @@ package service
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
 
+	"github.com/LootNex/OrderService/Consumer/internal/db/postgresql"
 	"github.com/LootNex/OrderService/Consumer/internal/errs"
-	"github.com/LootNex/OrderService/Consumer/internal/models"
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"github.com/LootNex/OrderService/Contract/models"
 	"go.uber.org/zap"
 )
 
 type MockRepManager struct {
-	SaveNewOrderFunc  func(ctx context.Context, order models.Order) error
-	GetOrderByIDFunc  func(ctx context.Context, orderID string) (models.Order, error)
-	GetAllOrderIDFunc func(ctx context.Context) ([]string, error)
+	SaveNewOrderFunc      func(ctx context.Context, order models.Order) error
+	GetOrderByIDFunc      func(ctx context.Context, orderID string) (models.Order, error)
+	GetAllOrderIDFunc     func(ctx context.Context) ([]string, error)
+	UpdateOrderStatusFunc func(ctx context.Context, order models.Order) error
+	ListOrdersFunc        func(ctx context.Context, filter postgresql.ListFilter) ([]string, error)
+	GetOrdersByIDsFunc    func(ctx context.Context, orderIDs []string) ([]models.Order, error)
+	ReplaceOrderFunc      func(ctx context.Context, order models.Order) error
 }
 
 type MockCacheManager struct {
-	SaveOrderCacheFunc func(ctx context.Context, order models.Order) error
-	GetOrderByIDFunc   func(ctx context.Context, orderID string) (models.Order, error)
+	SaveOrderCacheFunc  func(ctx context.Context, order models.Order) error
+	GetOrderByIDFunc    func(ctx context.Context, orderID string) (models.Order, error)
+	GetOrdersByIDsFunc  func(ctx context.Context, orderIDs []string) (map[string]models.Order, error)
+	SaveOrdersCacheFunc func(ctx context.Context, orders []models.Order) error
+}
+
+type MockPublisher struct {
+	published []events.Event
+}
+
+func (mp *MockPublisher) Publish(e events.Event) {
+	mp.published = append(mp.published, e)
 }
 
 type MockValidator struct {
@@ -37,6 +54,30 @@ func (mRP MockRepManager) GetAllOrderID(ctx context.Context) ([]string, error) {
 	return mRP.GetAllOrderIDFunc(ctx)
 }
 
+func (mRP MockRepManager) UpdateOrderStatus(ctx context.Context, order models.Order) error {
+	return mRP.UpdateOrderStatusFunc(ctx, order)
+}
+
+func (mRP MockRepManager) ListOrders(ctx context.Context, filter postgresql.ListFilter) ([]string, error) {
+	return mRP.ListOrdersFunc(ctx, filter)
+}
+
+func (mRP MockRepManager) GetOrdersByIDs(ctx context.Context, orderIDs []string) ([]models.Order, error) {
+	return mRP.GetOrdersByIDsFunc(ctx, orderIDs)
+}
+
+func (mRP MockRepManager) ReplaceOrder(ctx context.Context, order models.Order) error {
+	return mRP.ReplaceOrderFunc(ctx, order)
+}
+
+func (mCM MockCacheManager) GetOrdersByIDs(ctx context.Context, orderIDs []string) (map[string]models.Order, error) {
+	return mCM.GetOrdersByIDsFunc(ctx, orderIDs)
+}
+
+func (mCM MockCacheManager) SaveOrdersCache(ctx context.Context, orders []models.Order) error {
+	return mCM.SaveOrdersCacheFunc(ctx, orders)
+}
+
 func (mCM MockCacheManager) SaveOrderCache(ctx context.Context, order models.Order) error {
 	return mCM.SaveOrderCacheFunc(ctx, order)
 }
@@ -135,11 +176,135 @@ func TestSaveNewOrder(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Errorf("expected: %v, got: %v", tt.wantErr, err)
 			}
+			if tt.name == "invalid validator" && !errors.Is(err, errs.ErrInvalidOrder) {
+				t.Errorf("expected ErrInvalidOrder, got: %v", err)
+			}
 		})
 	}
 
 }
 
+func TestSaveNewOrder_StatusUpdate(t *testing.T) {
+
+	order := &models.Order{
+		OrderUID:        "123",
+		TrackNumber:     "TRACK123",
+		CustomerID:      "cust1",
+		DeliveryService: "meest",
+		DateCreated:     "2021-11-26T06:22:19Z",
+		Delivery: models.Delivery{
+			Name: "Test", Phone: "123", Email: "test@test.com",
+		},
+		Payment: models.Payment{
+			Transaction: "tr1", Amount: 100, Currency: "USD",
+		},
+		Items: []models.Item{
+			{ChrtID: 1, TrackNumber: "T1", Price: 100, TotalPrice: 100, Status: 203},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		updateErr error
+		wantErr   bool
+	}{
+		{
+			name:      "success",
+			updateErr: nil,
+			wantErr:   false,
+		},
+		{
+			name:      "invalid UpdateOrderStatus",
+			updateErr: errors.New("cannot update"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			log, err := zap.NewDevelopment()
+			if err != nil {
+				t.Errorf("cannot init logger err: %v", err)
+			}
+
+			var cached models.Order
+			pub := &MockPublisher{}
+			orderServ := OrderService{
+				Events: pub,
+				Rep: MockRepManager{
+					SaveNewOrderFunc: func(ctx context.Context, order models.Order) error { return errs.ErrOrderExists },
+					UpdateOrderStatusFunc: func(ctx context.Context, order models.Order) error {
+						return tt.updateErr
+					},
+					GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+						return *order, nil
+					}},
+				Cach: MockCacheManager{SaveOrderCacheFunc: func(ctx context.Context, order models.Order) error {
+					cached = order
+					return nil
+				}},
+				log: log,
+			}
+
+			err = orderServ.SaveNewOrder(context.Background(), order)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected: %v, got: %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && cached.OrderUID != order.OrderUID {
+				t.Errorf("expected order %v to be cached, got %v", order.OrderUID, cached.OrderUID)
+			}
+
+			wantEvents := 1
+			if tt.wantErr {
+				wantEvents = 0
+			}
+			if len(pub.published) != wantEvents {
+				t.Fatalf("expected %d events, got %d", wantEvents, len(pub.published))
+			}
+			if wantEvents == 1 && pub.published[0].Type != events.OrderStatusChanged {
+				t.Errorf("expected %s event, got %s", events.OrderStatusChanged, pub.published[0].Type)
+			}
+		})
+	}
+}
+
+func TestSaveNewOrder_PublishesCreated(t *testing.T) {
+
+	pub := &MockPublisher{}
+	orderServ := OrderService{
+		Rep:    MockRepManager{SaveNewOrderFunc: func(ctx context.Context, order models.Order) error { return nil }},
+		Cach:   MockCacheManager{SaveOrderCacheFunc: func(ctx context.Context, order models.Order) error { return nil }},
+		Events: pub,
+		log:    zap.NewNop(),
+	}
+
+	order := &models.Order{
+		OrderUID:        "123",
+		TrackNumber:     "TRACK123",
+		CustomerID:      "cust1",
+		DeliveryService: "meest",
+		DateCreated:     "2021-11-26T06:22:19Z",
+		Delivery: models.Delivery{
+			Name: "Test", Phone: "123", Email: "test@test.com",
+		},
+		Payment: models.Payment{
+			Transaction: "tr1", Amount: 100, Currency: "USD",
+		},
+		Items: []models.Item{
+			{ChrtID: 1, TrackNumber: "T1", Price: 100, TotalPrice: 100},
+		},
+	}
+
+	if err := orderServ.SaveNewOrder(context.Background(), order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pub.published) != 1 || pub.published[0].Type != events.OrderCreated || pub.published[0].Order.OrderUID != "123" {
+		t.Errorf("unexpected events %+v", pub.published)
+	}
+}
+
 func TestGetOrderByID(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -267,3 +432,180 @@ func TestLoadCache(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOrdersByIDs(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		cacheErr    error
+		wantQueried []string
+	}{
+		{
+			name:        "cache hit and misses",
+			wantQueried: []string{"2", "3"},
+		},
+		{
+			name:        "cache unavailable",
+			cacheErr:    errors.New("redis is down"),
+			wantQueried: []string{"1", "2", "3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var queried []string
+			var backfilled []models.Order
+
+			orderServ := OrderService{
+				Rep: MockRepManager{GetOrdersByIDsFunc: func(ctx context.Context, orderIDs []string) ([]models.Order, error) {
+					queried = orderIDs
+					var orders []models.Order
+					for _, id := range orderIDs {
+						if id != "3" {
+							orders = append(orders, models.Order{OrderUID: id})
+						}
+					}
+					return orders, nil
+				}},
+				Cach: MockCacheManager{
+					GetOrdersByIDsFunc: func(ctx context.Context, orderIDs []string) (map[string]models.Order, error) {
+						if tt.cacheErr != nil {
+							return nil, tt.cacheErr
+						}
+						return map[string]models.Order{"1": {OrderUID: "1"}}, nil
+					},
+					SaveOrdersCacheFunc: func(ctx context.Context, orders []models.Order) error {
+						backfilled = orders
+						return nil
+					},
+				},
+				log: zap.NewNop(),
+			}
+
+			orders, missing, err := orderServ.GetOrdersByIDs(context.Background(), []string{"1", "2", "2", "3"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(orders) != 2 || orders[0].OrderUID != "1" || orders[1].OrderUID != "2" {
+				t.Errorf("unexpected orders %v", orders)
+			}
+			if len(missing) != 1 || missing[0] != "3" {
+				t.Errorf("unexpected missing %v", missing)
+			}
+			if !reflect.DeepEqual(queried, tt.wantQueried) {
+				t.Errorf("expected query for %v, got %v", tt.wantQueried, queried)
+			}
+			if len(backfilled) != len(tt.wantQueried)-1 {
+				t.Errorf("expected the loaded orders to be cached, got %v", backfilled)
+			}
+		})
+	}
+}
+
+// After an erase a lagging replica can still return the customer's data,
+// so orders that end up in the cache are read from the primary.
+func TestCacheFillSkipsReplicaReads(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		replica   bool
+		wantSaved int
+	}{
+		{name: "primary", wantSaved: 2},
+		{name: "replica", replica: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read := func(ctx context.Context) {
+				if tt.replica && !postgresql.ReadsPrimary(ctx) {
+					postgresql.ReadFromReplica(ctx)
+				}
+			}
+
+			saved := 0
+			orderServ := OrderService{
+				Rep: MockRepManager{
+					GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+						read(ctx)
+						return models.Order{OrderUID: orderID}, nil
+					},
+					GetOrdersByIDsFunc: func(ctx context.Context, orderIDs []string) ([]models.Order, error) {
+						read(ctx)
+						return []models.Order{{OrderUID: orderIDs[0]}}, nil
+					},
+				},
+				Cach: MockCacheManager{
+					GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+						return models.Order{}, errs.ErrOrderNotFound
+					},
+					GetOrdersByIDsFunc: func(ctx context.Context, orderIDs []string) (map[string]models.Order, error) {
+						return map[string]models.Order{}, nil
+					},
+					SaveOrderCacheFunc: func(ctx context.Context, order models.Order) error {
+						saved++
+						return nil
+					},
+					SaveOrdersCacheFunc: func(ctx context.Context, orders []models.Order) error {
+						saved++
+						return nil
+					},
+				},
+				log: zap.NewNop(),
+			}
+
+			order, err := orderServ.GetOrderByID(context.Background(), "1")
+			if err != nil || order.OrderUID != "1" {
+				t.Fatalf("unexpected result %+v, %v", order, err)
+			}
+			orders, _, err := orderServ.GetOrdersByIDs(context.Background(), []string{"2"})
+			if err != nil || len(orders) != 1 {
+				t.Fatalf("unexpected result %+v, %v", orders, err)
+			}
+
+			if saved != tt.wantSaved {
+				t.Errorf("expected %d cache writes, got %d", tt.wantSaved, saved)
+			}
+		})
+	}
+}
+
+func TestLoadCacheReadsOrdersFromPrimary(t *testing.T) {
+
+	var cached []string
+	orderServ := OrderService{
+		Rep: MockRepManager{
+			GetAllOrderIDFunc: func(ctx context.Context) ([]string, error) {
+				if postgresql.ReadsPrimary(ctx) {
+					t.Error("expected the order IDs to be listed on a replica")
+				}
+				return []string{"1", "gone"}, nil
+			},
+			GetOrderByIDFunc: func(ctx context.Context, orderID string) (models.Order, error) {
+				if !postgresql.ReadsPrimary(ctx) {
+					t.Errorf("expected order %s to be read from the primary", orderID)
+				}
+				if orderID == "gone" {
+					return models.Order{}, errs.ErrOrderNotFound
+				}
+				return models.Order{OrderUID: orderID}, nil
+			},
+		},
+		Cach: MockCacheManager{
+			SaveOrderCacheFunc: func(ctx context.Context, order models.Order) error {
+				cached = append(cached, order.OrderUID)
+				return nil
+			},
+		},
+		log: zap.NewNop(),
+	}
+
+	if err := orderServ.LoadCache(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cached) != 1 || cached[0] != "1" {
+		t.Errorf("expected only order 1 to be cached, got %v", cached)
+	}
+}
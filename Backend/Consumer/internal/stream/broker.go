@@ -0,0 +1,187 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+const (
+	defaultHistory      = 256
+	defaultClientBuffer = 64
+)
+
+// Summary is what the stream shows for an accepted order.
+type Summary struct {
+	OrderUID        string    `json:"order_uid"`
+	TrackNumber     string    `json:"track_number"`
+	CustomerID      string    `json:"customer_id"`
+	DeliveryService string    `json:"delivery_service"`
+	Amount          int       `json:"amount"`
+	Currency        string    `json:"currency"`
+	Items           int       `json:"items"`
+	DateCreated     string    `json:"date_created"`
+	AcceptedAt      time.Time `json:"accepted_at"`
+}
+
+// Message carries the full order for gRPC watchers; SSE clients only get
+// the summary.
+type Message struct {
+	ID      uint64
+	Summary Summary
+	Order   models.Order
+}
+
+// Subscription receives new messages on C. C is closed when the client falls
+// too far behind or the broker shuts down; the client reconnects with
+// Last-Event-ID and catches up from the history.
+type Subscription struct {
+	C chan Message
+}
+
+type Manager interface {
+	// Subscribe registers a client and returns the retained messages after
+	// lastID. With lastID 0 the backlog is empty. It reports false once the
+	// broker is closed.
+	Subscribe(lastID uint64) (*Subscription, []Message, bool)
+	Unsubscribe(sub *Subscription)
+}
+
+// Broker fans accepted orders out to stream clients. Notify never blocks, so
+// slow clients cannot hold up ingestion.
+type Broker struct {
+	mu           sync.Mutex
+	seq          uint64
+	history      []Message
+	next         int
+	clientBuffer int
+	subs         map[*Subscription]struct{}
+	closed       bool
+}
+
+func NewBroker(history, clientBuffer int) *Broker {
+	if history <= 0 {
+		history = defaultHistory
+	}
+	if clientBuffer <= 0 {
+		clientBuffer = defaultClientBuffer
+	}
+
+	return &Broker{
+		history:      make([]Message, 0, history),
+		clientBuffer: clientBuffer,
+		subs:         make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *Broker) Notify(e events.Event) {
+	if e.Type != events.OrderCreated {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	msg := Message{ID: b.seq, Summary: summarize(e), Order: e.Order}
+
+	if len(b.history) < cap(b.history) {
+		b.history = append(b.history, msg)
+	} else {
+		b.history[b.next] = msg
+		b.next = (b.next + 1) % cap(b.history)
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.C <- msg:
+		default:
+			delete(b.subs, sub)
+			close(sub.C)
+		}
+	}
+}
+
+func (b *Broker) Subscribe(lastID uint64) (*Subscription, []Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, false
+	}
+
+	var backlog []Message
+	if lastID > 0 {
+		for i := range b.history {
+			msg := b.history[(b.next+i)%len(b.history)]
+			if msg.ID > lastID {
+				backlog = append(backlog, msg)
+			}
+		}
+	}
+
+	sub := &Subscription{C: make(chan Message, b.clientBuffer)}
+	b.subs[sub] = struct{}{}
+
+	return sub, backlog, true
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.C)
+	}
+}
+
+// Redact clears the delivery data and customer of erased orders kept for
+// resume, so reconnecting clients do not receive them again.
+func (b *Broker) Redact(orderIDs []string, customerID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	erased := make(map[string]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		erased[id] = true
+	}
+
+	for i := range b.history {
+		msg := &b.history[i]
+		if erased[msg.Order.OrderUID] {
+			msg.Order.Delivery = models.Delivery{}
+			msg.Order.CustomerID = customerID
+			msg.Summary.CustomerID = customerID
+		}
+	}
+}
+
+// Close ends every stream. The HTTP server calls it on shutdown, otherwise
+// open streams would keep Shutdown waiting until its deadline.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.C)
+	}
+}
+
+func summarize(e events.Event) Summary {
+	return Summary{
+		OrderUID:        e.Order.OrderUID,
+		TrackNumber:     e.Order.TrackNumber,
+		CustomerID:      e.Order.CustomerID,
+		DeliveryService: e.Order.DeliveryService,
+		Amount:          e.Order.Payment.Amount,
+		Currency:        e.Order.Payment.Currency,
+		Items:           len(e.Order.Items),
+		DateCreated:     e.Order.DateCreated,
+		AcceptedAt:      e.Time,
+	}
+}
@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+func publish(b *Broker, uids ...string) {
+	for _, uid := range uids {
+		b.Notify(events.New(events.OrderCreated, models.Order{OrderUID: uid}))
+	}
+}
+
+func ids(msgs []Message) []uint64 {
+	var out []uint64
+	for _, m := range msgs {
+		out = append(out, m.ID)
+	}
+	return out
+}
+
+func TestBrokerBacklog(t *testing.T) {
+	b := NewBroker(3, 10)
+	publish(b, "a", "b", "c", "d", "e")
+
+	tests := []struct {
+		name   string
+		lastID uint64
+		want   []uint64
+	}{
+		{name: "new client", lastID: 0, want: nil},
+		{name: "resume", lastID: 3, want: []uint64{4, 5}},
+		{name: "older than history", lastID: 1, want: []uint64{3, 4, 5}},
+		{name: "up to date", lastID: 5, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, backlog, ok := b.Subscribe(tt.lastID)
+			if !ok {
+				t.Fatal("subscribe failed")
+			}
+			defer b.Unsubscribe(sub)
+
+			got := ids(backlog)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerSlowClient(t *testing.T) {
+	b := NewBroker(10, 2)
+
+	sub, _, _ := b.Subscribe(0)
+	publish(b, "a", "b", "c")
+
+	var got int
+	for range sub.C {
+		got++
+	}
+	if got != 2 {
+		t.Errorf("expected the buffered 2 messages before disconnect, got %d", got)
+	}
+
+	// Unsubscribing a dropped client must not panic.
+	b.Unsubscribe(sub)
+}
+
+func TestBrokerIgnoresOtherEvents(t *testing.T) {
+	b := NewBroker(10, 2)
+	sub, _, _ := b.Subscribe(0)
+
+	b.Notify(events.New(events.OrderStatusChanged, models.Order{OrderUID: "a"}))
+
+	select {
+	case msg := <-sub.C:
+		t.Errorf("unexpected message %+v", msg)
+	default:
+	}
+}
+
+func TestBrokerRedact(t *testing.T) {
+	b := NewBroker(3, 10)
+	b.Notify(events.New(events.OrderCreated, models.Order{OrderUID: "a", CustomerID: "alice", Delivery: models.Delivery{Name: "Alice"}}))
+	b.Notify(events.New(events.OrderCreated, models.Order{OrderUID: "b", CustomerID: "bob", Delivery: models.Delivery{Name: "Bob"}}))
+
+	b.Redact([]string{"a"}, "erased-1")
+
+	for _, msg := range b.history {
+		switch msg.Order.OrderUID {
+		case "a":
+			if msg.Order.Delivery.Name != "" || msg.Order.CustomerID != "erased-1" || msg.Summary.CustomerID != "erased-1" {
+				t.Errorf("order a is not redacted: %+v", msg)
+			}
+		case "b":
+			if msg.Order.Delivery.Name != "Bob" || msg.Summary.CustomerID != "bob" {
+				t.Errorf("order b must be kept: %+v", msg)
+			}
+		}
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker(10, 2)
+	sub, _, _ := b.Subscribe(0)
+
+	b.Close()
+
+	if _, ok := <-sub.C; ok {
+		t.Error("expected the subscription to be closed")
+	}
+	if _, _, ok := b.Subscribe(0); ok {
+		t.Error("expected subscribe to fail after close")
+	}
+}
@@ -0,0 +1,221 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWorkers      = 4
+	defaultQueueSize    = 1000
+	defaultMaxAttempts  = 5
+	defaultTimeout      = 5 * time.Second
+	defaultBackoff      = time.Second
+	defaultDisableAfter = 10
+	maxBackoff          = time.Minute
+)
+
+type Options struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	Timeout     time.Duration
+	// Backoff is the delay before the second attempt; it doubles after
+	// every failed attempt.
+	Backoff      time.Duration
+	DisableAfter int
+}
+
+// Dispatcher calls webhooks for order events. Events are queued without
+// blocking; when the queue is full they are dropped and logged.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	opts   Options
+	queue  chan events.Event
+	log    *zap.Logger
+}
+
+func NewDispatcher(store Store, opts Options, logg *zap.Logger) *Dispatcher {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.DisableAfter <= 0 {
+		opts.DisableAfter = defaultDisableAfter
+	}
+
+	return &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+		queue:  make(chan events.Event, opts.QueueSize),
+		log:    logg,
+	}
+}
+
+func (d *Dispatcher) Notify(e events.Event) {
+	select {
+	case d.queue <- e:
+	default:
+		d.log.Warn("webhook queue is full, event dropped", zap.String("event_id", e.ID), zap.String("type", e.Type))
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) error {
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e := <-d.queue:
+					d.dispatch(ctx, e)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, e events.Event) {
+
+	subs, err := d.store.WebhooksFor(ctx, e.Type)
+	if err != nil {
+		d.log.Error("cannot get webhooks", zap.String("type", e.Type), zap.Error(err))
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		d.log.Error("cannot marshal webhook payload", zap.Error(err))
+		return
+	}
+
+	for _, sub := range subs {
+		d.deliver(ctx, sub, e, body)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, sub Subscription, e events.Event, body []byte) {
+
+	backoff := d.opts.Backoff
+
+	for attempt := 1; attempt <= d.opts.MaxAttempts; attempt++ {
+		status, err := d.send(ctx, sub, e, body, attempt)
+		if err == nil {
+			if sub.FailureCount > 0 {
+				if err := d.store.RecordSuccess(ctx, sub.ID); err != nil {
+					d.log.Error("cannot reset webhook failures", zap.Int64("webhook_id", sub.ID), zap.Error(err))
+				}
+			}
+			return
+		}
+
+		d.log.Warn("webhook delivery failed", zap.Int64("webhook_id", sub.ID), zap.String("event_id", e.ID),
+			zap.Int("attempt", attempt), zap.Int("status", status), zap.Error(err))
+
+		if attempt == d.opts.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+
+	disabled, err := d.store.RecordFailure(ctx, sub.ID, d.opts.DisableAfter)
+	if err != nil {
+		d.log.Error("cannot record webhook failure", zap.Int64("webhook_id", sub.ID), zap.Error(err))
+		return
+	}
+	if disabled {
+		d.log.Warn("webhook disabled after repeated failures", zap.Int64("webhook_id", sub.ID), zap.String("url", sub.URL))
+	}
+}
+
+// send makes one attempt and writes it to the delivery log.
+func (d *Dispatcher) send(ctx context.Context, sub Subscription, e events.Event, body []byte, attempt int) (int, error) {
+
+	start := time.Now()
+	status, err := d.post(ctx, sub, e, body)
+
+	entry := Delivery{
+		WebhookID:   sub.ID,
+		EventID:     e.ID,
+		EventType:   e.Type,
+		Attempt:     attempt,
+		StatusCode:  status,
+		DurationMS:  time.Since(start).Milliseconds(),
+		DeliveredAt: start,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := d.store.LogDelivery(ctx, entry); logErr != nil {
+		d.log.Error("cannot log webhook delivery", zap.Int64("webhook_id", sub.ID), zap.Error(logErr))
+	}
+
+	return status, err
+}
+
+func (d *Dispatcher) post(ctx context.Context, sub Subscription, e events.Event, body []byte) (int, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("cannot build request err:%w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, e.Type)
+	req.Header.Set(HeaderEventID, e.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
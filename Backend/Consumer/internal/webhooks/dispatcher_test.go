@@ -0,0 +1,176 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+	"github.com/LootNex/OrderService/Contract/models"
+	"go.uber.org/zap"
+)
+
+// MockStore keeps webhooks in memory and records the delivery log.
+type MockStore struct {
+	Store
+
+	mu         sync.Mutex
+	subs       []Subscription
+	deliveries []Delivery
+	failures   map[int64]int
+	successes  int
+}
+
+func (ms *MockStore) WebhooksFor(ctx context.Context, eventType string) ([]Subscription, error) {
+	return ms.subs, nil
+}
+
+func (ms *MockStore) LogDelivery(ctx context.Context, d Delivery) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.deliveries = append(ms.deliveries, d)
+	return nil
+}
+
+func (ms *MockStore) RecordFailure(ctx context.Context, id int64, disableAfter int) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.failures[id]++
+	return ms.failures[id] >= disableAfter, nil
+}
+
+func (ms *MockStore) RecordSuccess(ctx context.Context, id int64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.successes++
+	return nil
+}
+
+func TestDispatcherDeliver(t *testing.T) {
+	tests := []struct {
+		name         string
+		failFirst    int
+		failureCount int
+		wantAttempts int
+		wantFailures int
+		wantSuccess  int
+	}{
+		{
+			name:         "delivered first time",
+			wantAttempts: 1,
+		},
+		{
+			name:         "delivered after retries",
+			failFirst:    2,
+			failureCount: 3,
+			wantAttempts: 3,
+			wantSuccess:  1,
+		},
+		{
+			name:         "out of attempts",
+			failFirst:    10,
+			wantAttempts: 3,
+			wantFailures: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const secret = "s3cr3t"
+			calls := 0
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				body, _ := io.ReadAll(r.Body)
+				ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+				if r.Header.Get(HeaderSignature) != Sign(secret, ts, body) {
+					t.Error("invalid signature")
+				}
+				if r.Header.Get(HeaderEvent) != events.OrderCreated {
+					t.Errorf("unexpected event header %q", r.Header.Get(HeaderEvent))
+				}
+				if calls <= tt.failFirst {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer srv.Close()
+
+			store := &MockStore{
+				subs:     []Subscription{{ID: 1, URL: srv.URL, Secret: secret, Active: true, FailureCount: tt.failureCount}},
+				failures: map[int64]int{},
+			}
+			d := NewDispatcher(store, Options{MaxAttempts: 3, Backoff: time.Millisecond}, zap.NewNop())
+
+			d.dispatch(context.Background(), events.New(events.OrderCreated, models.Order{OrderUID: "123"}))
+
+			if len(store.deliveries) != tt.wantAttempts {
+				t.Errorf("expected %d logged attempts, got %d", tt.wantAttempts, len(store.deliveries))
+			}
+			if store.failures[1] != tt.wantFailures {
+				t.Errorf("expected %d failures, got %d", tt.wantFailures, store.failures[1])
+			}
+			if store.successes != tt.wantSuccess {
+				t.Errorf("expected %d failure resets, got %d", tt.wantSuccess, store.successes)
+			}
+		})
+	}
+}
+
+func TestNotifyDoesNotBlock(t *testing.T) {
+	d := NewDispatcher(&MockStore{}, Options{QueueSize: 1}, zap.NewNop())
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			d.Notify(events.New(events.OrderCreated, models.Order{}))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on a full queue")
+	}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     Subscription
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			sub:  Subscription{URL: "https://example.com/hook", EventTypes: []string{events.OrderCreated}, Secret: "s"},
+		},
+		{
+			name:    "relative url",
+			sub:     Subscription{URL: "/hook", EventTypes: []string{events.OrderCreated}, Secret: "s"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown event",
+			sub:     Subscription{URL: "https://example.com/hook", EventTypes: []string{"order.deleted"}, Secret: "s"},
+			wantErr: true,
+		},
+		{
+			name:    "no events",
+			sub:     Subscription{URL: "https://example.com/hook", Secret: "s"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sub.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/LootNex/OrderService/Consumer/internal/events"
+)
+
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-ID"
+)
+
+type Subscription struct {
+	ID           int64      `json:"id"`
+	URL          string     `json:"url"`
+	EventTypes   []string   `json:"event_types"`
+	Secret       string     `json:"secret,omitempty"`
+	Active       bool       `json:"active"`
+	FailureCount int        `json:"failure_count"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+}
+
+// Delivery is one attempt to call a webhook.
+type Delivery struct {
+	ID          int64     `json:"id"`
+	WebhookID   int64     `json:"webhook_id"`
+	EventID     string    `json:"event_id"`
+	EventType   string    `json:"event_type"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMS  int64     `json:"duration_ms"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+type Store interface {
+	CreateWebhook(ctx context.Context, sub Subscription) (Subscription, error)
+	GetWebhook(ctx context.Context, id int64) (Subscription, error)
+	ListWebhooks(ctx context.Context) ([]Subscription, error)
+	UpdateWebhook(ctx context.Context, sub Subscription) (Subscription, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	// WebhooksFor returns the active subscriptions to eventType.
+	WebhooksFor(ctx context.Context, eventType string) ([]Subscription, error)
+	LogDelivery(ctx context.Context, d Delivery) error
+	ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]Delivery, error)
+	// RecordFailure counts a delivery that ran out of attempts and disables
+	// the webhook after disableAfter failures in a row.
+	RecordFailure(ctx context.Context, id int64, disableAfter int) (disabled bool, err error)
+	RecordSuccess(ctx context.Context, id int64) error
+}
+
+func (s Subscription) Validate() error {
+
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	if len(s.EventTypes) == 0 {
+		return errors.New("event_types is required")
+	}
+	for _, t := range s.EventTypes {
+		if !events.Known(t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+
+	if s.Secret == "" {
+		return errors.New("secret is required")
+	}
+
+	return nil
+}
+
+func NewSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Sign returns the value of the signature header. Receivers recompute it
+// over "<timestamp>.<body>" with the shared secret and reject stale
+// timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
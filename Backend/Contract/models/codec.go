@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/LootNex/OrderService/Contract/models/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types carried in the content-type header of Kafka messages.
+const (
+	HeaderContentType   = "content-type"
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// ContentTypeFor maps an encoding name from a config file to its content
+// type. An empty encoding means JSON.
+func ContentTypeFor(encoding string) (string, error) {
+	switch encoding {
+	case "", "json":
+		return ContentTypeJSON, nil
+	case "protobuf", "proto":
+		return ContentTypeProtobuf, nil
+	default:
+		return "", fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+func Marshal(order Order, contentType string) ([]byte, error) {
+	switch contentType {
+	case ContentTypeJSON:
+		msg, err := json.Marshal(order)
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal order %w", err)
+		}
+		return msg, nil
+	case ContentTypeProtobuf:
+		msg, err := proto.Marshal(order.ToProto())
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal order to protobuf %w", err)
+		}
+		return msg, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+}
+
+func Unmarshal(data []byte, contentType string) (Order, error) {
+
+	var order Order
+
+	switch contentType {
+	case ContentTypeJSON:
+		if err := json.Unmarshal(data, &order); err != nil {
+			return order, fmt.Errorf("cannot unmarshal json order err:%w", err)
+		}
+	case ContentTypeProtobuf:
+		var p pb.Order
+		if err := proto.Unmarshal(data, &p); err != nil {
+			return order, fmt.Errorf("cannot unmarshal protobuf order err:%w", err)
+		}
+		order = OrderFromProto(&p)
+	default:
+		return order, fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	return order, nil
+}
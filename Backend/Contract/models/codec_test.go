@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func sampleOrder(t *testing.T) Order {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/order_v1.json")
+	if err != nil {
+		t.Fatalf("cannot read sample: %v", err)
+	}
+
+	var order Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		t.Fatalf("cannot unmarshal sample: %v", err)
+	}
+	return order
+}
+
+func TestContentTypeFor(t *testing.T) {
+	tests := []struct {
+		encoding string
+		want     string
+		wantErr  bool
+	}{
+		{encoding: "", want: ContentTypeJSON},
+		{encoding: "json", want: ContentTypeJSON},
+		{encoding: "protobuf", want: ContentTypeProtobuf},
+		{encoding: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ContentTypeFor(tt.encoding)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("encoding %q: expected err:%v, got err:%v", tt.encoding, tt.wantErr, err)
+		}
+		if got != tt.want {
+			t.Errorf("encoding %q: expected %q, got %q", tt.encoding, tt.want, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	order := sampleOrder(t)
+
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeProtobuf} {
+		t.Run(contentType, func(t *testing.T) {
+			data, err := Marshal(order, contentType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := Unmarshal(data, contentType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, order) {
+				t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, order)
+			}
+		})
+	}
+}
+
+func TestProtoMatchesJSON(t *testing.T) {
+	order := sampleOrder(t)
+	order.Items = append(order.Items, Item{ChrtID: 1, TrackNumber: "T1", Name: "second"})
+
+	if got := OrderFromProto(order.ToProto()); !reflect.DeepEqual(got, order) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, order)
+	}
+
+	empty := Order{}
+	if got := OrderFromProto(empty.ToProto()); !reflect.DeepEqual(got, empty) {
+		t.Errorf("empty order round trip mismatch: got %+v", got)
+	}
+}
+
+func TestUnmarshalUnknownContentType(t *testing.T) {
+	if _, err := Unmarshal([]byte("{}"), "text/xml"); err == nil {
+		t.Error("expected error for unknown content type")
+	}
+}
@@ -0,0 +1,63 @@
+// Package models is the order contract shared by the Producer and the
+// Consumer. Any change here must keep TestSchemaCompatibility green:
+// fields may be added, but never removed, retyped or made required.
+package models
+
+// SchemaVersion is bumped together with testdata/order.schema.json.
+const SchemaVersion = "1.0.0"
+
+type Order struct {
+	OrderUID          string   `json:"order_uid" schema:"required"`
+	TrackNumber       string   `json:"track_number" schema:"required"`
+	Entry             string   `json:"entry"`
+	Delivery          Delivery `json:"delivery" schema:"required"`
+	Payment           Payment  `json:"payment" schema:"required"`
+	Items             []Item   `json:"items" schema:"required"`
+	Locale            string   `json:"locale"`
+	InternalSignature string   `json:"internal_signature"`
+	CustomerID        string   `json:"customer_id" schema:"required"`
+	DeliveryService   string   `json:"delivery_service" schema:"required"`
+	ShardKey          string   `json:"shardkey"`
+	SmID              int      `json:"sm_id"`
+	DateCreated       string   `json:"date_created" schema:"required,format=date-time"`
+	OofShard          string   `json:"oof_shard"`
+}
+
+type Delivery struct {
+	// DeliveryID is assigned by the Consumer's storage and is never sent by producers.
+	DeliveryID string `json:"delivery_id,omitempty"`
+	Name       string `json:"name" schema:"required"`
+	Phone      string `json:"phone" schema:"required"`
+	Zip        string `json:"zip"`
+	City       string `json:"city"`
+	Address    string `json:"address"`
+	Region     string `json:"region"`
+	Email      string `json:"email" schema:"required,format=email"`
+}
+
+type Payment struct {
+	Transaction  string `json:"transaction" schema:"required"`
+	RequestID    string `json:"request_id"`
+	Currency     string `json:"currency" schema:"required"`
+	Provider     string `json:"provider"`
+	Amount       int    `json:"amount" schema:"required"`
+	PaymentDT    int64  `json:"payment_dt"`
+	Bank         string `json:"bank"`
+	DeliveryCost int    `json:"delivery_cost"`
+	GoodsTotal   int    `json:"goods_total"`
+	CustomFee    int    `json:"custom_fee"`
+}
+
+type Item struct {
+	ChrtID      int    `json:"chrt_id" schema:"required"`
+	TrackNumber string `json:"track_number" schema:"required"`
+	Price       int    `json:"price"`
+	RID         string `json:"rid"`
+	Name        string `json:"name"`
+	Sale        int    `json:"sale"`
+	Size        string `json:"size"`
+	TotalPrice  int    `json:"total_price"`
+	NmID        int    `json:"nm_id"`
+	Brand       string `json:"brand"`
+	Status      int    `json:"status"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import "github.com/LootNex/OrderService/Contract/models/pb"
+
+func (o *Order) ToProto() *pb.Order {
+	items := make([]*pb.Item, 0, len(o.Items))
+	for _, item := range o.Items {
+		items = append(items, &pb.Item{
+			ChrtId:      int64(item.ChrtID),
+			TrackNumber: item.TrackNumber,
+			Price:       int64(item.Price),
+			Rid:         item.RID,
+			Name:        item.Name,
+			Sale:        int64(item.Sale),
+			Size:        item.Size,
+			TotalPrice:  int64(item.TotalPrice),
+			NmId:        int64(item.NmID),
+			Brand:       item.Brand,
+			Status:      int64(item.Status),
+		})
+	}
+
+	return &pb.Order{
+		OrderUid:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &pb.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &pb.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDT,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.ShardKey,
+		SmId:              int64(o.SmID),
+		DateCreated:       o.DateCreated,
+		OofShard:          o.OofShard,
+	}
+}
+
+func OrderFromProto(p *pb.Order) Order {
+	order := Order{
+		OrderUID:          p.GetOrderUid(),
+		TrackNumber:       p.GetTrackNumber(),
+		Entry:             p.GetEntry(),
+		Locale:            p.GetLocale(),
+		InternalSignature: p.GetInternalSignature(),
+		CustomerID:        p.GetCustomerId(),
+		DeliveryService:   p.GetDeliveryService(),
+		ShardKey:          p.GetShardkey(),
+		SmID:              int(p.GetSmId()),
+		DateCreated:       p.GetDateCreated(),
+		OofShard:          p.GetOofShard(),
+		Delivery: Delivery{
+			Name:    p.GetDelivery().GetName(),
+			Phone:   p.GetDelivery().GetPhone(),
+			Zip:     p.GetDelivery().GetZip(),
+			City:    p.GetDelivery().GetCity(),
+			Address: p.GetDelivery().GetAddress(),
+			Region:  p.GetDelivery().GetRegion(),
+			Email:   p.GetDelivery().GetEmail(),
+		},
+		Payment: Payment{
+			Transaction:  p.GetPayment().GetTransaction(),
+			RequestID:    p.GetPayment().GetRequestId(),
+			Currency:     p.GetPayment().GetCurrency(),
+			Provider:     p.GetPayment().GetProvider(),
+			Amount:       int(p.GetPayment().GetAmount()),
+			PaymentDT:    p.GetPayment().GetPaymentDt(),
+			Bank:         p.GetPayment().GetBank(),
+			DeliveryCost: int(p.GetPayment().GetDeliveryCost()),
+			GoodsTotal:   int(p.GetPayment().GetGoodsTotal()),
+			CustomFee:    int(p.GetPayment().GetCustomFee()),
+		},
+	}
+
+	for _, item := range p.GetItems() {
+		order.Items = append(order.Items, Item{
+			ChrtID:      int(item.GetChrtId()),
+			TrackNumber: item.GetTrackNumber(),
+			Price:       int(item.GetPrice()),
+			RID:         item.GetRid(),
+			Name:        item.GetName(),
+			Sale:        int(item.GetSale()),
+			Size:        item.GetSize(),
+			TotalPrice:  int(item.GetTotalPrice()),
+			NmID:        int(item.GetNmId()),
+			Brand:       item.GetBrand(),
+			Status:      int(item.GetStatus()),
+		})
+	}
+
+	return order
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema describes Order as a JSON Schema document. Property names come
+// from the json tags, required fields and formats from the schema tags.
+func Schema() map[string]any {
+	schema := typeSchema(reflect.TypeOf(Order{}))
+	schema["$schema"] = schemaDialect
+	schema["$id"] = "https://github.com/LootNex/OrderService/Contract/order/" + SchemaVersion
+	schema["title"] = "Order"
+	return schema
+}
+
+func JSONSchema() ([]byte, error) {
+	return json.MarshalIndent(Schema(), "", "  ")
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			prop := typeSchema(field.Type)
+			for _, opt := range strings.Split(field.Tag.Get("schema"), ",") {
+				switch {
+				case opt == "required":
+					required = append(required, name)
+				case strings.HasPrefix(opt, "format="):
+					prop["format"] = strings.TrimPrefix(opt, "format=")
+				}
+			}
+			properties[name] = prop
+		}
+
+		return map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	default:
+		panic("models: no json schema mapping for " + t.String())
+	}
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/order.schema.json from the current types")
+
+const goldenSchema = "testdata/order.schema.json"
+
+// TestSchemaCompatibility compares the current schema with the published
+// one. Older consumers break if a field disappears or changes type, and
+// messages from older producers are rejected if a field becomes required.
+// Additive changes pass; run `go test -run TestSchema -update` and bump
+// SchemaVersion to publish them.
+func TestSchemaCompatibility(t *testing.T) {
+	current, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("cannot build schema: %v", err)
+	}
+
+	if *update {
+		if err := os.WriteFile(goldenSchema, append(current, '\n'), 0o644); err != nil {
+			t.Fatalf("cannot write golden schema: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(goldenSchema)
+	if err != nil {
+		t.Fatalf("cannot read golden schema: %v", err)
+	}
+
+	var published, actual map[string]any
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatalf("cannot unmarshal golden schema: %v", err)
+	}
+	if err := json.Unmarshal(current, &actual); err != nil {
+		t.Fatalf("cannot unmarshal current schema: %v", err)
+	}
+
+	for _, problem := range compareSchemas("order", published, actual) {
+		t.Error(problem)
+	}
+}
+
+func TestPublishedSamplesStillValid(t *testing.T) {
+	files, err := filepath.Glob("testdata/order_v*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no samples found err:%v", err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("cannot read sample: %v", err)
+			}
+
+			order, err := Unmarshal(data, ContentTypeJSON)
+			if err != nil {
+				t.Fatalf("cannot decode sample: %v", err)
+			}
+			if err := order.Validate(); err != nil {
+				t.Errorf("sample no longer validates: %v", err)
+			}
+		})
+	}
+}
+
+func compareSchemas(path string, old, cur map[string]any) []string {
+	var problems []string
+
+	if old["type"] != cur["type"] {
+		return append(problems, path+": type changed from "+toString(old["type"])+" to "+toString(cur["type"]))
+	}
+	if old["format"] != cur["format"] {
+		problems = append(problems, path+": format changed from "+toString(old["format"])+" to "+toString(cur["format"]))
+	}
+
+	switch old["type"] {
+	case "array":
+		problems = append(problems, compareSchemas(path+"[]", asMap(old["items"]), asMap(cur["items"]))...)
+	case "object":
+		oldProps, curProps := asMap(old["properties"]), asMap(cur["properties"])
+		for name, prop := range oldProps {
+			curProp, ok := curProps[name]
+			if !ok {
+				problems = append(problems, path+"."+name+": field removed")
+				continue
+			}
+			problems = append(problems, compareSchemas(path+"."+name, asMap(prop), asMap(curProp))...)
+		}
+
+		oldRequired := asStrings(old["required"])
+		for _, name := range asStrings(cur["required"]) {
+			if !slices.Contains(oldRequired, name) {
+				problems = append(problems, path+"."+name+": field became required")
+			}
+		}
+	}
+
+	return problems
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func asStrings(v any) []string {
+	list, _ := v.([]any)
+	res := make([]string, 0, len(list))
+	for _, s := range list {
+		res = append(res, toString(s))
+	}
+	return res
+}
+
+func toString(v any) string {
+	s, _ := v.(string)
+	return s
+}
@@ -1,14 +1,62 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
 
+	"github.com/LootNex/OrderService/Producer/internal/kafka"
+	"github.com/LootNex/OrderService/Producer/internal/loadgen"
+	"github.com/LootNex/OrderService/Producer/internal/replay"
 	"github.com/LootNex/OrderService/Producer/internal/server"
 )
 
 func main() {
-	if err := server.StartServer(); err != nil {
+	mode, err := parseMode(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("invalid arguments err:%v\n", err)
+		os.Exit(2)
+	}
+
+	if err := server.StartServer(mode); err != nil {
 		fmt.Printf("cannot start Producer server err:%v", err)
 	}
 
 }
+
+// parseMode picks the subcommand. Without one the Producer generates load,
+// which keeps the container's default command working.
+func parseMode(args []string) (server.Mode, error) {
+
+	if len(args) > 0 && args[0] == "replay" {
+		opts, err := replay.ParseFlags(args[1:], os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(ctx context.Context, producer kafka.KafkaManager) error {
+			report, err := replay.Run(ctx, producer, opts)
+			report.Print(os.Stdout)
+			return err
+		}, nil
+	}
+
+	if len(args) > 0 && args[0] == "generate" {
+		args = args[1:]
+	}
+
+	opts, err := loadgen.ParseFlags(args, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, producer kafka.KafkaManager) error {
+		loadgen.Run(ctx, producer, opts).Print(os.Stdout)
+		return nil
+	}, nil
+}
@@ -10,6 +10,10 @@ type Config struct {
 	Kafka struct {
 		Brokers []string
 		Topic   string
+		// Encoding is either "json" (default) or "protobuf".
+		Encoding          string
+		Partitions        int
+		ReplicationFactor int
 	}
 }
 
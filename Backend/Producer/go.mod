@@ -2,7 +2,10 @@ module github.com/LootNex/OrderService/Producer
 
 go 1.24.3
 
-require github.com/segmentio/kafka-go v0.4.48
+require (
+	github.com/segmentio/kafka-go v0.4.48
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/fsnotify/fsnotify v1.8.0 // indirect
@@ -16,14 +19,17 @@ require (
 	github.com/subosito/gotenv v1.6.0 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
-	golang.org/x/sys v0.29.0 // indirect
-	golang.org/x/text v0.21.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
 )
 
 require (
+	github.com/LootNex/OrderService/Contract v0.0.0-00010101000000-000000000000
 	github.com/brianvoe/gofakeit/v7 v7.5.1
 	github.com/klauspost/compress v1.15.9 // indirect
 	github.com/pierrec/lz4/v4 v4.1.15 // indirect
 	github.com/spf13/viper v1.20.1
 )
+
+replace github.com/LootNex/OrderService/Contract => ../Contract
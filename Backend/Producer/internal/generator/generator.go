@@ -0,0 +1,285 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Profile describes the shape of generated data. Empty distributions fall
+// back to the historical defaults: random currency and delivery service,
+// locale "en", provider "wbpay" and a single status 202.
+type Profile struct {
+	Items            Weighted[int]
+	Currencies       Weighted[string]
+	Locales          Weighted[string]
+	DeliveryServices Weighted[string]
+	Providers        Weighted[string]
+	// Lifecycles are the item status sequences an order goes through. The
+	// first status is sent with the order, each next one as an update.
+	Lifecycles Weighted[[]int]
+	// EventGap is the number of messages between two events of one order.
+	EventGap int
+	// CustomerReuse is the probability that an order comes from a customer
+	// who has already ordered. CustomerPool caps the number of distinct
+	// customers, 0 means no cap.
+	CustomerReuse float64
+	CustomerPool  int
+	// StartTime and TimeStep set date_created of the n-th order to
+	// StartTime + n*TimeStep. A zero StartTime uses the wall clock, unless
+	// the generator is seeded: then it is derived from the seed.
+	StartTime time.Time
+	TimeStep  time.Duration
+}
+
+func DefaultProfile() Profile {
+	return Profile{
+		Items:    Fixed(1),
+		EventGap: 1,
+	}
+}
+
+// maxCustomers bounds the remembered customers when CustomerPool is not set.
+const maxCustomers = 10000
+
+type customer struct {
+	id       string
+	delivery models.Delivery
+}
+
+type event struct {
+	due   int
+	order models.Order
+}
+
+// Generator builds random orders. All randomness comes from one seeded
+// source, so the same seed and profile yield the same sequence of orders
+// as long as the generator is used from a single goroutine.
+type Generator struct {
+	faker     *gofakeit.Faker
+	profile   Profile
+	customers []customer
+	pending   []event
+	messages  int
+	orders    int
+}
+
+// seededEpoch is where the dates of a seeded run without a start time
+// begin; the seed picks a day in the year after it.
+var seededEpoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// New returns a generator for the seed. A zero seed picks a random one.
+func New(seed uint64, profile Profile) *Generator {
+	if profile.Items.Empty() {
+		profile.Items = Fixed(1)
+	}
+	if profile.EventGap < 1 {
+		profile.EventGap = 1
+	}
+	// A seeded run must not depend on the wall clock, or the same seed
+	// would yield different dates and payment times on every run.
+	if seed != 0 && profile.StartTime.IsZero() {
+		profile.StartTime = seededEpoch.Add(time.Duration(seed%365) * 24 * time.Hour)
+		if profile.TimeStep == 0 {
+			profile.TimeStep = time.Second
+		}
+	}
+
+	return &Generator{
+		faker:   gofakeit.New(seed),
+		profile: profile,
+	}
+}
+
+// Chance reports true with probability p.
+func (g *Generator) Chance(p float64) bool {
+	return p > 0 && g.faker.Float64() < p
+}
+
+// Float64 returns a number in [0, 1).
+func (g *Generator) Float64() float64 {
+	return g.faker.Float64()
+}
+
+// Intn returns a number in [0, n).
+func (g *Generator) Intn(n int) int {
+	return g.faker.IntN(n)
+}
+
+// Update returns the next lifecycle event that is due, if any. Callers
+// should ask for it before generating a new order.
+func (g *Generator) Update() (models.Order, bool) {
+	if len(g.pending) == 0 || g.pending[0].due > g.messages {
+		return models.Order{}, false
+	}
+
+	e := g.pending[0]
+	g.pending = g.pending[1:]
+	g.messages++
+	return e.order, true
+}
+
+// Order returns a new order and schedules its lifecycle events.
+func (g *Generator) Order() models.Order {
+
+	order := g.build()
+	g.messages++
+
+	statuses := []int{202}
+	if !g.profile.Lifecycles.Empty() {
+		statuses = g.profile.Lifecycles.Sample(g)
+	}
+
+	setStatus(&order, statuses[0])
+	for i, status := range statuses[1:] {
+		update := order
+		update.Items = append([]models.Item(nil), order.Items...)
+		setStatus(&update, status)
+		g.schedule(event{due: g.messages + (i+1)*g.profile.EventGap - 1, order: update})
+	}
+
+	return order
+}
+
+// Invalid returns a new order that breaks one of the rules checked by the
+// Consumer's validation. No lifecycle events are scheduled for it.
+func (g *Generator) Invalid() models.Order {
+
+	order := g.build()
+	g.messages++
+	setStatus(&order, 202)
+
+	switch g.faker.IntN(5) {
+	case 0:
+		order.OrderUID = ""
+	case 1:
+		order.DateCreated = "yesterday"
+	case 2:
+		order.Delivery.Email = "not-an-email"
+	case 3:
+		order.Payment.Amount = 0
+	default:
+		order.Items = nil
+	}
+
+	return order
+}
+
+// Duplicate marks that the caller resent an earlier order, so lifecycle
+// events keep their spacing in the message stream.
+func (g *Generator) Duplicate() {
+	g.messages++
+}
+
+func (g *Generator) schedule(e event) {
+	i := sort.Search(len(g.pending), func(i int) bool { return g.pending[i].due > e.due })
+	g.pending = append(g.pending, event{})
+	copy(g.pending[i+1:], g.pending[i:])
+	g.pending[i] = e
+}
+
+func (g *Generator) build() models.Order {
+
+	created := time.Now()
+	if !g.profile.StartTime.IsZero() {
+		created = g.profile.StartTime.Add(time.Duration(g.orders) * g.profile.TimeStep)
+	}
+	g.orders++
+
+	cust := g.customer()
+	trackNumber := g.faker.Regex("[A-Z0-9]{10}")
+
+	order := models.Order{
+		OrderUID:          g.faker.UUID(),
+		TrackNumber:       trackNumber,
+		Entry:             "WBIL",
+		Delivery:          cust.delivery,
+		Locale:            g.pick(g.profile.Locales, func() string { return "en" }),
+		InternalSignature: "",
+		CustomerID:        cust.id,
+		DeliveryService:   g.pick(g.profile.DeliveryServices, g.faker.Company),
+		ShardKey:          fmt.Sprintf("%d", g.faker.Number(1, 10)),
+		SmID:              g.faker.Number(1, 1000),
+		DateCreated:       created.Format(time.RFC3339),
+		OofShard:          fmt.Sprintf("%d", g.faker.Number(1, 5)),
+	}
+
+	goodsTotal := 0
+	for i, n := 0, g.profile.Items.Sample(g); i < n; i++ {
+		price := g.faker.Number(100, 1000)
+		sale := g.faker.Number(0, 50)
+		item := models.Item{
+			ChrtID:      g.faker.Number(1000000, 9999999),
+			TrackNumber: trackNumber,
+			Price:       price,
+			RID:         g.faker.UUID(),
+			Name:        g.faker.ProductName(),
+			Sale:        sale,
+			Size:        g.faker.RandomString([]string{"S", "M", "L"}),
+			TotalPrice:  price * (100 - sale) / 100,
+			NmID:        g.faker.Number(100000, 999999),
+			Brand:       g.faker.Company(),
+		}
+		goodsTotal += item.TotalPrice
+		order.Items = append(order.Items, item)
+	}
+
+	deliveryCost := g.faker.Number(100, 2000)
+	order.Payment = models.Payment{
+		Transaction:  g.faker.UUID(),
+		Currency:     g.pick(g.profile.Currencies, g.faker.CurrencyShort),
+		Provider:     g.pick(g.profile.Providers, func() string { return "wbpay" }),
+		Amount:       goodsTotal + deliveryCost,
+		PaymentDT:    created.Unix(),
+		Bank:         g.faker.Company(),
+		DeliveryCost: deliveryCost,
+		GoodsTotal:   goodsTotal,
+		CustomFee:    0,
+	}
+
+	return order
+}
+
+func (g *Generator) customer() customer {
+
+	canReuse := len(g.customers) > 0 &&
+		(g.Chance(g.profile.CustomerReuse) || (g.profile.CustomerPool > 0 && len(g.customers) >= g.profile.CustomerPool))
+	if canReuse {
+		return g.customers[g.faker.IntN(len(g.customers))]
+	}
+
+	c := customer{
+		id: g.faker.Username(),
+		delivery: models.Delivery{
+			Name:    g.faker.Name(),
+			Phone:   g.faker.Phone(),
+			Zip:     g.faker.Zip(),
+			City:    g.faker.City(),
+			Address: g.faker.Street(),
+			Region:  g.faker.State(),
+			Email:   g.faker.Email(),
+		},
+	}
+	if len(g.customers) < maxCustomers {
+		g.customers = append(g.customers, c)
+	} else {
+		g.customers[g.faker.IntN(len(g.customers))] = c
+	}
+	return c
+}
+
+func (g *Generator) pick(w Weighted[string], fallback func() string) string {
+	if w.Empty() {
+		return fallback()
+	}
+	return w.Sample(g)
+}
+
+func setStatus(order *models.Order, status int) {
+	for i := range order.Items {
+		order.Items[i].Status = status
+	}
+}
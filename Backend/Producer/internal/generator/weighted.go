@@ -0,0 +1,41 @@
+package generator
+
+// Weighted is a discrete distribution over a fixed set of values.
+type Weighted[T any] struct {
+	values  []T
+	weights []float64
+	total   float64
+}
+
+func Fixed[T any](value T) Weighted[T] {
+	var w Weighted[T]
+	w.Add(value, 1)
+	return w
+}
+
+// Add appends a value. Values with zero weight are never sampled.
+func (w *Weighted[T]) Add(value T, weight float64) {
+	w.values = append(w.values, value)
+	w.weights = append(w.weights, weight)
+	w.total += weight
+}
+
+// Empty reports whether nothing can be sampled.
+func (w Weighted[T]) Empty() bool {
+	return w.total <= 0
+}
+
+func (w Weighted[T]) Sample(g *Generator) T {
+	if len(w.values) == 1 {
+		return w.values[0]
+	}
+
+	x := g.Float64() * w.total
+	for i, weight := range w.weights {
+		if x < weight {
+			return w.values[i]
+		}
+		x -= weight
+	}
+	return w.values[len(w.values)-1]
+}
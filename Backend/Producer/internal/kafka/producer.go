@@ -2,34 +2,48 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"time"
 
-	"github.com/LootNex/OrderService/Producer/internal/models"
+	"github.com/LootNex/OrderService/Contract/models"
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/compress"
 )
 
+// batchTimeout bounds how long a write waits for its batch to fill. Send is
+// synchronous, so with the default of one second each sender would get out
+// only about one message per second.
+const batchTimeout = 10 * time.Millisecond
+
 type KafkaProducer struct {
-	Writer *kafka.Writer
+	Writer      *kafka.Writer
+	ContentType string
 }
 
 type KafkaManager interface {
 	Send(order models.Order) error
 }
 
-func NewKafkaProducer(brokers []string, topic string) *KafkaProducer {
+func NewKafkaProducer(brokers []string, topic, encoding string) (*KafkaProducer, error) {
+
+	contentType, err := models.ContentTypeFor(encoding)
+	if err != nil {
+		return nil, err
+	}
+
 	return &KafkaProducer{
 		Writer: kafka.NewWriter(kafka.WriterConfig{
 			Brokers:          brokers,
 			Topic:            topic,
-			Balancer:         &kafka.LeastBytes{},
+			Balancer:         &kafka.Hash{},
 			RequiredAcks:     int(kafka.RequireAll),
 			CompressionCodec: &compress.SnappyCodec,
 			BatchSize:        100,
+			BatchTimeout:     batchTimeout,
 			Async:            false,
 		}),
-	}
+		ContentType: contentType,
+	}, nil
 }
 
 func EnsureTopic(broker, topic string, partitions, replication int) error {
@@ -52,13 +66,17 @@ func EnsureTopic(broker, topic string, partitions, replication int) error {
 
 func (k KafkaProducer) Send(order models.Order) error {
 
-	msg, err := json.Marshal(order)
+	msg, err := models.Marshal(order, k.ContentType)
 	if err != nil {
-		return fmt.Errorf("cannot marshal order %w", err)
+		return err
 	}
 	return k.Writer.WriteMessages(context.Background(),
 		kafka.Message{
+			Key:   []byte(order.OrderUID),
 			Value: msg,
+			Headers: []kafka.Header{
+				{Key: models.HeaderContentType, Value: []byte(k.ContentType)},
+			},
 		},
 	)
 }
@@ -0,0 +1,192 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/LootNex/OrderService/Producer/internal/generator"
+	"github.com/LootNex/OrderService/Producer/internal/kafka"
+)
+
+// maxRecent bounds the pool of sent orders that duplicates are drawn from.
+// The pool holds the latest version sent of each order, so a duplicate
+// never moves an order back to an earlier status.
+const maxRecent = 1000
+
+type job struct {
+	order     models.Order
+	invalid   bool
+	duplicate bool
+	update    bool
+}
+
+// Report counts messages. Sent and Failed are the outcome of sending;
+// Invalid, Duplicates and Updates break down the sent messages by kind, so
+// deliberately invalid or repeated orders are not mistaken for failures.
+type Report struct {
+	Sent       int
+	Failed     int
+	Invalid    int
+	Duplicates int
+	Updates    int
+	Elapsed    time.Duration
+	// Rate is the requested rate, 0 if it was not limited.
+	Rate float64
+	// Errors counts failed sends by error message.
+	Errors map[string]int
+}
+
+// Orders is the number of sent new, valid orders.
+func (r Report) Orders() int {
+	return r.Sent - r.Invalid - r.Duplicates - r.Updates
+}
+
+func (r Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Sent) / r.Elapsed.Seconds()
+}
+
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "sent:       %d messages (orders=%d status updates=%d intentional: invalid=%d duplicates=%d)\n",
+		r.Sent, r.Orders(), r.Updates, r.Invalid, r.Duplicates)
+	fmt.Fprintf(w, "failed:     %d send errors\n", r.Failed)
+	fmt.Fprintf(w, "elapsed:    %s\n", r.Elapsed.Round(time.Millisecond))
+	if r.Rate > 0 {
+		fmt.Fprintf(w, "throughput: %.2f messages/sec (requested %.2f/sec)\n", r.Throughput(), r.Rate)
+	} else {
+		fmt.Fprintf(w, "throughput: %.2f messages/sec (no limit requested)\n", r.Throughput())
+	}
+
+	if len(r.Errors) == 0 {
+		return
+	}
+
+	msgs := make([]string, 0, len(r.Errors))
+	for msg := range r.Errors {
+		msgs = append(msgs, msg)
+	}
+	sort.Slice(msgs, func(i, j int) bool { return r.Errors[msgs[i]] > r.Errors[msgs[j]] })
+
+	fmt.Fprintln(w, "errors:")
+	for _, msg := range msgs {
+		fmt.Fprintf(w, "  %dx %s\n", r.Errors[msg], msg)
+	}
+}
+
+// Run sends orders until the count or the duration is reached or ctx is
+// cancelled. Orders are generated by a single goroutine in a fixed order,
+// so a seeded run produces the same messages regardless of concurrency.
+func Run(ctx context.Context, sender kafka.KafkaManager, opts Options) Report {
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	report := Report{Rate: opts.Rate, Errors: map[string]int{}}
+	var mu sync.Mutex
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				err := sender.Send(j.order)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					report.Failed++
+					report.Errors[err.Error()]++
+				case j.invalid:
+					report.Sent++
+					report.Invalid++
+				case j.duplicate:
+					report.Sent++
+					report.Duplicates++
+				case j.update:
+					report.Sent++
+					report.Updates++
+				default:
+					report.Sent++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	start := time.Now()
+	generate(ctx, opts, jobs)
+	close(jobs)
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+
+	return report
+}
+
+func generate(ctx context.Context, opts Options, jobs chan<- job) {
+
+	gen := generator.New(opts.Seed, opts.Profile)
+	recent := make([]models.Order, 0, maxRecent)
+	// index finds an order in recent by order_uid.
+	index := make(map[string]int, maxRecent)
+
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for n := 0; opts.Count == 0 || n < opts.Count; n++ {
+
+		var j job
+		if update, ok := gen.Update(); ok {
+			j = job{order: update, update: true}
+			if i, ok := index[update.OrderUID]; ok {
+				recent[i] = update
+			}
+		} else if len(recent) > 0 && gen.Chance(opts.DuplicateRatio) {
+			gen.Duplicate()
+			j = job{order: recent[gen.Intn(len(recent))], duplicate: true}
+		} else if gen.Chance(opts.InvalidPercent / 100) {
+			j = job{order: gen.Invalid(), invalid: true}
+		} else {
+			j = job{order: gen.Order()}
+			if len(recent) < maxRecent {
+				index[j.order.OrderUID] = len(recent)
+				recent = append(recent, j.order)
+			} else {
+				i := gen.Intn(maxRecent)
+				delete(index, recent[i].OrderUID)
+				index[j.order.OrderUID] = i
+				recent[i] = j.order
+			}
+		}
+
+		if n > 0 && tick != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case jobs <- j:
+		}
+	}
+}
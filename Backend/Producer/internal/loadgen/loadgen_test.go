@@ -0,0 +1,177 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/LootNex/OrderService/Producer/internal/generator"
+)
+
+type MockSender struct {
+	mu     sync.Mutex
+	orders []models.Order
+	err    error
+}
+
+func (ms *MockSender) Send(order models.Order) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.orders = append(ms.orders, order)
+	return ms.err
+}
+
+func testOptions() Options {
+	profile := generator.DefaultProfile()
+	profile.Items, _ = ParseDistribution("1-4")
+	return Options{
+		Count:          200,
+		Concurrency:    1,
+		Profile:        profile,
+		InvalidPercent: 20,
+		DuplicateRatio: 0.1,
+		Seed:           7,
+	}
+}
+
+func TestRun(t *testing.T) {
+	sender := &MockSender{}
+	opts := testOptions()
+
+	report := Run(context.Background(), sender, opts)
+
+	if report.Sent != opts.Count || report.Failed != 0 {
+		t.Errorf("expected %d sent, got %+v", opts.Count, report)
+	}
+	if report.Invalid == 0 || report.Duplicates == 0 {
+		t.Errorf("expected invalid and duplicate orders, got %+v", report)
+	}
+	if report.Orders()+report.Invalid+report.Duplicates+report.Updates != report.Sent {
+		t.Errorf("breakdown does not add up to sent messages: %+v", report)
+	}
+
+	invalid, seen, duplicates := 0, map[string]bool{}, 0
+	for _, order := range sender.orders {
+		if order.Validate() != nil {
+			invalid++
+			continue
+		}
+		if seen[order.OrderUID] {
+			duplicates++
+		}
+		seen[order.OrderUID] = true
+	}
+	if invalid != report.Invalid {
+		t.Errorf("expected %d invalid orders, found %d", report.Invalid, invalid)
+	}
+	if duplicates != report.Duplicates {
+		t.Errorf("expected %d duplicates, found %d", report.Duplicates, duplicates)
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	first, second := &MockSender{}, &MockSender{}
+
+	Run(context.Background(), first, testOptions())
+	Run(context.Background(), second, testOptions())
+
+	// Whole orders, dates included, so nothing depends on the wall clock.
+	if !reflect.DeepEqual(first.orders, second.orders) {
+		t.Error("same seed produced different orders")
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	sender := &MockSender{err: errors.New("broker unavailable")}
+	opts := testOptions()
+	opts.Count = 5
+
+	report := Run(context.Background(), sender, opts)
+
+	if report.Failed != 5 || report.Errors["broker unavailable"] != 5 {
+		t.Errorf("expected 5 failures, got %+v", report)
+	}
+	if report.Sent != 0 || report.Invalid != 0 || report.Duplicates != 0 {
+		t.Errorf("failed sends must not count as sent messages, got %+v", report)
+	}
+}
+
+func TestRun_Duration(t *testing.T) {
+	sender := &MockSender{}
+	opts := testOptions()
+	opts.Count = 0
+	opts.Rate = 100
+	opts.Duration = 100 * time.Millisecond
+
+	report := Run(context.Background(), sender, opts)
+
+	if report.Sent == 0 || report.Sent > 20 {
+		t.Errorf("expected about 10 orders in 100ms at 100/s, got %d", report.Sent)
+	}
+}
+
+func TestRun_DuplicatesKeepStatus(t *testing.T) {
+	sender := &MockSender{}
+	opts := testOptions()
+	opts.Count = 2000
+	opts.InvalidPercent = 0
+	opts.DuplicateRatio = 0.3
+	opts.Profile.EventGap = 3
+	opts.Profile.Lifecycles.Add([]int{202, 203, 204}, 1)
+
+	report := Run(context.Background(), sender, opts)
+	if report.Updates == 0 || report.Duplicates == 0 {
+		t.Fatalf("expected updates and duplicates, got %+v", report)
+	}
+
+	// Once an order moved on from a status, no later message may carry it.
+	current := map[string]int{}
+	left := map[string]map[int]bool{}
+	for _, order := range sender.orders {
+		status := order.Items[0].Status
+		if left[order.OrderUID][status] {
+			t.Fatalf("order %s went back to status %d", order.OrderUID, status)
+		}
+		if prev, ok := current[order.OrderUID]; ok && prev != status {
+			if left[order.OrderUID] == nil {
+				left[order.OrderUID] = map[int]bool{}
+			}
+			left[order.OrderUID][prev] = true
+		}
+		current[order.OrderUID] = status
+	}
+}
+
+func TestReportPrint(t *testing.T) {
+	tests := []struct {
+		name   string
+		report Report
+		want   string
+	}{
+		{
+			name:   "rate",
+			report: Report{Sent: 150, Elapsed: time.Second, Rate: 200},
+			want:   "throughput: 150.00 messages/sec (requested 200.00/sec)",
+		},
+		{
+			name:   "no rate",
+			report: Report{Sent: 150, Elapsed: time.Second},
+			want:   "throughput: 150.00 messages/sec (no limit requested)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			tt.report.Print(&out)
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("expected %q in report:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package loadgen
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LootNex/OrderService/Producer/internal/generator"
+	"github.com/LootNex/OrderService/Producer/internal/scenario"
+)
+
+type Options struct {
+	// Count is the number of orders to send, 0 means no limit. The flag
+	// defaults to 10, or to no limit when -duration is set.
+	Count int
+	// Rate is the number of orders per second, 0 means as fast as possible.
+	Rate float64
+	// Duration stops the run after the given time, 0 means no limit.
+	Duration    time.Duration
+	Concurrency int
+	// Profile shapes the generated orders, see generator.Profile.
+	Profile generator.Profile
+	// InvalidPercent is the share of orders, in percent, that fail validation.
+	InvalidPercent float64
+	// DuplicateRatio is the share of messages, from 0 to 1, that resend an
+	// order which was already sent.
+	DuplicateRatio float64
+	// Seed makes the generated orders reproducible, 0 picks a random seed.
+	Seed uint64
+}
+
+func ParseFlags(args []string, output io.Writer) (Options, error) {
+
+	var opts Options
+	var items, scenarioPath string
+
+	fs := flag.NewFlagSet("producer", flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	fs.IntVar(&opts.Count, "count", 10, "number of orders to send, 0 for no limit (the default with -duration)")
+	fs.Float64Var(&opts.Rate, "rate", 0.2, "orders per second, 0 for no limit")
+	fs.DurationVar(&opts.Duration, "duration", 0, "stop after this long, 0 for no limit")
+	fs.IntVar(&opts.Concurrency, "concurrency", 1, "number of concurrent senders")
+	fs.StringVar(&items, "items", "1", `items per order: "3", uniform "1-5" or weighted "1:60,2:30,5:10"`)
+	fs.Float64Var(&opts.InvalidPercent, "invalid", 0, "percentage of deliberately invalid orders")
+	fs.Float64Var(&opts.DuplicateRatio, "duplicates", 0, "ratio of messages resending an already sent order, 0..1")
+	fs.Uint64Var(&opts.Seed, "seed", 0, "seed for reproducible orders, 0 for random")
+	fs.StringVar(&scenarioPath, "scenario", "", "YAML scenario describing the generated data")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if set["duration"] && !set["count"] {
+		opts.Count = 0
+	}
+
+	opts.Profile = generator.DefaultProfile()
+	if scenarioPath != "" {
+		sc, err := scenario.Load(scenarioPath)
+		if err != nil {
+			return opts, err
+		}
+		opts.Profile = sc.Profile()
+		if !set["seed"] {
+			opts.Seed = sc.Seed
+		}
+	}
+
+	if scenarioPath == "" || set["items"] {
+		dist, err := ParseDistribution(items)
+		if err != nil {
+			return opts, fmt.Errorf("invalid -items: %w", err)
+		}
+		opts.Profile.Items = dist
+	}
+
+	return opts, opts.Validate()
+}
+
+func (o Options) Validate() error {
+	if o.Count < 0 {
+		return errors.New("count cannot be negative")
+	}
+	if o.Count == 0 && o.Duration == 0 {
+		return errors.New("either count or duration must be set")
+	}
+	if o.Rate < 0 {
+		return errors.New("rate cannot be negative")
+	}
+	if o.Concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+	if o.InvalidPercent < 0 || o.InvalidPercent > 100 {
+		return errors.New("invalid percentage must be between 0 and 100")
+	}
+	if o.DuplicateRatio < 0 || o.DuplicateRatio > 1 {
+		return errors.New("duplicate ratio must be between 0 and 1")
+	}
+	if o.Profile.Items.Empty() {
+		return errors.New("items distribution is empty")
+	}
+	return nil
+}
+
+// ParseDistribution accepts a fixed value ("3"), a uniform range ("1-5")
+// or a list of value:weight pairs ("1:60,2:30,5:10").
+func ParseDistribution(s string) (generator.Weighted[int], error) {
+
+	var d generator.Weighted[int]
+	s = strings.TrimSpace(s)
+
+	if lo, hi, ok := strings.Cut(s, "-"); ok {
+		min, err := parseCount(lo)
+		if err != nil {
+			return d, err
+		}
+		max, err := parseCount(hi)
+		if err != nil {
+			return d, err
+		}
+		if min > max {
+			return d, fmt.Errorf("range %q is empty", s)
+		}
+		for v := min; v <= max; v++ {
+			d.Add(v, 1)
+		}
+		return d, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		value, weight, hasWeight := strings.Cut(part, ":")
+
+		v, err := parseCount(value)
+		if err != nil {
+			return d, err
+		}
+
+		w := 1.0
+		if hasWeight {
+			w, err = strconv.ParseFloat(strings.TrimSpace(weight), 64)
+			if err != nil || w < 0 {
+				return d, fmt.Errorf("invalid weight %q", weight)
+			}
+		}
+		d.Add(v, w)
+	}
+
+	if d.Empty() {
+		return d, errors.New("weights sum to zero")
+	}
+
+	return d, nil
+}
+
+func parseCount(s string) (int, error) {
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid item count %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,88 @@
+package loadgen
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/LootNex/OrderService/Producer/internal/generator"
+)
+
+func TestParseDistribution(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		allowed []int
+		wantErr bool
+	}{
+		{name: "fixed", input: "3", allowed: []int{3}},
+		{name: "range", input: "1-3", allowed: []int{1, 2, 3}},
+		{name: "weighted", input: "1:60, 5:40", allowed: []int{1, 5}},
+		{name: "zero weight", input: "1:0,2:1", allowed: []int{2}},
+		{name: "empty range", input: "5-1", wantErr: true},
+		{name: "negative", input: "-1", wantErr: true},
+		{name: "bad weight", input: "1:x", wantErr: true},
+		{name: "all zero weights", input: "1:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDistribution(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected err:%v, got err:%v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			gen := generator.New(1, generator.DefaultProfile())
+			for i := 0; i < 100; i++ {
+				v := d.Sample(gen)
+				found := false
+				for _, a := range tt.allowed {
+					found = found || a == v
+				}
+				if !found {
+					t.Fatalf("sampled %d, allowed %v", v, tt.allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	opts, err := ParseFlags([]string{"-count", "0", "-duration", "1m", "-rate", "50", "-concurrency", "4",
+		"-items", "1-5", "-invalid", "10", "-duplicates", "0.2", "-seed", "42"}, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Duration != time.Minute || opts.Rate != 50 || opts.Concurrency != 4 || opts.Seed != 42 {
+		t.Errorf("unexpected options %+v", opts)
+	}
+
+	opts, err = ParseFlags([]string{"-duration", "1m"}, io.Discard)
+	if err != nil || opts.Count != 0 {
+		t.Errorf("expected no count limit with -duration, got %d, %v", opts.Count, err)
+	}
+	opts, err = ParseFlags([]string{"-duration", "1m", "-count", "5"}, io.Discard)
+	if err != nil || opts.Count != 5 {
+		t.Errorf("expected an explicit count to be kept, got %d, %v", opts.Count, err)
+	}
+	opts, err = ParseFlags(nil, io.Discard)
+	if err != nil || opts.Count != 10 {
+		t.Errorf("expected the default count 10, got %d, %v", opts.Count, err)
+	}
+
+	invalid := [][]string{
+		{"-count", "0"},
+		{"-concurrency", "0"},
+		{"-invalid", "150"},
+		{"-duplicates", "2"},
+		{"-items", "a"},
+	}
+	for _, args := range invalid {
+		if _, err := ParseFlags(args, io.Discard); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
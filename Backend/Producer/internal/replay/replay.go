@@ -0,0 +1,239 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/LootNex/OrderService/Producer/internal/kafka"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// maxLineSize bounds a single JSONL line; real orders are a few KB.
+const maxLineSize = 4 << 20
+
+type Options struct {
+	// Path is a JSONL file or a directory with *.jsonl files.
+	Path string
+	// RewriteUID gives every order a new order_uid. Lines that shared an
+	// order_uid still share the new one, so status updates stay linked.
+	RewriteUID bool
+	// RewriteTime moves date_created and payment_dt to the time of sending.
+	RewriteTime bool
+	// Rate is the number of orders per second, 0 means as fast as possible.
+	Rate float64
+}
+
+func ParseFlags(args []string, output io.Writer) (Options, error) {
+
+	var opts Options
+
+	fs := flag.NewFlagSet("producer replay", flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	fs.StringVar(&opts.Path, "path", "", "JSONL file or directory with *.jsonl files")
+	fs.BoolVar(&opts.RewriteUID, "rewrite-uid", false, "replace order_uid with a fresh one")
+	fs.BoolVar(&opts.RewriteTime, "rewrite-time", false, "set date_created and payment_dt to now")
+	fs.Float64Var(&opts.Rate, "rate", 0, "orders per second, 0 for no limit")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	if opts.Path == "" {
+		return opts, errors.New("-path is required")
+	}
+	if opts.Rate < 0 {
+		return opts, errors.New("rate cannot be negative")
+	}
+
+	return opts, nil
+}
+
+type LineError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return e.File + ":" + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+type Report struct {
+	Sent        int
+	Failed      int
+	ParseErrors []LineError
+	SendErrors  []LineError
+	Elapsed     time.Duration
+}
+
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "orders:  sent=%d failed=%d unparsable=%d\n", r.Sent, r.Failed, len(r.ParseErrors))
+	fmt.Fprintf(w, "elapsed: %s\n", r.Elapsed.Round(time.Millisecond))
+
+	if len(r.ParseErrors) > 0 {
+		fmt.Fprintln(w, "lines that failed to parse:")
+		for _, e := range r.ParseErrors {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+	if len(r.SendErrors) > 0 {
+		fmt.Fprintln(w, "lines that failed to send:")
+		for _, e := range r.SendErrors {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+}
+
+// Run publishes every order found under opts.Path. Unparsable lines are
+// reported and skipped, they do not stop the replay.
+func Run(ctx context.Context, sender kafka.KafkaManager, opts Options) (Report, error) {
+
+	var report Report
+
+	files, err := listFiles(opts.Path)
+	if err != nil {
+		return report, err
+	}
+
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	uids := map[string]string{}
+	start := time.Now()
+	first := true
+
+	for _, file := range files {
+		err := readFile(file, func(line int, order models.Order, parseErr error) error {
+			if parseErr != nil {
+				report.ParseErrors = append(report.ParseErrors, LineError{File: file, Line: line, Err: parseErr})
+				return nil
+			}
+
+			rewrite(&order, opts, uids)
+
+			if !first && tick != nil {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-tick:
+				}
+			}
+			first = false
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := sender.Send(order); err != nil {
+				report.Failed++
+				report.SendErrors = append(report.SendErrors, LineError{File: file, Line: line, Err: err})
+				return nil
+			}
+			report.Sent++
+			return nil
+		})
+		if err != nil {
+			report.Elapsed = time.Since(start)
+			return report, err
+		}
+	}
+
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+func rewrite(order *models.Order, opts Options, uids map[string]string) {
+
+	if opts.RewriteUID {
+		newUID, ok := uids[order.OrderUID]
+		if !ok {
+			newUID = gofakeit.UUID()
+			uids[order.OrderUID] = newUID
+		}
+		if order.Payment.Transaction == order.OrderUID {
+			order.Payment.Transaction = newUID
+		}
+		order.OrderUID = newUID
+	}
+
+	if opts.RewriteTime {
+		now := time.Now()
+		order.DateCreated = now.Format(time.RFC3339)
+		order.Payment.PaymentDT = now.Unix()
+	}
+}
+
+func listFiles(path string) ([]string, error) {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open replay path err:%w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	for _, pattern := range []string{"*.jsonl", "*.ndjson"} {
+		matches, err := filepath.Glob(filepath.Join(path, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.jsonl files in %s", path)
+	}
+
+	return files, nil
+}
+
+func readFile(path string, fn func(line int, order models.Order, parseErr error) error) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open %s err:%w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		var order models.Order
+		parseErr := json.Unmarshal(data, &order)
+		if err := fn(line, order, parseErr); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read %s err:%w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LootNex/OrderService/Contract/models"
+)
+
+type MockSender struct {
+	orders []models.Order
+	err    error
+}
+
+func (ms *MockSender) Send(order models.Order) error {
+	ms.orders = append(ms.orders, order)
+	return ms.err
+}
+
+const (
+	orderLine  = `{"order_uid":"uid1","track_number":"T1","payment":{"transaction":"uid1","payment_dt":1},"date_created":"2021-11-26T06:22:19Z"}`
+	updateLine = `{"order_uid":"uid1","track_number":"T1","payment":{"transaction":"uid1","payment_dt":1},"date_created":"2021-11-26T06:22:19Z","items":[{"status":203}]}`
+	otherLine  = `{"order_uid":"uid2","track_number":"T2","payment":{"transaction":"tx2"}}`
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("cannot write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestRun_Directory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.jsonl", orderLine+"\n\n{broken\n"+updateLine+"\n")
+	writeFile(t, dir, "b.jsonl", otherLine+"\n")
+	writeFile(t, dir, "notes.txt", "ignored")
+
+	sender := &MockSender{}
+	report, err := Run(context.Background(), sender, Options{Path: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Sent != 3 || len(sender.orders) != 3 {
+		t.Errorf("expected 3 orders sent, got %d", report.Sent)
+	}
+	if len(report.ParseErrors) != 1 {
+		t.Fatalf("expected 1 parse error, got %v", report.ParseErrors)
+	}
+	if e := report.ParseErrors[0]; filepath.Base(e.File) != "a.jsonl" || e.Line != 3 {
+		t.Errorf("expected parse error at a.jsonl:3, got %s", e)
+	}
+	if sender.orders[2].OrderUID != "uid2" {
+		t.Errorf("expected files in name order, got %v last", sender.orders[2].OrderUID)
+	}
+}
+
+func TestRun_Rewrite(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "orders.jsonl", orderLine+"\n"+updateLine+"\n"+otherLine+"\n")
+
+	sender := &MockSender{}
+	_, err := Run(context.Background(), sender, Options{Path: path, RewriteUID: true, RewriteTime: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, update, other := sender.orders[0], sender.orders[1], sender.orders[2]
+	if first.OrderUID == "uid1" || other.OrderUID == "uid2" {
+		t.Error("order_uid was not rewritten")
+	}
+	if first.OrderUID != update.OrderUID {
+		t.Error("lines with the same order_uid must keep sharing it")
+	}
+	if first.Payment.Transaction != first.OrderUID || other.Payment.Transaction != "tx2" {
+		t.Error("transaction must follow order_uid only when they were equal")
+	}
+	if first.DateCreated == "2021-11-26T06:22:19Z" || first.Payment.PaymentDT == 1 {
+		t.Error("timestamps were not rewritten")
+	}
+}
+
+func TestRun_SendErrors(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "orders.jsonl", orderLine+"\n"+otherLine+"\n")
+
+	report, err := Run(context.Background(), &MockSender{err: errors.New("broker down")}, Options{Path: path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Failed != 2 || len(report.SendErrors) != 2 || report.SendErrors[1].Line != 2 {
+		t.Errorf("expected 2 send errors, got %+v", report)
+	}
+}
+
+func TestRun_MissingPath(t *testing.T) {
+	if _, err := Run(context.Background(), &MockSender{}, Options{Path: filepath.Join(t.TempDir(), "nope")}); err == nil {
+		t.Error("expected error for missing path")
+	}
+	if _, err := Run(context.Background(), &MockSender{}, Options{Path: t.TempDir()}); err == nil {
+		t.Error("expected error for directory without jsonl files")
+	}
+}
@@ -0,0 +1,153 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/LootNex/OrderService/Producer/internal/generator"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is the YAML description of a synthetic dataset, for example:
+//
+//	seed: 42
+//	start_time: 2025-01-01T00:00:00Z
+//	time_step: 1m
+//	items: {1: 60, 2: 30, 5: 10}
+//	currencies: {RUB: 70, USD: 20, EUR: 10}
+//	locales: {ru: 80, en: 20}
+//	delivery_services: {meest: 50, cdek: 30, boxberry: 20}
+//	providers: {wbpay: 90, sbp: 10}
+//	customers: {pool: 500, reuse: 0.7}
+//	lifecycle:
+//	  event_gap: 3
+//	  sequences:
+//	    - {weight: 70, statuses: [202, 203, 204]}
+//	    - {weight: 30, statuses: [202, 210]}
+//
+// Weights are relative. Without start_time the dates follow the wall clock
+// and are the only part of the dataset that differs between runs.
+type Scenario struct {
+	Seed             uint64             `yaml:"seed"`
+	StartTime        time.Time          `yaml:"start_time"`
+	TimeStep         time.Duration      `yaml:"time_step"`
+	Items            map[int]float64    `yaml:"items"`
+	Currencies       map[string]float64 `yaml:"currencies"`
+	Locales          map[string]float64 `yaml:"locales"`
+	DeliveryServices map[string]float64 `yaml:"delivery_services"`
+	Providers        map[string]float64 `yaml:"providers"`
+	Customers        struct {
+		Pool  int     `yaml:"pool"`
+		Reuse float64 `yaml:"reuse"`
+	} `yaml:"customers"`
+	Lifecycle struct {
+		EventGap  int `yaml:"event_gap"`
+		Sequences []struct {
+			Weight   float64 `yaml:"weight"`
+			Statuses []int   `yaml:"statuses"`
+		} `yaml:"sequences"`
+	} `yaml:"lifecycle"`
+}
+
+func Load(path string) (Scenario, error) {
+
+	var sc Scenario
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sc, fmt.Errorf("cannot read scenario err:%w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return sc, fmt.Errorf("cannot parse scenario err:%w", err)
+	}
+
+	return sc, sc.Validate()
+}
+
+func (sc Scenario) Validate() error {
+	if sc.Customers.Reuse < 0 || sc.Customers.Reuse > 1 {
+		return errors.New("customers.reuse must be between 0 and 1")
+	}
+	if sc.Customers.Pool < 0 {
+		return errors.New("customers.pool cannot be negative")
+	}
+	if sc.TimeStep < 0 {
+		return errors.New("time_step cannot be negative")
+	}
+	for n := range sc.Items {
+		if n < 0 {
+			return fmt.Errorf("invalid item count %d", n)
+		}
+	}
+	for i, seq := range sc.Lifecycle.Sequences {
+		if len(seq.Statuses) == 0 {
+			return fmt.Errorf("lifecycle.sequences[%d] has no statuses", i)
+		}
+	}
+	for _, weights := range []map[string]float64{sc.Currencies, sc.Locales, sc.DeliveryServices, sc.Providers} {
+		for value, w := range weights {
+			if w < 0 {
+				return fmt.Errorf("negative weight for %q", value)
+			}
+		}
+	}
+	return nil
+}
+
+// Profile converts the scenario into generator settings. Map keys are
+// sorted so that the same seed always samples the same values.
+func (sc Scenario) Profile() generator.Profile {
+
+	profile := generator.DefaultProfile()
+
+	if len(sc.Items) > 0 {
+		counts := make([]int, 0, len(sc.Items))
+		for n := range sc.Items {
+			counts = append(counts, n)
+		}
+		sort.Ints(counts)
+
+		profile.Items = generator.Weighted[int]{}
+		for _, n := range counts {
+			profile.Items.Add(n, sc.Items[n])
+		}
+	}
+
+	profile.Currencies = weighted(sc.Currencies)
+	profile.Locales = weighted(sc.Locales)
+	profile.DeliveryServices = weighted(sc.DeliveryServices)
+	profile.Providers = weighted(sc.Providers)
+
+	for _, seq := range sc.Lifecycle.Sequences {
+		profile.Lifecycles.Add(seq.Statuses, seq.Weight)
+	}
+	if sc.Lifecycle.EventGap > 0 {
+		profile.EventGap = sc.Lifecycle.EventGap
+	}
+
+	profile.CustomerPool = sc.Customers.Pool
+	profile.CustomerReuse = sc.Customers.Reuse
+	profile.StartTime = sc.StartTime
+	profile.TimeStep = sc.TimeStep
+
+	return profile
+}
+
+func weighted(m map[string]float64) generator.Weighted[string] {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var w generator.Weighted[string]
+	for _, k := range keys {
+		w.Add(k, m[k])
+	}
+	return w
+}
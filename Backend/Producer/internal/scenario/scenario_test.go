@@ -0,0 +1,129 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/LootNex/OrderService/Contract/models"
+	"github.com/LootNex/OrderService/Producer/internal/generator"
+)
+
+const examplePath = "../../configs/scenarios/example.yaml"
+
+func generate(t *testing.T, sc Scenario, n int) []models.Order {
+	t.Helper()
+
+	gen := generator.New(sc.Seed, sc.Profile())
+	orders := make([]models.Order, 0, n)
+	for len(orders) < n {
+		if update, ok := gen.Update(); ok {
+			orders = append(orders, update)
+			continue
+		}
+		orders = append(orders, gen.Order())
+	}
+	return orders
+}
+
+func TestLoad_Example(t *testing.T) {
+	sc, err := Load(examplePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sc.Seed != 42 || sc.Customers.Pool != 2000 || len(sc.Lifecycle.Sequences) != 3 {
+		t.Errorf("unexpected scenario %+v", sc)
+	}
+	if sc.StartTime.IsZero() || sc.TimeStep.Minutes() != 1 {
+		t.Errorf("start_time and time_step not parsed: %v %v", sc.StartTime, sc.TimeStep)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"reuse":     "customers: {reuse: 2}",
+		"sequence":  "lifecycle: {sequences: [{weight: 1, statuses: []}]}",
+		"weight":    "locales: {ru: -1}",
+		"malformed": "items: [1, 2",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.yaml")
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatalf("cannot write scenario: %v", err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestProfile_Deterministic(t *testing.T) {
+	sc, err := Load(examplePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(generate(t, sc, 300), generate(t, sc, 300)) {
+		t.Error("same seed produced different datasets")
+	}
+
+	other := sc
+	other.Seed++
+	if reflect.DeepEqual(generate(t, sc, 50), generate(t, other, 50)) {
+		t.Error("different seeds produced the same dataset")
+	}
+}
+
+func TestProfile_Distributions(t *testing.T) {
+	sc, err := Load(examplePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	customers := map[string]int{}
+	statuses := map[string][]int{}
+	newOrders := 0
+
+	for _, order := range generate(t, sc, 2000) {
+		if err := order.Validate(); err != nil {
+			t.Fatalf("generated invalid order: %v", err)
+		}
+		if _, ok := sc.Locales[order.Locale]; !ok {
+			t.Errorf("unexpected locale %q", order.Locale)
+		}
+		if _, ok := sc.Currencies[order.Payment.Currency]; !ok {
+			t.Errorf("unexpected currency %q", order.Payment.Currency)
+		}
+		if _, ok := sc.DeliveryServices[order.DeliveryService]; !ok {
+			t.Errorf("unexpected delivery service %q", order.DeliveryService)
+		}
+		if _, ok := sc.Items[len(order.Items)]; !ok {
+			t.Errorf("unexpected item count %d", len(order.Items))
+		}
+
+		if _, seen := statuses[order.OrderUID]; !seen {
+			newOrders++
+			customers[order.CustomerID]++
+		}
+		statuses[order.OrderUID] = append(statuses[order.OrderUID], order.Items[0].Status)
+	}
+
+	if len(customers) >= newOrders*3/4 {
+		t.Errorf("expected customers to be reused, got %d customers for %d orders", len(customers), newOrders)
+	}
+
+	for uid, got := range statuses {
+		matches := false
+		for _, seq := range sc.Lifecycle.Sequences {
+			matches = matches || (len(got) <= len(seq.Statuses) && slices.Equal(seq.Statuses[:len(got)], got))
+		}
+		if !matches {
+			t.Errorf("order %s went through unexpected statuses %v", uid, got)
+		}
+	}
+}
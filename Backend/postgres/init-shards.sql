@@ -0,0 +1,3 @@
+-- Second database for trying out sharding locally, see postgres.shards in
+-- Consumer/configs/config.yaml.
+CREATE DATABASE orders_s1;